@@ -17,3 +17,20 @@ func TestJsonContentType(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestFormGroup(t *testing.T) {
+	for _, c := range []struct {
+		key         string
+		start, last int
+		expected    string
+	}{
+		{"tcp", 80, 80, "80/tcp"},
+		{"udp", 80, 82, "80-82/udp"},
+		{"0.0.0.0/tcp", 80, 80, "0.0.0.0:80->80/tcp"},
+		{"::/tcp", 80, 81, "[::]:80-81->80-81/tcp"},
+	} {
+		if group := FormGroup(c.key, c.start, c.last); group != c.expected {
+			t.Fatalf("Expected %s for %s, got %s", c.expected, c.key, group)
+		}
+	}
+}
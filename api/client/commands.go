@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -613,9 +614,15 @@ func (cli *DockerCli) CmdInfo(args ...string) error {
 	if remoteInfo.Exists("SwapLimit") && !remoteInfo.GetBool("SwapLimit") {
 		fmt.Fprintf(cli.err, "WARNING: No swap limit support\n")
 	}
+	if remoteInfo.Exists("PidsLimit") && !remoteInfo.GetBool("PidsLimit") {
+		fmt.Fprintf(cli.err, "WARNING: No pids limit support\n")
+	}
 	if remoteInfo.Exists("IPv4Forwarding") && !remoteInfo.GetBool("IPv4Forwarding") {
 		fmt.Fprintf(cli.err, "WARNING: IPv4 forwarding is disabled.\n")
 	}
+	for _, warning := range remoteInfo.GetList("DriverWarnings") {
+		fmt.Fprintf(cli.err, "WARNING: %s\n", warning)
+	}
 	if remoteInfo.Exists("Labels") {
 		fmt.Fprintln(cli.out, "Labels:")
 		for _, attribute := range remoteInfo.GetList("Labels") {
@@ -864,6 +871,44 @@ func (cli *DockerCli) CmdRename(args ...string) error {
 	return nil
 }
 
+func (cli *DockerCli) CmdUpdate(args ...string) error {
+	cmd := cli.Subcmd("update", "CONTAINER [CONTAINER...]", "Update the network rates of one or more containers", true)
+	flIngressRate := cmd.String([]string{"-network-ingress-rate"}, "", "Limit the rate of the traffic received by the container (e.g. 10mbit), 0 is unlimited")
+	flEgressRate := cmd.String([]string{"-network-egress-rate"}, "", "Limit the rate of the traffic sent by the container (e.g. 10mbit), 0 is unlimited")
+	cmd.Require(flag.Min, 1)
+
+	utils.ParseFlags(cmd, args, true)
+
+	config := make(map[string]int64)
+	for flName, rate := range map[string]*string{
+		"NetworkIngressRate": flIngressRate,
+		"NetworkEgressRate":  flEgressRate,
+	} {
+		if *rate == "" {
+			continue
+		}
+		bits, err := runconfig.ParseNetworkRate(*rate)
+		if err != nil {
+			return err
+		}
+		config[flName] = bits
+	}
+	if len(config) == 0 {
+		return fmt.Errorf("You must provide one or more options to update")
+	}
+
+	var encounteredError error
+	for _, name := range cmd.Args() {
+		if _, _, err := readBody(cli.call("POST", "/containers/"+name+"/update", config, false)); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			encounteredError = fmt.Errorf("Error: failed to update one or more containers")
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	return encounteredError
+}
+
 func (cli *DockerCli) CmdInspect(args ...string) error {
 	cmd := cli.Subcmd("inspect", "CONTAINER|IMAGE [CONTAINER|IMAGE...]", "Return low-level information on a container or image", true)
 	tmplStr := cmd.String([]string{"f", "#format", "-format"}, "", "Format the output using the given go template")
@@ -1011,7 +1056,7 @@ func (cli *DockerCli) CmdPort(args ...string) error {
 		natPort := port + "/" + proto
 		if frontends, exists := ports[nat.Port(port+"/"+proto)]; exists && frontends != nil {
 			for _, frontend := range frontends {
-				fmt.Fprintf(cli.out, "%s:%s\n", frontend.HostIp, frontend.HostPort)
+				fmt.Fprintf(cli.out, "%s\n", net.JoinHostPort(frontend.HostIp, frontend.HostPort))
 			}
 			return nil
 		}
@@ -1020,7 +1065,7 @@ func (cli *DockerCli) CmdPort(args ...string) error {
 
 	for from, frontends := range ports {
 		for _, frontend := range frontends {
-			fmt.Fprintf(cli.out, "%s -> %s:%s\n", from, frontend.HostIp, frontend.HostPort)
+			fmt.Fprintf(cli.out, "%s -> %s\n", from, net.JoinHostPort(frontend.HostIp, frontend.HostPort))
 		}
 	}
 
@@ -1220,6 +1265,7 @@ func (cli *DockerCli) CmdImport(args ...string) error {
 
 func (cli *DockerCli) CmdPush(args ...string) error {
 	cmd := cli.Subcmd("push", "NAME[:TAG]", "Push an image or a repository to the registry", true)
+	compress := cmd.String([]string{"-compress"}, "gzip", "Compress the layers pushed to v1 registries with none, gzip or zstd")
 	cmd.Require(flag.Exact, 1)
 
 	utils.ParseFlags(cmd, args, true)
@@ -1251,6 +1297,7 @@ func (cli *DockerCli) CmdPush(args ...string) error {
 
 	v := url.Values{}
 	v.Set("tag", tag)
+	v.Set("compress", *compress)
 
 	push := func(authConfig registry.AuthConfig) error {
 		buf, err := json.Marshal(authConfig)
@@ -2460,6 +2507,8 @@ func (cli *DockerCli) CmdCp(args ...string) error {
 func (cli *DockerCli) CmdSave(args ...string) error {
 	cmd := cli.Subcmd("save", "IMAGE [IMAGE...]", "Save an image(s) to a tar archive (streamed to STDOUT by default)", true)
 	outfile := cmd.String([]string{"o", "-output"}, "", "Write to an file, instead of STDOUT")
+	compress := cmd.String([]string{"-compress"}, "none", "Compress the archive with none, gzip or zstd")
+	format := cmd.String([]string{"-format"}, "docker", "Format of the archive, docker or oci")
 	cmd.Require(flag.Min, 1)
 
 	utils.ParseFlags(cmd, args, true)
@@ -2477,13 +2526,19 @@ func (cli *DockerCli) CmdSave(args ...string) error {
 		return errors.New("Cowardly refusing to save to a terminal. Use the -o flag or redirect.")
 	}
 
+	v := url.Values{}
+	if *compress != "none" {
+		v.Set("compress", *compress)
+	}
+	if *format != "docker" {
+		v.Set("format", *format)
+	}
 	if len(cmd.Args()) == 1 {
 		image := cmd.Arg(0)
-		if err := cli.stream("GET", "/images/"+image+"/get", nil, output, nil); err != nil {
+		if err := cli.stream("GET", "/images/"+image+"/get?"+v.Encode(), nil, output, nil); err != nil {
 			return err
 		}
 	} else {
-		v := url.Values{}
 		for _, arg := range cmd.Args() {
 			v.Add("names", arg)
 		}
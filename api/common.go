@@ -3,8 +3,10 @@ package api
 import (
 	"fmt"
 	"mime"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
@@ -50,7 +52,7 @@ func DisplayablePorts(ports *engine.Table) string {
 		)
 		if port.Get("IP") != "" {
 			if port.GetInt("PublicPort") != current {
-				hostMappings = append(hostMappings, fmt.Sprintf("%s:%d->%d/%s", port.Get("IP"), port.GetInt("PublicPort"), port.GetInt("PrivatePort"), port.Get("Type")))
+				hostMappings = append(hostMappings, fmt.Sprintf("%s->%d/%s", net.JoinHostPort(port.Get("IP"), strconv.Itoa(port.GetInt("PublicPort"))), port.GetInt("PrivatePort"), port.Get("Type")))
 				continue
 			}
 			portKey = fmt.Sprintf("%s/%s", port.Get("IP"), port.Get("Type"))
@@ -96,7 +98,7 @@ func FormGroup(key string, start, last int) string {
 		group = fmt.Sprintf("%d-%d", start, last)
 	}
 	if ip != "" {
-		group = fmt.Sprintf("%s:%s->%s", ip, group, group)
+		group = fmt.Sprintf("%s->%s", net.JoinHostPort(ip, group), group)
 	}
 	return fmt.Sprintf("%s/%s", group, groupType)
 }
@@ -668,6 +668,7 @@ func postImagesPush(eng *engine.Engine, version version.Version, w http.Response
 	job.SetenvJson("metaHeaders", metaHeaders)
 	job.SetenvJson("authConfig", authConfig)
 	job.Setenv("tag", r.Form.Get("tag"))
+	job.Setenv("compress", r.Form.Get("compress"))
 	if version.GreaterThan("1.0") {
 		job.SetenvBool("json", true)
 		streamJSON(job, w, true)
@@ -701,6 +702,8 @@ func getImagesGet(eng *engine.Engine, version version.Version, w http.ResponseWr
 	} else {
 		job = eng.Job("image_export", r.Form["names"]...)
 	}
+	job.Setenv("compress", r.Form.Get("compress"))
+	job.Setenv("format", r.Form.Get("format"))
 	job.Stdout.Add(w)
 	return job.Run()
 }
@@ -780,6 +783,25 @@ func postContainerRename(eng *engine.Engine, version version.Version, w http.Res
 	return nil
 }
 
+func postContainersUpdate(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if vars == nil {
+		return fmt.Errorf("Missing parameter")
+	}
+	if err := checkForJson(r); err != nil {
+		return err
+	}
+
+	job := eng.Job("container_update", vars["name"])
+	if err := job.DecodeEnv(r.Body); err != nil {
+		return err
+	}
+	if err := job.Run(); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 func deleteContainers(eng *engine.Engine, version version.Version, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := parseForm(r); err != nil {
 		return err
@@ -1356,6 +1378,7 @@ func createRouter(eng *engine.Engine, logging, enableCors bool, corsHeaders stri
 			"/exec/{name:.*}/start":         postContainerExecStart,
 			"/exec/{name:.*}/resize":        postContainerExecResize,
 			"/containers/{name:.*}/rename":  postContainerRename,
+			"/containers/{name:.*}/update":  postContainersUpdate,
 		},
 		"DELETE": {
 			"/containers/{name:.*}": deleteContainers,
@@ -67,6 +67,13 @@ type BlkioStats struct {
 	SectorsRecursive        []BlkioStatEntry `json:"sectors_recursive"`
 }
 
+type PidsStats struct {
+	// number of pids in the cgroup
+	Current uint64 `json:"current"`
+	// active pids hard limit, 0 if there is no limit
+	Limit uint64 `json:"limit"`
+}
+
 type Network struct {
 	RxBytes   uint64 `json:"rx_bytes"`
 	RxPackets uint64 `json:"rx_packets"`
@@ -76,6 +83,13 @@ type Network struct {
 	TxPackets uint64 `json:"tx_packets"`
 	TxErrors  uint64 `json:"tx_errors"`
 	TxDropped uint64 `json:"tx_dropped"`
+	// Shaping of the traffic received and sent by the container, in bits
+	// per second, 0 is unlimited.
+	IngressRate int64 `json:"ingress_rate"`
+	EgressRate  int64 `json:"egress_rate"`
+	// Packets dropped by the shaping.
+	IngressDropped uint64 `json:"ingress_dropped"`
+	EgressDropped  uint64 `json:"egress_dropped"`
 }
 
 type Stats struct {
@@ -84,4 +98,5 @@ type Stats struct {
 	CpuStats    CpuStats    `json:"cpu_stats,omitempty"`
 	MemoryStats MemoryStats `json:"memory_stats,omitempty"`
 	BlkioStats  BlkioStats  `json:"blkio_stats,omitempty"`
+	PidsStats   PidsStats   `json:"pids_stats,omitempty"`
 }
@@ -16,6 +16,7 @@ const (
 	Volume     = "volume"
 	User       = "user"
 	Insert     = "insert"
+	StopSignal = "stopsignal"
 )
 
 // Commands is list of all Dockerfile commands
@@ -34,4 +35,5 @@ var Commands = map[string]struct{}{
 	Volume:     {},
 	User:       {},
 	Insert:     {},
+	StopSignal: {},
 }
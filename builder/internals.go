@@ -26,6 +26,7 @@ import (
 	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/chrootarchive"
 	"github.com/docker/docker/pkg/common"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/pkg/symlink"
@@ -456,6 +457,9 @@ func (b *Builder) processImageFrom(img *imagepkg.Image) error {
 	if img.Config != nil {
 		b.Config = img.Config
 	}
+	// images committed before the static addresses were left out of them
+	// would make every step but the first one fail to reserve them
+	b.Config.IPAddress, b.Config.IPv6Address = "", ""
 
 	if len(b.Config.Env) == 0 {
 		b.Config.Env = append(b.Config.Env, "PATH="+daemon.DefaultPathEnv)
@@ -640,8 +644,13 @@ func (b *Builder) addContext(container *daemon.Container, orig, dest string, dec
 		return err
 	}
 
+	rootUID, rootGID, err := b.Daemon.GetRemappedUIDGID()
+	if err != nil {
+		return err
+	}
+
 	if fi.IsDir() {
-		return copyAsDirectory(origPath, destPath, destExists)
+		return copyAsDirectory(origPath, destPath, rootUID, rootGID, destExists)
 	}
 
 	// If we are adding a remote file (or we've been told not to decompress), do not try to untar it
@@ -656,14 +665,16 @@ func (b *Builder) addContext(container *daemon.Container, orig, dest string, dec
 		}
 
 		// try to successfully untar the orig
-		if err := chrootarchive.UntarPath(origPath, tarDest); err == nil {
+		uidMaps, gidMaps := b.Daemon.GetUIDGIDMaps()
+		archiver := chrootarchive.NewArchiver(uidMaps, gidMaps)
+		if err := archiver.UntarPath(origPath, tarDest); err == nil {
 			return nil
 		} else if err != io.EOF {
 			log.Debugf("Couldn't untar %s to %s: %s", origPath, tarDest, err)
 		}
 	}
 
-	if err := os.MkdirAll(path.Dir(destPath), 0755); err != nil {
+	if err := idtools.MkdirAllNewAs(path.Dir(destPath), 0755, rootUID, rootGID); err != nil {
 		return err
 	}
 	if err := chrootarchive.CopyWithTar(origPath, destPath); err != nil {
@@ -675,14 +686,14 @@ func (b *Builder) addContext(container *daemon.Container, orig, dest string, dec
 		resPath = path.Join(destPath, path.Base(origPath))
 	}
 
-	return fixPermissions(origPath, resPath, 0, 0, destExists)
+	return fixPermissions(origPath, resPath, rootUID, rootGID, destExists)
 }
 
-func copyAsDirectory(source, destination string, destExisted bool) error {
+func copyAsDirectory(source, destination string, rootUID, rootGID int, destExisted bool) error {
 	if err := chrootarchive.CopyWithTar(source, destination); err != nil {
 		return err
 	}
-	return fixPermissions(source, destination, 0, 0, destExisted)
+	return fixPermissions(source, destination, rootUID, rootGID, destExisted)
 }
 
 func fixPermissions(source, destination string, uid, gid int, destExisted bool) error {
@@ -7,6 +7,7 @@ import (
 	apiserver "github.com/docker/docker/api/server"
 	"github.com/docker/docker/autogen/dockerversion"
 	"github.com/docker/docker/daemon/networkdriver/bridge"
+	"github.com/docker/docker/daemon/networkdriver/macvlan"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/events"
 	"github.com/docker/docker/pkg/parsers/kernel"
@@ -53,7 +54,10 @@ func remote(eng *engine.Engine) error {
 // These components should be broken off into plugins of their own.
 //
 func daemon(eng *engine.Engine) error {
-	return eng.Register("init_networkdriver", bridge.InitDriver)
+	if err := eng.Register("init_networkdriver", bridge.InitDriver); err != nil {
+		return err
+	}
+	return eng.Register("init_macvlan_driver", macvlan.InitDriver)
 }
 
 // builtins jobs independent of any subsystem
@@ -135,7 +135,7 @@ func main() {
 			usage()
 		}
 
-		err := devices.AddDevice(args[1], args[2])
+		err := devices.AddDevice(args[1], args[2], 0)
 		if err != nil {
 			fmt.Println("Can't create snap device: ", err)
 			os.Exit(1)
@@ -79,8 +79,9 @@ func (daemon *Daemon) Commit(container *Container, repository, tag, comment, aut
 	if container != nil {
 		containerID = container.ID
 		parentImageID = container.ImageID
-		containerConfig = container.Config
+		containerConfig = withoutAddresses(container.Config)
 	}
+	config = withoutAddresses(config)
 
 	img, err := daemon.graph.Create(rwTar, containerID, parentImageID, comment, author, containerConfig, config)
 	if err != nil {
@@ -95,3 +96,14 @@ func (daemon *Daemon) Commit(container *Container, repository, tag, comment, aut
 	}
 	return img, nil
 }
+
+// withoutAddresses returns a copy of config without the static addresses of
+// its container, which the containers created from an image can't share.
+func withoutAddresses(config *runconfig.Config) *runconfig.Config {
+	if config == nil || (config.IPAddress == "" && config.IPv6Address == "") {
+		return config
+	}
+	c := *config
+	c.IPAddress, c.IPv6Address = "", ""
+	return &c
+}
@@ -24,8 +24,10 @@ type Config struct {
 	AutoRestart                 bool
 	Dns                         []string
 	DnsSearch                   []string
+	DnsOptions                  []string
 	EnableIPv6                  bool
 	EnableIptables              bool
+	FirewallBackend             string
 	EnableIpForward             bool
 	EnableIpMasq                bool
 	DefaultIp                   net.IP
@@ -34,6 +36,11 @@ type Config struct {
 	FixedCIDR                   string
 	FixedCIDRv6                 string
 	InterContainerCommunication bool
+	EnableUserlandProxy         bool
+	MacvlanParent               string
+	MacvlanSubnet               string
+	MacvlanGateway              string
+	MacvlanIPRange              string
 	GraphDriver                 string
 	GraphOptions                []string
 	ExecDriver                  string
@@ -47,6 +54,8 @@ type Config struct {
 	TrustKeyPath                string
 	Labels                      []string
 	Ulimits                     map[string]*ulimit.Ulimit
+	RemappedRoot                string
+	Init                        bool
 }
 
 // InstallFlags adds command-line options to the top-level flag parser for
@@ -58,6 +67,7 @@ func (config *Config) InstallFlags() {
 	flag.StringVar(&config.Root, []string{"g", "-graph"}, "/var/lib/docker", "Root of the Docker runtime")
 	flag.BoolVar(&config.AutoRestart, []string{"#r", "#-restart"}, true, "--restart on the daemon has been deprecated in favor of --restart policies on docker run")
 	flag.BoolVar(&config.EnableIptables, []string{"#iptables", "-iptables"}, true, "Enable addition of iptables rules")
+	flag.StringVar(&config.FirewallBackend, []string{"-firewall-backend"}, "iptables", "Backend of the firewall rules, iptables or nftables")
 	flag.BoolVar(&config.EnableIpForward, []string{"#ip-forward", "-ip-forward"}, true, "Enable net.ipv4.ip_forward")
 	flag.BoolVar(&config.EnableIpMasq, []string{"-ip-masq"}, true, "Enable IP masquerading")
 	flag.BoolVar(&config.EnableIPv6, []string{"-ipv6"}, false, "Enable IPv6 networking")
@@ -66,6 +76,11 @@ func (config *Config) InstallFlags() {
 	flag.StringVar(&config.FixedCIDR, []string{"-fixed-cidr"}, "", "IPv4 subnet for fixed IPs")
 	flag.StringVar(&config.FixedCIDRv6, []string{"-fixed-cidr-v6"}, "", "IPv6 subnet for fixed IPs")
 	flag.BoolVar(&config.InterContainerCommunication, []string{"#icc", "-icc"}, true, "Enable inter-container communication")
+	flag.BoolVar(&config.EnableUserlandProxy, []string{"-userland-proxy"}, true, "Use userland proxy for the published ports")
+	flag.StringVar(&config.MacvlanParent, []string{"-macvlan-parent"}, "", "Parent interface of the macvlan network mode")
+	flag.StringVar(&config.MacvlanSubnet, []string{"-macvlan-subnet"}, "", "IPv4 subnet of the macvlan network mode")
+	flag.StringVar(&config.MacvlanGateway, []string{"-macvlan-gateway"}, "", "IPv4 gateway of the macvlan network mode")
+	flag.StringVar(&config.MacvlanIPRange, []string{"-macvlan-ip-range"}, "", "IPv4 subnet for the macvlan IPs")
 	flag.StringVar(&config.GraphDriver, []string{"s", "-storage-driver"}, "", "Storage driver to use")
 	flag.StringVar(&config.ExecDriver, []string{"e", "-exec-driver"}, "native", "Exec driver to use")
 	flag.BoolVar(&config.EnableSelinuxSupport, []string{"-selinux-enabled"}, false, "Enable selinux support")
@@ -73,11 +88,14 @@ func (config *Config) InstallFlags() {
 	flag.StringVar(&config.SocketGroup, []string{"G", "-group"}, "docker", "Group for the unix socket")
 	flag.BoolVar(&config.EnableCors, []string{"#api-enable-cors", "#-api-enable-cors"}, false, "Enable CORS headers in the remote API, this is deprecated by --api-cors-header")
 	flag.StringVar(&config.CorsHeaders, []string{"-api-cors-header"}, "", "Set CORS headers in the remote API")
+	flag.StringVar(&config.RemappedRoot, []string{"-userns-remap"}, "", "User/Group setting for user namespaces")
+	flag.BoolVar(&config.Init, []string{"-init"}, false, "Run an init in containers to forward signals and reap processes")
 	opts.IPVar(&config.DefaultIp, []string{"#ip", "-ip"}, "0.0.0.0", "Default IP when binding container ports")
 	opts.ListVar(&config.GraphOptions, []string{"-storage-opt"}, "Set storage driver options")
 	// FIXME: why the inconsistency between "hosts" and "sockets"?
 	opts.IPListVar(&config.Dns, []string{"#dns", "-dns"}, "DNS server to use")
 	opts.DnsSearchListVar(&config.DnsSearch, []string{"-dns-search"}, "DNS search domains to use")
+	opts.DnsOptListVar(&config.DnsOptions, []string{"-dns-opt"}, "DNS resolver options to use")
 	opts.LabelListVar(&config.Labels, []string{"-label"}, "Set key=value labels to the daemon")
 	config.Ulimits = make(map[string]*ulimit.Ulimit)
 	opts.UlimitMapVar(config.Ulimits, []string{"-default-ulimit"}, "Set default ulimits for containers")
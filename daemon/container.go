@@ -10,7 +10,9 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -19,6 +21,7 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/daemon/networkdriver/remote"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/links"
@@ -31,6 +34,7 @@ import (
 	"github.com/docker/docker/pkg/networkfs/etchosts"
 	"github.com/docker/docker/pkg/networkfs/resolvconf"
 	"github.com/docker/docker/pkg/promise"
+	"github.com/docker/docker/pkg/signal"
 	"github.com/docker/docker/pkg/symlink"
 	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/docker/runconfig"
@@ -84,6 +88,8 @@ type Container struct {
 	daemon                   *Daemon
 	MountLabel, ProcessLabel string
 	AppArmorProfile          string
+	SeccompProfile           string
+	AppliedSeccompProfile    string // the seccomp profile of the last start
 	RestartCount             int
 	UpdateDns                bool
 
@@ -96,7 +102,13 @@ type Container struct {
 	VolumesRW  map[string]bool
 	hostConfig *runconfig.HostConfig
 
+	// linksLock protects activeLinks, which the children of the container
+	// update when their IP changes. It is not the lock of the State, which
+	// the container holds while it starts its links.
+	linksLock          sync.Mutex
 	activeLinks        map[string]*links.Link
+	hostInterfaceName  string // interface of the network driver plugin to move inside the container
+	vethHost           string // host side of the veth pair of the container on the bridge
 	monitor            *containerMonitor
 	execCommands       *execStore
 	AppliedVolumesFrom map[string]struct{}
@@ -214,11 +226,11 @@ func populateCommand(c *Container, env []string) error {
 	}
 
 	parts := strings.SplitN(string(c.hostConfig.NetworkMode), ":", 2)
-	switch parts[0] {
-	case "none":
-	case "host":
+	switch {
+	case parts[0] == "none":
+	case parts[0] == "host":
 		en.HostNetworking = true
-	case "bridge", "": // empty string to support existing containers
+	case parts[0] == "bridge", parts[0] == "", parts[0] == "macvlan", c.hostConfig.NetworkMode.IsPlugin(): // empty string to support existing containers
 		if !c.Config.NetworkDisabled {
 			network := c.NetworkSettings
 			en.Interface = &execdriver.NetworkInterface{
@@ -231,9 +243,15 @@ func populateCommand(c *Container, env []string) error {
 				GlobalIPv6Address:    network.GlobalIPv6Address,
 				GlobalIPv6PrefixLen:  network.GlobalIPv6PrefixLen,
 				IPv6Gateway:          network.IPv6Gateway,
+				HairpinMode:          network.HairpinMode,
+			}
+			if c.hostConfig.NetworkMode.IsMacvlan() {
+				en.Interface.MacvlanParent = c.daemon.config.MacvlanParent
 			}
+			en.Interface.HostInterfaceName = c.hostInterfaceName
+			en.Interface.VethHost = c.vethHost
 		}
-	case "container":
+	case parts[0] == "container":
 		nc, err := c.getNetworkedContainer()
 		if err != nil {
 			return err
@@ -306,6 +324,7 @@ func populateCommand(c *Container, env []string) error {
 		CpuShares:  c.Config.CpuShares,
 		Cpuset:     c.Config.Cpuset,
 		Rlimits:    rlimits,
+		PidsLimit:  c.hostConfig.PidsLimit,
 	}
 
 	processConfig := execdriver.ProcessConfig{
@@ -319,6 +338,8 @@ func populateCommand(c *Container, env []string) error {
 	processConfig.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
 	processConfig.Env = env
 
+	uidMap, gidMap := c.daemon.GetUIDGIDMaps()
+
 	c.command = &execdriver.Command{
 		ID:                 c.ID,
 		Rootfs:             c.RootfsPath(),
@@ -338,6 +359,10 @@ func populateCommand(c *Container, env []string) error {
 		MountLabel:         c.GetMountLabel(),
 		LxcConfig:          lxcConfig,
 		AppArmorProfile:    c.AppArmorProfile,
+		SeccompProfile:     c.SeccompProfile,
+		Init:               c.initEnabled(),
+		UIDMapping:         uidMap,
+		GIDMapping:         gidMap,
 	}
 
 	return nil
@@ -515,6 +540,12 @@ func (container *Container) AllocateNetwork() error {
 	if container.Config.NetworkDisabled || !mode.IsPrivate() {
 		return nil
 	}
+	if mode.IsMacvlan() {
+		return container.allocateMacvlanNetwork()
+	}
+	if mode.IsPlugin() {
+		return container.allocatePluginNetwork()
+	}
 
 	var (
 		env *engine.Env
@@ -523,6 +554,8 @@ func (container *Container) AllocateNetwork() error {
 	)
 
 	job := eng.Job("allocate_interface", container.ID)
+	job.Setenv("RequestedIP", container.Config.IPAddress)
+	job.Setenv("RequestedIPv6", container.Config.IPv6Address)
 	job.Setenv("RequestedMac", container.Config.MacAddress)
 	if env, err = job.Stdout.AddEnv(); err != nil {
 		return err
@@ -535,15 +568,96 @@ func (container *Container) AllocateNetwork() error {
 	// make sure that it is always released in case of error, otherwise we
 	// might leak resources.
 
-	if container.Config.PortSpecs != nil {
-		if err = migratePortMappings(container.Config, container.hostConfig); err != nil {
+	portSpecs, bindings, err := container.portsToPublish()
+	if err != nil {
+		eng.Job("release_interface", container.ID).Run()
+		return err
+	}
+
+	for port := range portSpecs {
+		if err = container.allocatePort(eng, port, bindings); err != nil {
 			eng.Job("release_interface", container.ID).Run()
 			return err
 		}
+	}
+	container.WriteHostConfig()
+
+	container.NetworkSettings.Ports = bindings
+	container.NetworkSettings.Bridge = env.Get("Bridge")
+	container.NetworkSettings.IPAddress = env.Get("IP")
+	container.NetworkSettings.IPPrefixLen = env.GetInt("IPPrefixLen")
+	container.NetworkSettings.MacAddress = env.Get("MacAddress")
+	container.NetworkSettings.Gateway = env.Get("Gateway")
+	container.NetworkSettings.LinkLocalIPv6Address = env.Get("LinkLocalIPv6")
+	container.NetworkSettings.LinkLocalIPv6PrefixLen = 64
+	container.NetworkSettings.GlobalIPv6Address = env.Get("GlobalIPv6")
+	container.NetworkSettings.GlobalIPv6PrefixLen = env.GetInt("GlobalIPv6PrefixLen")
+	container.NetworkSettings.IPv6Gateway = env.Get("IPv6Gateway")
+	container.NetworkSettings.HairpinMode = env.GetBool("HairpinMode")
+	container.vethHost = env.Get("VethHost")
+
+	return nil
+}
+
+// isNetworkShaped returns whether the traffic of the container can be shaped,
+// which requires a veth pair on the bridge.
+func (container *Container) isNetworkShaped() bool {
+	return container.vethHost != ""
+}
+
+// setNetworkRate shapes the traffic of the running container to the rates of
+// its host config.
+func (container *Container) setNetworkRate() error {
+	ingressRate, egressRate := container.hostConfig.NetworkIngressRate, container.hostConfig.NetworkEgressRate
+	if !container.isNetworkShaped() {
+		if ingressRate != 0 || egressRate != 0 {
+			return fmt.Errorf("Network rates require the bridge network mode")
+		}
+		return nil
+	}
+
+	job := container.daemon.eng.Job("set_interface_rate", container.ID)
+	job.SetenvInt64("IngressRate", ingressRate)
+	job.SetenvInt64("EgressRate", egressRate)
+	return job.Run()
+}
+
+// networkShaping returns the shaping applied to the traffic of the running
+// container, nil if it isn't shaped.
+func (container *Container) networkShaping() (*NetworkShaping, error) {
+	if !container.isNetworkShaped() {
+		return nil, nil
+	}
+
+	job := container.daemon.eng.Job("interface_shaping", container.ID)
+	env, err := job.Stdout.AddEnv()
+	if err != nil {
+		return nil, err
+	}
+	if err := job.Run(); err != nil {
+		return nil, err
+	}
+	if env.GetInt64("IngressRate") == 0 && env.GetInt64("EgressRate") == 0 {
+		return nil, nil
+	}
+	return &NetworkShaping{
+		IngressRate:    env.GetInt64("IngressRate"),
+		EgressRate:     env.GetInt64("EgressRate"),
+		IngressDropped: uint64(env.GetInt64("IngressDropped")),
+		EgressDropped:  uint64(env.GetInt64("EgressDropped")),
+	}, nil
+}
+
+// portsToPublish returns the ports of the container, and the host ports
+// requested for them.
+func (container *Container) portsToPublish() (nat.PortSet, nat.PortMap, error) {
+	if container.Config.PortSpecs != nil {
+		if err := migratePortMappings(container.Config, container.hostConfig); err != nil {
+			return nil, nil, err
+		}
 		container.Config.PortSpecs = nil
-		if err = container.WriteHostConfig(); err != nil {
-			eng.Job("release_interface", container.ID).Run()
-			return err
+		if err := container.WriteHostConfig(); err != nil {
+			return nil, nil, err
 		}
 	}
 
@@ -570,41 +684,160 @@ func (container *Container) AllocateNetwork() error {
 
 	container.NetworkSettings.PortMapping = nil
 
+	return portSpecs, bindings, nil
+}
+
+// allocateMacvlanNetwork allocates an address of the macvlan network to the
+// container. No port is published, the container is reached directly on the
+// network of the parent interface.
+func (container *Container) allocateMacvlanNetwork() error {
+	if container.daemon.config.MacvlanParent == "" {
+		return fmt.Errorf("The macvlan network mode requires the daemon to run with --macvlan-parent")
+	}
+
+	job := container.daemon.eng.Job("allocate_macvlan_interface", container.ID)
+	job.Setenv("RequestedIP", container.Config.IPAddress)
+	job.Setenv("RequestedMac", container.Config.MacAddress)
+	env, err := job.Stdout.AddEnv()
+	if err != nil {
+		return err
+	}
+	if err := job.Run(); err != nil {
+		return err
+	}
+
+	container.NetworkSettings.IPAddress = env.Get("IP")
+	container.NetworkSettings.IPPrefixLen = env.GetInt("IPPrefixLen")
+	container.NetworkSettings.MacAddress = env.Get("MacAddress")
+	container.NetworkSettings.Gateway = env.Get("Gateway")
+
+	return nil
+}
+
+// allocatePluginNetwork creates the endpoint of the container with the
+// network driver plugin named by its network mode, which also gives the
+// interface to move inside the container and publishes its ports.
+func (container *Container) allocatePluginNetwork() error {
+	driver, err := remote.Lookup(string(container.hostConfig.NetworkMode))
+	if err != nil {
+		return fmt.Errorf("Unable to find the network driver %s: %s", container.hostConfig.NetworkMode, err)
+	}
+
+	ep, err := driver.CreateEndpoint(container.ID, container.Config.IPAddress, container.Config.IPv6Address, container.Config.MacAddress)
+	if err != nil {
+		return err
+	}
+
+	// Deleting the endpoint on error releases its ports too
+	if container.hostInterfaceName, err = driver.Join(container.ID); err != nil {
+		driver.DeleteEndpoint(container.ID)
+		return err
+	}
+
+	portSpecs, bindings, err := container.portsToPublish()
+	if err != nil {
+		driver.DeleteEndpoint(container.ID)
+		return err
+	}
 	for port := range portSpecs {
-		if err = container.allocatePort(eng, port, bindings); err != nil {
-			eng.Job("release_interface", container.ID).Run()
+		if err := container.publishPluginPort(driver, port, bindings); err != nil {
+			driver.DeleteEndpoint(container.ID)
 			return err
 		}
 	}
 	container.WriteHostConfig()
 
 	container.NetworkSettings.Ports = bindings
-	container.NetworkSettings.Bridge = env.Get("Bridge")
-	container.NetworkSettings.IPAddress = env.Get("IP")
-	container.NetworkSettings.IPPrefixLen = env.GetInt("IPPrefixLen")
-	container.NetworkSettings.MacAddress = env.Get("MacAddress")
-	container.NetworkSettings.Gateway = env.Get("Gateway")
-	container.NetworkSettings.LinkLocalIPv6Address = env.Get("LinkLocalIPv6")
-	container.NetworkSettings.LinkLocalIPv6PrefixLen = 64
-	container.NetworkSettings.GlobalIPv6Address = env.Get("GlobalIPv6")
-	container.NetworkSettings.GlobalIPv6PrefixLen = env.GetInt("GlobalIPv6PrefixLen")
-	container.NetworkSettings.IPv6Gateway = env.Get("IPv6Gateway")
+	container.NetworkSettings.IPAddress = ep.IPAddress
+	container.NetworkSettings.IPPrefixLen = ep.IPPrefixLen
+	container.NetworkSettings.MacAddress = ep.MacAddress
+	container.NetworkSettings.Gateway = ep.Gateway
+	container.NetworkSettings.GlobalIPv6Address = ep.GlobalIPv6Address
+	container.NetworkSettings.GlobalIPv6PrefixLen = ep.GlobalIPv6PrefixLen
+	container.NetworkSettings.IPv6Gateway = ep.IPv6Gateway
 
 	return nil
 }
 
+// releasePluginNetwork deletes the endpoint of the container with its
+// network driver plugin.
+func (container *Container) releasePluginNetwork() {
+	if !container.isNetworkAllocated() {
+		return
+	}
+	driver, err := remote.Lookup(string(container.hostConfig.NetworkMode))
+	if err != nil {
+		log.Errorf("%v: Unable to find the network driver %s: %s", container.ID, container.hostConfig.NetworkMode, err)
+		return
+	}
+	if err := driver.Leave(container.ID); err != nil {
+		log.Errorf("%v: Failed to leave the network: %s", container.ID, err)
+	}
+	if err := driver.DeleteEndpoint(container.ID); err != nil {
+		log.Errorf("%v: Failed to delete the network endpoint: %s", container.ID, err)
+	}
+}
+
 func (container *Container) ReleaseNetwork() {
 	if container.Config.NetworkDisabled || !container.hostConfig.NetworkMode.IsPrivate() {
 		return
 	}
+	if container.hostConfig.NetworkMode.IsPlugin() {
+		container.releasePluginNetwork()
+		container.hostInterfaceName = ""
+		container.NetworkSettings = &NetworkSettings{}
+		return
+	}
 	eng := container.daemon.eng
 
-	job := eng.Job("release_interface", container.ID)
+	release := "release_interface"
+	if container.hostConfig.NetworkMode.IsMacvlan() {
+		release = "release_macvlan_interface"
+	}
+	job := eng.Job(release, container.ID)
 	job.SetenvBool("overrideShutdown", true)
 	job.Run()
+	container.vethHost = ""
 	container.NetworkSettings = &NetworkSettings{}
 }
 
+// reserveAddresses reserves the static addresses requested for the container
+// on the bridge, so they can't be handed out to other containers while this
+// one exists. The static addresses of the macvlan network, and of network
+// driver plugins, are checked when they are allocated.
+func (container *Container) reserveAddresses() error {
+	if container.Config.IPAddress == "" && container.Config.IPv6Address == "" {
+		return nil
+	}
+	if container.hostConfig.NetworkMode.IsMacvlan() || container.hostConfig.NetworkMode.IsPlugin() {
+		return nil
+	}
+	if container.Config.NetworkDisabled || container.daemon.config.DisableNetwork {
+		return fmt.Errorf("Static IP addresses require networking to be enabled")
+	}
+
+	job := container.daemon.eng.Job("reserve_ip", container.ID)
+	job.Setenv("IP", container.Config.IPAddress)
+	job.Setenv("IPv6", container.Config.IPv6Address)
+	return job.Run()
+}
+
+// releaseAddresses releases the static addresses reserved for the container.
+func (container *Container) releaseAddresses() {
+	if container.Config.IPAddress == "" && container.Config.IPv6Address == "" {
+		return
+	}
+	if container.hostConfig.NetworkMode.IsMacvlan() || container.hostConfig.NetworkMode.IsPlugin() {
+		return
+	}
+	if container.Config.NetworkDisabled || container.daemon.config.DisableNetwork {
+		return
+	}
+	if err := container.daemon.eng.Job("unreserve_ip", container.ID).Run(); err != nil {
+		log.Errorf("%v: Failed to release static IP addresses: %v", container.ID, err)
+	}
+}
+
 func (container *Container) isNetworkAllocated() bool {
 	return container.NetworkSettings.IPAddress != ""
 }
@@ -617,12 +850,22 @@ func (container *Container) RestoreNetwork() error {
 	if !container.isNetworkAllocated() || container.Config.NetworkDisabled || !mode.IsPrivate() {
 		return nil
 	}
+	// network driver plugins keep the endpoints of the containers themselves
+	if mode.IsPlugin() {
+		return nil
+	}
 
 	eng := container.daemon.eng
 
+	allocate := "allocate_interface"
+	if mode.IsMacvlan() {
+		allocate = "allocate_macvlan_interface"
+	}
+
 	// Re-allocate the interface with the same IP and MAC address.
-	job := eng.Job("allocate_interface", container.ID)
+	job := eng.Job(allocate, container.ID)
 	job.Setenv("RequestedIP", container.NetworkSettings.IPAddress)
+	job.Setenv("RequestedIPv6", container.NetworkSettings.GlobalIPv6Address)
 	job.Setenv("RequestedMac", container.NetworkSettings.MacAddress)
 	if err := job.Run(); err != nil {
 		return err
@@ -643,11 +886,13 @@ func (container *Container) cleanup() {
 	container.ReleaseNetwork()
 
 	// Disable all active links
+	container.linksLock.Lock()
 	if container.activeLinks != nil {
 		for _, link := range container.activeLinks {
 			link.Disable()
 		}
 	}
+	container.linksLock.Unlock()
 
 	if err := container.Unmount(); err != nil {
 		log.Errorf("%v: Failed to umount filesystem: %v", container.ID, err)
@@ -749,9 +994,10 @@ func (container *Container) Stop(seconds int) error {
 		return nil
 	}
 
-	// 1. Send a SIGTERM
-	if err := container.killPossiblyDeadProcess(15); err != nil {
-		log.Infof("Failed to send SIGTERM to the process, force killing")
+	// 1. Send the stop signal, SIGTERM by default
+	stopSignal := container.StopSignal()
+	if err := container.killPossiblyDeadProcess(stopSignal); err != nil {
+		log.Infof("Failed to send signal %d to the process, force killing", stopSignal)
 		if err := container.killPossiblyDeadProcess(9); err != nil {
 			return err
 		}
@@ -759,7 +1005,7 @@ func (container *Container) Stop(seconds int) error {
 
 	// 2. Wait for the process to exit on its own
 	if _, err := container.WaitStop(time.Duration(seconds) * time.Second); err != nil {
-		log.Infof("Container %v failed to exit within %d seconds of SIGTERM - using the force", container.ID, seconds)
+		log.Infof("Container %v failed to exit within %d seconds of signal %d - using the force", container.ID, seconds, stopSignal)
 		// 3. If it doesn't, then send SIGKILL
 		if err := container.Kill(); err != nil {
 			container.WaitStop(-1 * time.Second)
@@ -769,6 +1015,25 @@ func (container *Container) Stop(seconds int) error {
 	return nil
 }
 
+// initEnabled returns whether an init runs as PID 1 of the container, which
+// is the daemon default unless set with --init.
+func (container *Container) initEnabled() bool {
+	if container.hostConfig.Init != nil {
+		return *container.hostConfig.Init
+	}
+	return container.daemon.config.Init
+}
+
+// StopSignal returns the signal sent to stop the container: the one set by
+// --stop-signal or STOPSIGNAL, or SIGTERM.
+func (container *Container) StopSignal() int {
+	stopSignal, err := signal.ParseSignal(container.Config.StopSignal)
+	if err != nil {
+		stopSignal, _ = signal.ParseSignal(signal.DefaultStopSignal)
+	}
+	return int(stopSignal)
+}
+
 func (container *Container) Restart(seconds int) error {
 	// Avoid unnecessarily unmounting and then directly mounting
 	// the container when the container stops and then starts
@@ -815,7 +1080,12 @@ func (container *Container) Export() (archive.Archive, error) {
 		return nil, err
 	}
 
-	archive, err := archive.Tar(container.basefs, archive.Uncompressed)
+	uidMaps, gidMaps := container.daemon.GetUIDGIDMaps()
+	archive, err := archive.TarWithOptions(container.basefs, &archive.TarOptions{
+		Compression: archive.Uncompressed,
+		UIDMaps:     uidMaps,
+		GIDMaps:     gidMaps,
+	})
 	if err != nil {
 		container.Unmount()
 		return nil, err
@@ -950,9 +1220,12 @@ func (container *Container) Copy(resource string) (io.ReadCloser, error) {
 		basePath = path.Dir(basePath)
 	}
 
+	uidMaps, gidMaps := container.daemon.GetUIDGIDMaps()
 	archive, err := archive.TarWithOptions(basePath, &archive.TarOptions{
 		Compression:  archive.Uncompressed,
 		IncludeFiles: filter,
+		UIDMaps:      uidMaps,
+		GIDMaps:      gidMaps,
 	})
 	if err != nil {
 		container.Unmount()
@@ -994,6 +1267,8 @@ func (container *Container) SetHostConfig(hostConfig *runconfig.HostConfig) {
 }
 
 func (container *Container) DisableLink(name string) {
+	container.linksLock.Lock()
+	defer container.linksLock.Unlock()
 	if container.activeLinks != nil {
 		if link, exists := container.activeLinks[name]; exists {
 			link.Disable()
@@ -1005,29 +1280,41 @@ func (container *Container) DisableLink(name string) {
 
 func (container *Container) setupContainerDns() error {
 	if container.ResolvConfPath != "" {
-		// check if this is an existing container that needs DNS update:
-		if container.UpdateDns {
-			// read the host's resolv.conf, get the hash and call updateResolvConf
-			log.Debugf("Check container (%s) for update to resolv.conf - UpdateDns flag was set", container.ID)
-			latestResolvConf, latestHash := resolvconf.GetLastModified()
-
-			// clean container resolv.conf re: localhost nameservers and IPv6 NS (if IPv6 disabled)
-			updatedResolvConf, modified := resolvconf.FilterResolvDns(latestResolvConf, container.daemon.config.EnableIPv6)
-			if modified {
-				// changes have occurred during resolv.conf localhost cleanup: generate an updated hash
-				newHash, err := utils.HashData(bytes.NewReader(updatedResolvConf))
-				if err != nil {
-					return err
-				}
-				latestHash = newHash
-			}
+		// the resolv.conf of the container whose network is shared is
+		// that container's to update
+		if container.hostConfig.NetworkMode.IsContainer() {
+			return nil
+		}
 
-			if err := container.updateResolvConf(updatedResolvConf, latestHash); err != nil {
-				return err
-			}
-			// successful update of the restarting container; set the flag off
+		// the host's resolv.conf may have changed while the container was
+		// running (the UpdateDns flag is set then), or while the daemon
+		// was down: regenerate the container's resolv.conf from the
+		// current one if its hash changed, unless the container modified it
+		log.Debugf("Check container (%s) for update to resolv.conf", container.ID)
+		latestResolvConf, err := resolvconf.Get()
+		if err != nil {
+			return err
+		}
+
+		// clean container resolv.conf re: localhost nameservers and IPv6 NS (if IPv6 disabled)
+		updatedResolvConf := latestResolvConf
+		if container.hostConfig.NetworkMode != "host" {
+			updatedResolvConf, _ = resolvconf.FilterResolvDns(latestResolvConf, container.daemon.config.EnableIPv6)
+		}
+		latestHash, err := utils.HashData(bytes.NewReader(updatedResolvConf))
+		if err != nil {
+			return err
+		}
+		if hash, err := ioutil.ReadFile(container.ResolvConfPath + ".hash"); err == nil && string(hash) == latestHash {
 			container.UpdateDns = false
+			return nil
+		}
+
+		if err := container.updateResolvConf(updatedResolvConf, latestHash); err != nil {
+			return err
 		}
+		// successful update of the restarting container; set the flag off
+		container.UpdateDns = false
 		return nil
 	}
 
@@ -1045,33 +1332,22 @@ func (container *Container) setupContainerDns() error {
 		return err
 	}
 
+	//get a sha256 hash of the resolv conf at this point so we can check
+	//for changes when the host resolv.conf changes (e.g. network update)
+	var resolvHash string
 	if config.NetworkMode != "host" {
+		// replace any localhost/127.*, and remove IPv6 nameservers if IPv6 disabled in daemon
+		resolvConf, _ = resolvconf.FilterResolvDns(resolvConf, daemon.config.EnableIPv6)
+
 		// check configurations for any container/daemon dns settings
-		if len(config.Dns) > 0 || len(daemon.config.Dns) > 0 || len(config.DnsSearch) > 0 || len(daemon.config.DnsSearch) > 0 {
-			var (
-				dns       = resolvconf.GetNameservers(resolvConf)
-				dnsSearch = resolvconf.GetSearchDomains(resolvConf)
-			)
-			if len(config.Dns) > 0 {
-				dns = config.Dns
-			} else if len(daemon.config.Dns) > 0 {
-				dns = daemon.config.Dns
-			}
-			if len(config.DnsSearch) > 0 {
-				dnsSearch = config.DnsSearch
-			} else if len(daemon.config.DnsSearch) > 0 {
-				dnsSearch = daemon.config.DnsSearch
+		if dns, dnsSearch, dnsOptions, custom := container.dnsSettings(resolvConf); custom {
+			if resolvHash, err = resolvconf.Build(container.ResolvConfPath, dns, dnsSearch, dnsOptions); err != nil {
+				return err
 			}
-			return resolvconf.Build(container.ResolvConfPath, dns, dnsSearch)
+			return ioutil.WriteFile(container.ResolvConfPath+".hash", []byte(resolvHash), 0644)
 		}
-
-		// replace any localhost/127.*, and remove IPv6 nameservers if IPv6 disabled in daemon
-		resolvConf, _ = resolvconf.FilterResolvDns(resolvConf, daemon.config.EnableIPv6)
 	}
-	//get a sha256 hash of the resolv conf at this point so we can check
-	//for changes when the host resolv.conf changes (e.g. network update)
-	resolvHash, err := utils.HashData(bytes.NewReader(resolvConf))
-	if err != nil {
+	if resolvHash, err = utils.HashData(bytes.NewReader(resolvConf)); err != nil {
 		return err
 	}
 	resolvHashFile := container.ResolvConfPath + ".hash"
@@ -1081,12 +1357,37 @@ func (container *Container) setupContainerDns() error {
 	return ioutil.WriteFile(container.ResolvConfPath, resolvConf, 0644)
 }
 
+// dnsSettings returns the nameservers, search domains and resolver options
+// of the container: its own ones, else the daemon's, else the ones of
+// resolvConf. custom is set when any of them isn't from resolvConf.
+func (container *Container) dnsSettings(resolvConf []byte) (dns, dnsSearch, dnsOptions []string, custom bool) {
+	var (
+		config = container.hostConfig
+		daemon = container.daemon
+	)
+	pick := func(values, defaults, host []string) []string {
+		switch {
+		case len(values) > 0:
+			custom = true
+			return values
+		case len(defaults) > 0:
+			custom = true
+			return defaults
+		}
+		return host
+	}
+	dns = pick(config.Dns, daemon.config.Dns, resolvconf.GetNameservers(resolvConf))
+	dnsSearch = pick(config.DnsSearch, daemon.config.DnsSearch, resolvconf.GetSearchDomains(resolvConf))
+	dnsOptions = pick(config.DnsOptions, daemon.config.DnsOptions, resolvconf.GetOptions(resolvConf))
+	return
+}
+
 // called when the host's resolv.conf changes to check whether container's resolv.conf
 // is unchanged by the container "user" since container start: if unchanged, the
 // container's resolv.conf will be updated to match the host's new resolv.conf
 func (container *Container) updateResolvConf(updatedResolvConf []byte, newResolvHash string) error {
 
-	if container.ResolvConfPath == "" {
+	if container.ResolvConfPath == "" || container.hostConfig.NetworkMode.IsContainer() {
 		return nil
 	}
 	if container.Running {
@@ -1137,11 +1438,16 @@ func (container *Container) updateResolvConf(updatedResolvConf []byte, newResolv
 			return err
 		}
 
-		// write the updates to the temp files
-		if err = ioutil.WriteFile(tmpHashFile.Name(), []byte(newResolvHash), 0644); err != nil {
+		// write the updates to the temp files; the container's own dns
+		// settings are applied to the updated host resolv.conf
+		if dns, dnsSearch, dnsOptions, custom := container.dnsSettings(updatedResolvConf); custom && container.hostConfig.NetworkMode != "host" {
+			if newResolvHash, err = resolvconf.Build(tmpResolvFile.Name(), dns, dnsSearch, dnsOptions); err != nil {
+				return err
+			}
+		} else if err = ioutil.WriteFile(tmpResolvFile.Name(), updatedResolvConf, 0644); err != nil {
 			return err
 		}
-		if err = ioutil.WriteFile(tmpResolvFile.Name(), updatedResolvConf, 0644); err != nil {
+		if err = ioutil.WriteFile(tmpHashFile.Name(), []byte(newResolvHash), 0644); err != nil {
 			return err
 		}
 
@@ -1154,6 +1460,9 @@ func (container *Container) updateResolvConf(updatedResolvConf []byte, newResolv
 	return nil
 }
 
+// updateParentsHosts points the containers linking to the container at its
+// new IP: the records of the link in their /etc/hosts, and the rules of the
+// link of the running ones. Each parent updated gets a link_update event.
 func (container *Container) updateParentsHosts() error {
 	refs := container.daemon.ContainerGraph().RefPaths(container.ID)
 	for _, ref := range refs {
@@ -1170,7 +1479,17 @@ func (container *Container) updateParentsHosts() error {
 			log.Debugf("Update /etc/hosts of %s for alias %s with ip %s", c.ID, ref.Name, container.NetworkSettings.IPAddress)
 			if err := etchosts.Update(c.HostsPath, container.NetworkSettings.IPAddress, ref.Name); err != nil {
 				log.Errorf("Failed to update /etc/hosts in parent container %s for alias %s: %v", c.ID, ref.Name, err)
+				continue
+			}
+			// the rules of the link still allow the previous IP
+			c.linksLock.Lock()
+			if link, exists := c.activeLinks[ref.Name]; exists && link.IsEnabled {
+				if err := link.Update(c.NetworkSettings.IPAddress, container.NetworkSettings.IPAddress); err != nil {
+					log.Errorf("Failed to update the link of parent container %s for alias %s: %v", c.ID, ref.Name, err)
+				}
 			}
+			c.linksLock.Unlock()
+			c.LogEvent("link_update")
 		}
 	}
 	return nil
@@ -1221,7 +1540,7 @@ func (container *Container) initializeNetworking() error {
 		container.Config.Domainname = nc.Config.Domainname
 		return nil
 	}
-	if container.daemon.config.DisableNetwork {
+	if container.daemon.config.DisableNetwork && !container.hostConfig.NetworkMode.IsMacvlan() && !container.hostConfig.NetworkMode.IsPlugin() {
 		container.Config.NetworkDisabled = true
 		return container.buildHostnameAndHostsFiles("127.0.1.1")
 	}
@@ -1241,6 +1560,10 @@ func (container *Container) verifyDaemonSettings() {
 		log.Infof("WARNING: Your kernel does not support swap limit capabilities. Limitation discarded.")
 		container.Config.MemorySwap = -1
 	}
+	if container.hostConfig.PidsLimit != 0 && !container.daemon.sysInfo.PidsLimit {
+		log.Infof("WARNING: Your kernel does not support pids limit capabilities. Limitation discarded.")
+		container.hostConfig.PidsLimit = 0
+	}
 	if container.daemon.sysInfo.IPv4ForwardingDisabled {
 		log.Infof("WARNING: IPv4 forwarding is disabled. Networking will not work")
 	}
@@ -1257,41 +1580,34 @@ func (container *Container) setupLinkedContainers() ([]string, error) {
 	}
 
 	if len(children) > 0 {
+		container.linksLock.Lock()
 		container.activeLinks = make(map[string]*links.Link, len(children))
+		container.linksLock.Unlock()
 
 		// If we encounter an error make sure that we rollback any network
 		// config and iptables changes
 		rollback := func() {
+			container.linksLock.Lock()
 			for _, link := range container.activeLinks {
 				link.Disable()
 			}
 			container.activeLinks = nil
+			container.linksLock.Unlock()
 		}
 
 		for linkAlias, child := range children {
+			// IsRunning takes the lock of the child, which holds it while
+			// updating the links of its parents: check it without ours
 			if !child.IsRunning() {
 				return nil, fmt.Errorf("Cannot link to a non running container: %s AS %s", child.Name, linkAlias)
 			}
 
-			link, err := links.NewLink(
-				container.NetworkSettings.IPAddress,
-				child.NetworkSettings.IPAddress,
-				linkAlias,
-				child.Config.Env,
-				child.Config.ExposedPorts,
-				daemon.eng)
-
+			link, err := container.addLink(linkAlias, child)
 			if err != nil {
 				rollback()
 				return nil, err
 			}
 
-			container.activeLinks[link.Alias()] = link
-			if err := link.Enable(); err != nil {
-				rollback()
-				return nil, err
-			}
-
 			for _, envVar := range link.ToEnv() {
 				env = append(env, envVar)
 			}
@@ -1300,6 +1616,31 @@ func (container *Container) setupLinkedContainers() ([]string, error) {
 	return env, nil
 }
 
+// addLink creates and enables the link alias to child. The IP of child is
+// read under the lock of the links, so that an update of the links for a
+// new IP of child either sees the link or happens before it is created.
+func (container *Container) addLink(alias string, child *Container) (*links.Link, error) {
+	container.linksLock.Lock()
+	defer container.linksLock.Unlock()
+
+	link, err := links.NewLink(
+		container.NetworkSettings.IPAddress,
+		child.NetworkSettings.IPAddress,
+		alias,
+		child.Config.Env,
+		child.Config.ExposedPorts,
+		container.daemon.eng)
+	if err != nil {
+		return nil, err
+	}
+
+	container.activeLinks[link.Alias()] = link
+	if err := link.Enable(); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
 func (container *Container) createDaemonEnvironment(linkedEnv []string) []string {
 	// if a domain name was specified, append it to the hostname (see #7851)
 	fullHostname := container.Config.Hostname
@@ -1392,6 +1733,7 @@ func (container *Container) allocatePort(eng *engine.Engine, port nat.Port, bind
 		binding = append(binding, nat.PortBinding{})
 	}
 
+	var extra []nat.PortBinding
 	for i := 0; i < len(binding); i++ {
 		b := binding[i]
 
@@ -1412,6 +1754,37 @@ func (container *Container) allocatePort(eng *engine.Engine, port nat.Port, bind
 		b.HostPort = portEnv.Get("HostPort")
 
 		binding[i] = b
+		// the port is published on the IPv6 addresses too
+		if ip6 := portEnv.Get("HostIPv6"); ip6 != "" {
+			extra = append(extra, nat.PortBinding{HostIp: ip6, HostPort: b.HostPort})
+		}
+	}
+	bindings[port] = append(binding, extra...)
+	return nil
+}
+
+// publishPluginPort publishes port through the network driver plugin, as
+// allocatePort does on the bridge.
+func (container *Container) publishPluginPort(driver *remote.Driver, port nat.Port, bindings nat.PortMap) error {
+	binding := bindings[port]
+	if container.hostConfig.PublishAllPorts && len(binding) == 0 {
+		binding = append(binding, nat.PortBinding{})
+	}
+
+	for i, b := range binding {
+		var hostPort int
+		if b.HostPort != "" {
+			p, err := nat.ParsePort(b.HostPort)
+			if err != nil {
+				return fmt.Errorf("Invalid host port %s: %s", b.HostPort, err)
+			}
+			hostPort = p
+		}
+		hostIP, hostPort, err := driver.PublishPort(container.ID, port.Proto(), b.HostIp, hostPort, port.Int())
+		if err != nil {
+			return err
+		}
+		binding[i] = nat.PortBinding{HostIp: hostIP, HostPort: strconv.Itoa(hostPort)}
 	}
 	bindings[port] = binding
 	return nil
@@ -1426,6 +1799,17 @@ func (container *Container) GetProcessLabel() string {
 	return container.ProcessLabel
 }
 
+// GetSeccompProfile returns the seccomp profile the execution driver
+// applied at the last start of the container: "default", "unconfined" or
+// the JSON of the profile. A container which never started, or whose driver
+// doesn't support seccomp, is "unconfined".
+func (container *Container) GetSeccompProfile() string {
+	if container.AppliedSeccompProfile == "" {
+		return "unconfined"
+	}
+	return container.AppliedSeccompProfile
+}
+
 func (container *Container) GetMountLabel() string {
 	if container.hostConfig.Privileged {
 		return ""
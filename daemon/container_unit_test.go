@@ -1,8 +1,11 @@
 package daemon
 
 import (
-	"github.com/docker/docker/nat"
+	"reflect"
 	"testing"
+
+	"github.com/docker/docker/nat"
+	"github.com/docker/docker/runconfig"
 )
 
 func TestParseNetworkOptsPrivateOnly(t *testing.T) {
@@ -195,3 +198,34 @@ func TestValidContainerNames(t *testing.T) {
 		}
 	}
 }
+
+func TestDnsSettings(t *testing.T) {
+	container := &Container{
+		hostConfig: &runconfig.HostConfig{DnsOptions: []string{"ndots:2"}},
+		daemon:     &Daemon{config: &Config{DnsSearch: []string{"example.com"}}},
+	}
+	resolvConf := []byte("nameserver 1.2.3.4\nsearch example.org\noptions timeout:1 rotate\n")
+
+	dns, dnsSearch, dnsOptions, custom := container.dnsSettings(resolvConf)
+	if !custom {
+		t.Fatal("Expected custom DNS settings")
+	}
+	if !reflect.DeepEqual(dns, []string{"1.2.3.4"}) {
+		t.Fatalf("Expected the nameservers of the host, got %v", dns)
+	}
+	if !reflect.DeepEqual(dnsSearch, []string{"example.com"}) {
+		t.Fatalf("Expected the search domains of the daemon, got %v", dnsSearch)
+	}
+	if !reflect.DeepEqual(dnsOptions, []string{"ndots:2"}) {
+		t.Fatalf("Expected the options of the container, got %v", dnsOptions)
+	}
+
+	container.hostConfig.DnsOptions = nil
+	container.daemon.config.DnsSearch = nil
+	if _, _, dnsOptions, custom = container.dnsSettings(resolvConf); custom {
+		t.Fatal("Expected the DNS settings of the host only")
+	}
+	if !reflect.DeepEqual(dnsOptions, []string{"timeout:1", "rotate"}) {
+		t.Fatalf("Expected the options of the host, got %v", dnsOptions)
+	}
+}
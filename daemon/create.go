@@ -109,7 +109,31 @@ func (daemon *Daemon) Create(config *runconfig.Config, hostConfig *runconfig.Hos
 	if err := daemon.Register(container); err != nil {
 		return nil, nil, err
 	}
-	if err := daemon.createRootfs(container); err != nil {
+	if err := daemon.createRootfs(container, hostConfig.StorageOpt); err != nil {
+		// the storage driver may reject the options of the container,
+		// don't leave the container behind
+		daemon.idIndex.Delete(container.ID)
+		daemon.containers.Delete(container.ID)
+		daemon.containerGraph.Purge(container.ID)
+		return nil, nil, err
+	}
+	switch {
+	case container.Config.IPAddress == "" && container.Config.IPv6Address == "":
+	case hostConfig.NetworkMode.IsMacvlan() && container.Config.IPv6Address != "":
+		err = fmt.Errorf("Static IPv6 addresses require the bridge network mode")
+	case hostConfig.NetworkMode.IsMacvlan():
+		// checked by the macvlan driver when the address is allocated
+	case !hostConfig.NetworkMode.IsPrivate():
+		err = fmt.Errorf("Static IP addresses require the bridge network mode")
+	default:
+		err = container.reserveAddresses()
+	}
+	if err != nil {
+		daemon.driver.Remove(container.ID)
+		daemon.driver.Remove(container.ID + "-init")
+		daemon.idIndex.Delete(container.ID)
+		daemon.containers.Delete(container.ID)
+		daemon.containerGraph.Purge(container.ID)
 		return nil, nil, err
 	}
 	if hostConfig != nil {
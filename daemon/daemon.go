@@ -33,11 +33,14 @@ import (
 	"github.com/docker/docker/pkg/broadcastwriter"
 	"github.com/docker/docker/pkg/common"
 	"github.com/docker/docker/pkg/graphdb"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/namesgenerator"
 	"github.com/docker/docker/pkg/networkfs/resolvconf"
 	"github.com/docker/docker/pkg/parsers"
 	"github.com/docker/docker/pkg/parsers/kernel"
+	"github.com/docker/docker/pkg/seccomp"
+	"github.com/docker/docker/pkg/signal"
 	"github.com/docker/docker/pkg/sysinfo"
 	"github.com/docker/docker/pkg/truncindex"
 	"github.com/docker/docker/runconfig"
@@ -106,6 +109,8 @@ type Daemon struct {
 	execDriver     execdriver.Driver
 	trustStore     *trust.TrustStore
 	statsCollector *statsCollector
+	uidMaps        []idtools.IDMap
+	gidMaps        []idtools.IDMap
 }
 
 // Install installs daemon capabilities to eng.
@@ -133,6 +138,7 @@ func (daemon *Daemon) Install(eng *engine.Engine) error {
 		"stop":              daemon.ContainerStop,
 		"top":               daemon.ContainerTop,
 		"unpause":           daemon.ContainerUnpause,
+		"container_update":  daemon.ContainerUpdate,
 		"wait":              daemon.ContainerWait,
 		"image_delete":      daemon.ImageDelete, // FIXME: see above
 		"execCreate":        daemon.ContainerExecCreate,
@@ -401,6 +407,13 @@ func (daemon *Daemon) restore() error {
 		registeredContainers = append(registeredContainers, container)
 	}
 
+	// reserve the static addresses again, so that no other container gets them
+	for _, container := range registeredContainers {
+		if err := container.reserveAddresses(); err != nil {
+			log.Errorf("Failed to reserve the static IP addresses of container %s: %s", container.ID, err)
+		}
+	}
+
 	// check the restart policy on the containers and restart any container with
 	// the restart policy of "always"
 	if daemon.config.AutoRestart {
@@ -418,6 +431,22 @@ func (daemon *Daemon) restore() error {
 		}
 	}
 
+	// free the network allocations of the last run which no running
+	// container claimed back
+	if !daemon.config.DisableNetwork {
+		running := []string{}
+		for _, container := range registeredContainers {
+			if container.IsRunning() {
+				running = append(running, container.ID)
+			}
+		}
+		job := daemon.eng.Job("reconcile_allocations")
+		job.SetenvList("Containers", running)
+		if err := job.Run(); err != nil {
+			log.Errorf("Failed to reconcile the network allocations: %s", err)
+		}
+	}
+
 	if !debug {
 		fmt.Println()
 		log.Infof("Loading containers: done.")
@@ -508,6 +537,11 @@ func (daemon *Daemon) mergeAndVerifyConfig(config *runconfig.Config, img *image.
 	if len(config.Entrypoint) == 0 && len(config.Cmd) == 0 {
 		return nil, fmt.Errorf("No command specified")
 	}
+	if config.StopSignal != "" {
+		if _, err := signal.ParseSignal(config.StopSignal); err != nil {
+			return nil, err
+		}
+	}
 	return warnings, nil
 }
 
@@ -619,6 +653,17 @@ func parseSecurityOpt(container *Container, config *runconfig.HostConfig) error
 	)
 
 	for _, opt := range config.SecurityOpt {
+		// seccomp profiles are JSON, which may contain ':'
+		if strings.HasPrefix(opt, "seccomp=") || strings.HasPrefix(opt, "seccomp:") {
+			profile := opt[len("seccomp="):]
+			if profile != "unconfined" {
+				if _, err := seccomp.LoadProfile(profile); err != nil {
+					return fmt.Errorf("Invalid --security-opt %q: %v", "seccomp", err)
+				}
+			}
+			container.SeccompProfile = profile
+			continue
+		}
 		con := strings.SplitN(opt, ":", 2)
 		if len(con) == 1 {
 			return fmt.Errorf("Invalid --security-opt: %q", opt)
@@ -670,27 +715,44 @@ func (daemon *Daemon) newContainer(name string, config *runconfig.Config, imgID
 	return container, err
 }
 
-func (daemon *Daemon) createRootfs(container *Container) error {
+func (daemon *Daemon) createRootfs(container *Container, storageOpt map[string]string) (err error) {
 	// Step 1: create the container directory.
 	// This doubles as a barrier to avoid race conditions.
 	if err := os.Mkdir(container.root, 0700); err != nil {
 		return err
 	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(container.root)
+		}
+	}()
+	rootUID, rootGID, err := daemon.GetRemappedUIDGID()
+	if err != nil {
+		return err
+	}
+	// the container's root needs to reach its hosts and resolv.conf files
+	if err := os.Chown(container.root, rootUID, rootGID); err != nil {
+		return err
+	}
 	initID := fmt.Sprintf("%s-init", container.ID)
-	if err := daemon.driver.Create(initID, container.ImageID); err != nil {
+	if err := daemon.driver.Create(initID, container.ImageID, nil); err != nil {
 		return err
 	}
 	initPath, err := daemon.driver.Get(initID, "")
 	if err != nil {
+		daemon.driver.Remove(initID)
 		return err
 	}
-	defer daemon.driver.Put(initID)
-
-	if err := graph.SetupInitLayer(initPath); err != nil {
+	err = graph.SetupInitLayer(initPath, rootUID, rootGID)
+	daemon.driver.Put(initID)
+	if err != nil {
+		daemon.driver.Remove(initID)
 		return err
 	}
 
-	if err := daemon.driver.Create(container.ID, initID); err != nil {
+	// the storage options only apply to the layer of the container
+	if err := daemon.driver.Create(container.ID, initID, storageOpt); err != nil {
+		daemon.driver.Remove(initID)
 		return err
 	}
 	return nil
@@ -812,9 +874,18 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 	if !config.EnableIptables && !config.InterContainerCommunication {
 		return nil, fmt.Errorf("You specified --iptables=false with --icc=false. ICC uses iptables to function. Please set --icc or --iptables to true.")
 	}
+	if !config.EnableIptables && !config.EnableUserlandProxy {
+		return nil, fmt.Errorf("You specified --iptables=false with --userland-proxy=false. Without the userland proxy, the published ports are only forwarded by iptables. Please set --userland-proxy or --iptables to true.")
+	}
 	if !config.EnableIptables && config.EnableIpMasq {
 		config.EnableIpMasq = false
 	}
+	if config.Init && config.ExecDriver != "native" {
+		return nil, fmt.Errorf("You specified --init with the %s execdriver. The container init is only supported by the native execdriver.", config.ExecDriver)
+	}
+	if config.MacvlanParent == "" && (config.MacvlanSubnet != "" || config.MacvlanGateway != "" || config.MacvlanIPRange != "") {
+		return nil, fmt.Errorf("You specified macvlan options without --macvlan-parent. Please set the parent interface of the macvlan network mode.")
+	}
 	config.DisableNetwork = config.BridgeIface == disableNetworkBridge
 
 	// Claim the pidfile first, to avoid any and all unexpected race conditions.
@@ -865,16 +936,34 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		}
 	}
 	config.Root = realRoot
+
+	uidMaps, gidMaps, err := setupRemappedRoot(config)
+	if err != nil {
+		return nil, err
+	}
+	rootUID, rootGID, err := idtools.GetRootUIDGID(uidMaps, gidMaps)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the root directory if it doesn't exists
-	if err := os.MkdirAll(config.Root, 0700); err != nil && !os.IsExist(err) {
+	if err := setupDaemonRoot(config, rootUID, rootGID); err != nil {
 		return nil, err
 	}
 
 	// Set the default driver
 	graphdriver.DefaultDriver = config.GraphDriver
 
+	// Log the events of the storage of the driver, such as its pool
+	// running out of space
+	graphdriver.LogEvent = func(action, id string) {
+		if err := eng.Job("log", action, id, "").Run(); err != nil {
+			log.Errorf("Error logging event %s for %s: %s", action, id, err)
+		}
+	}
+
 	// Load storage driver
-	driver, err := graphdriver.New(config.Root, config.GraphOptions)
+	driver, err := graphdriver.New(config.Root, config.GraphOptions, uidMaps, gidMaps)
 	if err != nil {
 		return nil, err
 	}
@@ -887,12 +976,12 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 
 	daemonRepo := path.Join(config.Root, "containers")
 
-	if err := os.MkdirAll(daemonRepo, 0700); err != nil && !os.IsExist(err) {
+	if err := idtools.MkdirAllAs(daemonRepo, 0700, rootUID, rootGID); err != nil && !os.IsExist(err) {
 		return nil, err
 	}
 
 	// Migrate the container if it is aufs and aufs is enabled
-	if err = migrateIfAufs(driver, config.Root); err != nil {
+	if err = migrateIfAufs(driver, config.Root, rootUID, rootGID); err != nil {
 		return nil, err
 	}
 
@@ -902,7 +991,7 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		return nil, err
 	}
 
-	volumesDriver, err := graphdriver.GetDriver("vfs", config.Root, config.GraphOptions)
+	volumesDriver, err := graphdriver.GetDriver("vfs", config.Root, config.GraphOptions, uidMaps, gidMaps)
 	if err != nil {
 		return nil, err
 	}
@@ -936,15 +1025,31 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		job := eng.Job("init_networkdriver")
 
 		job.SetenvBool("EnableIptables", config.EnableIptables)
+		job.Setenv("FirewallBackend", config.FirewallBackend)
 		job.SetenvBool("InterContainerCommunication", config.InterContainerCommunication)
 		job.SetenvBool("EnableIpForward", config.EnableIpForward)
 		job.SetenvBool("EnableIpMasq", config.EnableIpMasq)
+		job.SetenvBool("EnableUserlandProxy", config.EnableUserlandProxy)
 		job.SetenvBool("EnableIPv6", config.EnableIPv6)
 		job.Setenv("BridgeIface", config.BridgeIface)
 		job.Setenv("BridgeIP", config.BridgeIP)
 		job.Setenv("FixedCIDR", config.FixedCIDR)
 		job.Setenv("FixedCIDRv6", config.FixedCIDRv6)
 		job.Setenv("DefaultBindingIP", config.DefaultIp.String())
+		job.Setenv("StateDir", path.Join(config.Root, "network"))
+
+		if err := job.Run(); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.MacvlanParent != "" {
+		job := eng.Job("init_macvlan_driver")
+
+		job.Setenv("Parent", config.MacvlanParent)
+		job.Setenv("Subnet", config.MacvlanSubnet)
+		job.Setenv("Gateway", config.MacvlanGateway)
+		job.Setenv("IPRange", config.MacvlanIPRange)
 
 		if err := job.Run(); err != nil {
 			return nil, err
@@ -965,7 +1070,8 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 
 	if sysInitPath != localCopy {
 		// When we find a suitable dockerinit binary (even if it's our local binary), we copy it into config.Root at localCopy for future use (so that the original can go away without that being a problem, for example during a package upgrade).
-		if err := os.Mkdir(path.Dir(localCopy), 0700); err != nil && !os.IsExist(err) {
+		// The remapped root needs to be able to exec the local copy
+		if err := idtools.MkdirAs(path.Dir(localCopy), 0700, rootUID, rootGID); err != nil && !os.IsExist(err) {
 			return nil, err
 		}
 		if _, err := utils.CopyFile(sysInitPath, localCopy); err != nil {
@@ -974,11 +1080,14 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		if err := os.Chmod(localCopy, 0700); err != nil {
 			return nil, err
 		}
+		if err := os.Chown(localCopy, rootUID, rootGID); err != nil {
+			return nil, err
+		}
 		sysInitPath = localCopy
 	}
 
 	sysInfo := sysinfo.New(false)
-	ed, err := execdrivers.NewDriver(config.ExecDriver, config.Root, sysInitPath, sysInfo)
+	ed, err := execdrivers.NewDriver(config.ExecDriver, config.Root, sysInitPath, sysInfo, rootUID, rootGID)
 	if err != nil {
 		return nil, err
 	}
@@ -1001,6 +1110,8 @@ func NewDaemonFromDirectory(config *Config, eng *engine.Engine) (*Daemon, error)
 		eng:            eng,
 		trustStore:     t,
 		statsCollector: newStatsCollector(1 * time.Second),
+		uidMaps:        uidMaps,
+		gidMaps:        gidMaps,
 	}
 	if err := daemon.restore(); err != nil {
 		return nil, err
@@ -1044,8 +1155,9 @@ func (daemon *Daemon) shutdown() error {
 
 			go func() {
 				defer group.Done()
-				if err := c.KillSig(15); err != nil {
-					log.Debugf("kill 15 error for %s - %s", c.ID, err)
+				sig := c.StopSignal()
+				if err := c.KillSig(sig); err != nil {
+					log.Debugf("kill %d error for %s - %s", sig, c.ID, err)
 				}
 				c.WaitStop(-1 * time.Second)
 				log.Debugf("container stopped %s", c.ID)
@@ -31,9 +31,54 @@ func TestParseSecurityOpt(t *testing.T) {
 		t.Fatal("Expected parseSecurityOpt error, got nil")
 	}
 
+	// test seccomp
+	profile := `{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": [{"name": "read", "action": "SCMP_ACT_ALLOW"}]}`
+	config.SecurityOpt = []string{"seccomp=" + profile}
+	if err := parseSecurityOpt(container, config); err != nil {
+		t.Fatalf("Unexpected parseSecurityOpt error: %v", err)
+	}
+	if container.SeccompProfile != profile {
+		t.Fatalf("Unexpected SeccompProfile, expected: %q, got %q", profile, container.SeccompProfile)
+	}
+
+	config.SecurityOpt = []string{"seccomp:unconfined"}
+	if err := parseSecurityOpt(container, config); err != nil {
+		t.Fatalf("Unexpected parseSecurityOpt error: %v", err)
+	}
+	if container.SeccompProfile != "unconfined" {
+		t.Fatalf("Unexpected SeccompProfile, expected: \"unconfined\", got %q", container.SeccompProfile)
+	}
+
+	// test invalid seccomp profile
+	config.SecurityOpt = []string{"seccomp=/path/to/profile.json"}
+	if err := parseSecurityOpt(container, config); err == nil {
+		t.Fatal("Expected parseSecurityOpt error, got nil")
+	}
+
 	// test invalid opt
 	config.SecurityOpt = []string{"test"}
 	if err := parseSecurityOpt(container, config); err == nil {
 		t.Fatal("Expected parseSecurityOpt error, got nil")
 	}
 }
+
+func TestVerifyTmpfs(t *testing.T) {
+	config := &runconfig.HostConfig{Tmpfs: map[string]string{"/run": "", "/tmp": "size=64m,exec"}}
+	if err := verifyTmpfs(config); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, config := range []*runconfig.HostConfig{
+		{Tmpfs: map[string]string{"run": ""}},
+		{Tmpfs: map[string]string{"/run": "bind"}},
+		{Tmpfs: map[string]string{"/run": "foo=bar"}},
+		{Tmpfs: map[string]string{"/data": ""}, Binds: []string{"/tmp:/data"}},
+		{Tmpfs: map[string]string{"/data/": ""}, Binds: []string{"/tmp:/data"}},
+		{Tmpfs: map[string]string{"/": ""}},
+		{Tmpfs: map[string]string{"/a/../": ""}},
+	} {
+		if err := verifyTmpfs(config); err == nil {
+			t.Fatalf("Expected verifyTmpfs error for %v", config)
+		}
+	}
+}
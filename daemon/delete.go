@@ -96,6 +96,8 @@ func (daemon *Daemon) Rm(container *Container) error {
 		return err
 	}
 
+	container.releaseAddresses()
+
 	// Deregister the container before removing its directory, to avoid race conditions
 	daemon.idIndex.Delete(container.ID)
 	daemon.containers.Delete(container.ID)
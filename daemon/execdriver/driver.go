@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/docker/docker/daemon/execdriver/native/template"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/libcontainer"
 	"github.com/docker/libcontainer/devices"
@@ -98,6 +99,10 @@ type NetworkInterface struct {
 	LinkLocalIPv6Address string `json:"link_local_ipv6"`
 	GlobalIPv6PrefixLen  int    `json:"global_ipv6_prefix_len"`
 	IPv6Gateway          string `json:"ipv6_gateway"`
+	HairpinMode          bool   `json:"hairpin_mode"`
+	MacvlanParent        string `json:"macvlan_parent"`      // if set, the interface is a macvlan interface of the parent instead of a veth on the bridge
+	HostInterfaceName    string `json:"host_interface_name"` // if set, the existing host interface is moved inside the container instead
+	VethHost             string `json:"veth_host"`           // name of the host side of the veth pair, random if empty
 }
 
 type Resources struct {
@@ -106,6 +111,7 @@ type Resources struct {
 	CpuShares  int64            `json:"cpu_shares"`
 	Cpuset     string           `json:"cpuset"`
 	Rlimits    []*ulimit.Rlimit `json:"rlimits"`
+	PidsLimit  int64            `json:"pids_limit"`
 }
 
 type ResourceStats struct {
@@ -121,6 +127,9 @@ type Mount struct {
 	Writable    bool   `json:"writable"`
 	Private     bool   `json:"private"`
 	Slave       bool   `json:"slave"`
+	Propagation string `json:"propagation"` // Propagation mode of bind mounts, as "shared" or "rslave"
+	Type        string `json:"type"`        // "tmpfs" for tmpfs mounts, bind mounts otherwise
+	Data        string `json:"data"`        // Mount options of tmpfs mounts
 }
 
 // Describes a process that will be run inside a container.
@@ -159,6 +168,11 @@ type Command struct {
 	MountLabel         string            `json:"mount_label"`
 	LxcConfig          []string          `json:"lxc_config"`
 	AppArmorProfile    string            `json:"apparmor_profile"`
+	SeccompProfile     string            `json:"seccomp_profile"` // JSON profile, "unconfined", or empty for the default
+	SeccompApplied     string            `json:"seccomp_applied"` // set by the driver: the profile it applied, "default", "unconfined" or JSON
+	Init               bool              `json:"init"`            // run dockerinit as PID 1 to forward signals and reap processes
+	UIDMapping         []idtools.IDMap   `json:"uidmapping"`      // user namespace mappings, nil without --userns-remap
+	GIDMapping         []idtools.IDMap   `json:"gidmapping"`
 }
 
 func InitContainer(c *Command) *libcontainer.Config {
@@ -198,6 +212,7 @@ func SetupCgroups(container *libcontainer.Config, c *Command) error {
 		container.Cgroups.MemoryReservation = c.Resources.Memory
 		container.Cgroups.MemorySwap = c.Resources.MemorySwap
 		container.Cgroups.CpusetCpus = c.Resources.Cpuset
+		container.Cgroups.PidsLimit = c.Resources.PidsLimit
 	}
 
 	return nil
@@ -17,8 +17,20 @@ import (
 const LxcTemplate = `
 {{if .Network.Interface}}
 # network configuration
+{{if .Network.Interface.HostInterfaceName}}
+lxc.network.type = phys
+lxc.network.link = {{.Network.Interface.HostInterfaceName}}
+{{else if .Network.Interface.MacvlanParent}}
+lxc.network.type = macvlan
+lxc.network.macvlan.mode = bridge
+lxc.network.link = {{.Network.Interface.MacvlanParent}}
+{{else}}
 lxc.network.type = veth
 lxc.network.link = {{.Network.Interface.Bridge}}
+{{if .Network.Interface.VethHost}}
+lxc.network.veth.pair = {{.Network.Interface.VethHost}}
+{{end}}
+{{end}}
 lxc.network.name = eth0
 lxc.network.mtu = {{.Network.Mtu}}
 lxc.network.flags = up
@@ -88,6 +100,9 @@ lxc.mount.entry = devpts {{escapeFstabSpaces $ROOTFS}}/dev/pts devpts {{formatMo
 lxc.mount.entry = shm {{escapeFstabSpaces $ROOTFS}}/dev/shm tmpfs {{formatMountLabel "size=65536k,nosuid,nodev,noexec" ""}} 0 0
 
 {{range $value := .Mounts}}
+{{if eq $value.Type "tmpfs"}}
+lxc.mount.entry = tmpfs {{escapeFstabSpaces $ROOTFS}}/{{escapeFstabSpaces $value.Destination}} tmpfs noexec,nosuid,nodev{{if $value.Data}},{{$value.Data}}{{end}},create=dir 0 0
+{{else}}
 {{$createVal := isDirectory $value.Source}}
 {{if $value.Writable}}
 lxc.mount.entry = {{$value.Source}} {{escapeFstabSpaces $ROOTFS}}/{{escapeFstabSpaces $value.Destination}} none rbind,rw,create={{$createVal}} 0 0
@@ -95,6 +110,7 @@ lxc.mount.entry = {{$value.Source}} {{escapeFstabSpaces $ROOTFS}}/{{escapeFstabS
 lxc.mount.entry = {{$value.Source}} {{escapeFstabSpaces $ROOTFS}}/{{escapeFstabSpaces $value.Destination}} none rbind,ro,create={{$createVal}} 0 0
 {{end}}
 {{end}}
+{{end}}
 
 # limits
 {{if .Resources}}
@@ -67,6 +67,43 @@ func TestLXCConfig(t *testing.T) {
 		fmt.Sprintf("lxc.cgroup.memory.memsw.limit_in_bytes = %d", mem*2))
 }
 
+func TestLXCConfigMacvlan(t *testing.T) {
+	root, err := ioutil.TempDir("", "TestLXCConfigMacvlan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	os.MkdirAll(path.Join(root, "containers", "1"), 0777)
+
+	driver, err := NewDriver(root, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command := &execdriver.Command{
+		ID: "1",
+		Network: &execdriver.Network{
+			Mtu: 1500,
+			Interface: &execdriver.NetworkInterface{
+				IPAddress:     "10.1.0.10",
+				IPPrefixLen:   24,
+				Gateway:       "10.1.0.1",
+				MacvlanParent: "eth1",
+			},
+		},
+		AllowedDevices: make([]*devices.Device, 0),
+		ProcessConfig:  execdriver.ProcessConfig{},
+	}
+	p, err := driver.generateLXCConfig(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grepFile(t, p, "lxc.network.type = macvlan")
+	grepFile(t, p, "lxc.network.macvlan.mode = bridge")
+	grepFile(t, p, "lxc.network.link = eth1")
+	grepFile(t, p, "lxc.network.ipv4 = 10.1.0.10/24")
+}
+
 func TestCustomLxcConfig(t *testing.T) {
 	root, err := ioutil.TempDir("", "TestCustomLxcConfig")
 	if err != nil {
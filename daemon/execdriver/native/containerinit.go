@@ -0,0 +1,114 @@
+// +build linux
+
+package native
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"unsafe"
+
+	"github.com/docker/docker/pkg/reexec"
+	"github.com/docker/docker/pkg/term"
+)
+
+// ContainerInitPath is where the dockerinit binary is mounted in containers
+// started with --init. Run as PID 1 from there, it starts the command of the
+// container, forwards signals to it and reaps orphaned processes.
+const ContainerInitPath = "/dev/init"
+
+// forwardedSignals are the signals the init passes on to the command of the
+// container. The ones the terminal sends reach the child directly, through
+// its process group.
+var forwardedSignals = []os.Signal{
+	syscall.SIGHUP,
+	syscall.SIGINT,
+	syscall.SIGQUIT,
+	syscall.SIGABRT,
+	syscall.SIGUSR1,
+	syscall.SIGUSR2,
+	syscall.SIGALRM,
+	syscall.SIGTERM,
+	syscall.SIGCONT,
+	syscall.SIGTSTP,
+}
+
+func init() {
+	reexec.Register(ContainerInitPath, containerInit)
+}
+
+func containerInit() {
+	if len(os.Args) < 2 {
+		fmt.Fprintf(os.Stderr, "%s: no command to run\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	// catch the signals before starting the child so that none is lost;
+	// SIGCHLD is only used to reap, and handing the terminal over must not
+	// stop us
+	sigc := make(chan os.Signal, 32)
+	signal.Notify(sigc, append(forwardedSignals, syscall.SIGCHLD)...)
+	signal.Ignore(syscall.SIGURG, syscall.SIGTTIN, syscall.SIGTTOU)
+
+	path, err := exec.LookPath(os.Args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[0], err)
+		os.Exit(127)
+	}
+
+	// the child gets its own process group, so that signals sent by the
+	// terminal reach it once and not again through us
+	tty := term.IsTerminal(os.Stdin.Fd())
+	child, err := os.StartProcess(path, os.Args[1:], &os.ProcAttr{
+		Env:   os.Environ(),
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+		Sys:   &syscall.SysProcAttr{Setpgid: true},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[0], err)
+		os.Exit(126)
+	}
+	if tty {
+		// hand the terminal over to the child, and wake it up in case it
+		// got stopped reading from it in the meantime
+		pgid := child.Pid
+		syscall.Syscall(syscall.SYS_IOCTL, os.Stdin.Fd(), syscall.TIOCSPGRP, uintptr(unsafe.Pointer(&pgid)))
+		syscall.Kill(-child.Pid, syscall.SIGCONT)
+	}
+
+	for sig := range sigc {
+		if sig != syscall.SIGCHLD {
+			// the child may be gone already; reaping tells us
+			child.Signal(sig)
+			continue
+		}
+		if status, exited := reap(child.Pid); exited {
+			os.Exit(status)
+		}
+	}
+}
+
+// reap waits for all the exited children, and returns the exit status of
+// the child with the given pid if it is one of them. A child killed by a
+// signal exits with 128 + the signal, like in a shell.
+func reap(pid int) (int, bool) {
+	for {
+		var ws syscall.WaitStatus
+		p, err := syscall.Wait4(-1, &ws, syscall.WNOHANG, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || p <= 0 {
+			return 0, false
+		}
+		if p != pid {
+			continue
+		}
+		if ws.Signaled() {
+			return 128 + int(ws.Signal()), true
+		}
+		return ws.ExitStatus(), true
+	}
+}
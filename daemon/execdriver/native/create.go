@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 
 	"github.com/docker/docker/daemon/execdriver"
+	mountpkg "github.com/docker/docker/pkg/mount"
 	"github.com/docker/libcontainer"
 	"github.com/docker/libcontainer/apparmor"
 	"github.com/docker/libcontainer/devices"
@@ -32,6 +33,10 @@ func (d *driver) createContainer(c *execdriver.Command) (*libcontainer.Config, e
 		return nil, err
 	}
 
+	if err := d.createUserns(container, c); err != nil {
+		return nil, err
+	}
+
 	if c.ProcessConfig.Privileged {
 		if err := d.setPrivileged(container); err != nil {
 			return nil, err
@@ -58,6 +63,14 @@ func (d *driver) createContainer(c *execdriver.Command) (*libcontainer.Config, e
 		return nil, err
 	}
 
+	seccompProfile, err := d.setupSeccomp(container, c)
+	if err != nil {
+		return nil, err
+	}
+	c.SeccompApplied = seccompProfile
+
+	d.setupInit(container, c)
+
 	d.setupRlimits(container, c)
 
 	cmds := make(map[string]*exec.Cmd)
@@ -86,20 +99,31 @@ func (d *driver) createNetwork(container *libcontainer.Config, c *execdriver.Com
 	}
 
 	if c.Network.Interface != nil {
-		vethNetwork := libcontainer.Network{
+		iface := libcontainer.Network{
 			Mtu:        c.Network.Mtu,
 			Address:    fmt.Sprintf("%s/%d", c.Network.Interface.IPAddress, c.Network.Interface.IPPrefixLen),
 			MacAddress: c.Network.Interface.MacAddress,
 			Gateway:    c.Network.Interface.Gateway,
-			Type:       "veth",
-			Bridge:     c.Network.Interface.Bridge,
-			VethPrefix: "veth",
+		}
+		switch {
+		case c.Network.Interface.HostInterfaceName != "":
+			iface.Type = "netdev"
+			iface.HostInterfaceName = c.Network.Interface.HostInterfaceName
+		case c.Network.Interface.MacvlanParent != "":
+			iface.Type = "macvlan"
+			iface.Parent = c.Network.Interface.MacvlanParent
+		default:
+			iface.Type = "veth"
+			iface.Bridge = c.Network.Interface.Bridge
+			iface.VethPrefix = "veth"
+			iface.HairpinMode = c.Network.Interface.HairpinMode
+			iface.HostInterfaceName = c.Network.Interface.VethHost
 		}
 		if c.Network.Interface.GlobalIPv6Address != "" {
-			vethNetwork.IPv6Address = fmt.Sprintf("%s/%d", c.Network.Interface.GlobalIPv6Address, c.Network.Interface.GlobalIPv6PrefixLen)
-			vethNetwork.IPv6Gateway = c.Network.Interface.IPv6Gateway
+			iface.IPv6Address = fmt.Sprintf("%s/%d", c.Network.Interface.GlobalIPv6Address, c.Network.Interface.GlobalIPv6PrefixLen)
+			iface.IPv6Gateway = c.Network.Interface.IPv6Gateway
 		}
-		container.Networks = append(container.Networks, &vethNetwork)
+		container.Networks = append(container.Networks, &iface)
 	}
 
 	if c.Network.ContainerID != "" {
@@ -150,6 +174,40 @@ func (d *driver) createPid(container *libcontainer.Config, c *execdriver.Command
 	return nil
 }
 
+func (d *driver) createUserns(container *libcontainer.Config, c *execdriver.Command) error {
+	if c.UIDMapping == nil {
+		return nil
+	}
+
+	// a namespace owned by another user namespace cannot be joined from
+	// inside our own
+	if c.Network.HostNetworking || c.Network.ContainerID != "" {
+		return fmt.Errorf("Sharing the network namespace is not supported with user namespaces")
+	}
+	if c.Ipc.HostIpc || c.Ipc.ContainerID != "" {
+		return fmt.Errorf("Sharing the IPC namespace is not supported with user namespaces")
+	}
+	if c.Pid.HostPid {
+		return fmt.Errorf("Sharing the PID namespace is not supported with user namespaces")
+	}
+
+	container.Namespaces.Add(libcontainer.NEWUSER, "")
+
+	// device nodes cannot be created from within a user namespace, so bind
+	// mount the ones from the host instead
+	for _, node := range container.MountConfig.DeviceNodes {
+		container.MountConfig.Mounts = append(container.MountConfig.Mounts, &mount.Mount{
+			Type:        "bind",
+			Source:      node.Path,
+			Destination: node.Path,
+			Writable:    true,
+		})
+	}
+	container.MountConfig.DeviceNodes = nil
+
+	return nil
+}
+
 func (d *driver) setPrivileged(container *libcontainer.Config) (err error) {
 	container.Capabilities = capabilities.GetAllCapabilities()
 	container.Cgroups.AllowAllDevices = true
@@ -186,6 +244,31 @@ func (d *driver) setupRlimits(container *libcontainer.Config, c *execdriver.Comm
 
 func (d *driver) setupMounts(container *libcontainer.Config, c *execdriver.Command) error {
 	for _, m := range c.Mounts {
+		if m.Type == "tmpfs" {
+			flags, data, err := mountpkg.ParseTmpfsOptions(m.Data)
+			if err != nil {
+				return err
+			}
+			container.MountConfig.Mounts = append(container.MountConfig.Mounts, &mount.Mount{
+				Type:        "tmpfs",
+				Destination: m.Destination,
+				Flags:       flags,
+				HasFlags:    true,
+				Data:        data,
+			})
+			continue
+		}
+		propagation, exists := mountPropagation[m.Propagation]
+		if !exists {
+			return fmt.Errorf("invalid mount propagation %q for %s", m.Propagation, m.Destination)
+		}
+		// the mounts of the namespace must propagate for the bind mounts to
+		switch {
+		case propagation&mountpkg.SHARED != 0:
+			container.MountConfig.RootPropagation = mountpkg.RSHARED
+		case propagation&mountpkg.SLAVE != 0 && container.MountConfig.RootPropagation == 0:
+			container.MountConfig.RootPropagation = mountpkg.RSLAVE
+		}
 		container.MountConfig.Mounts = append(container.MountConfig.Mounts, &mount.Mount{
 			Type:        "bind",
 			Source:      m.Source,
@@ -193,12 +276,38 @@ func (d *driver) setupMounts(container *libcontainer.Config, c *execdriver.Comma
 			Writable:    m.Writable,
 			Private:     m.Private,
 			Slave:       m.Slave,
+			Propagation: m.Propagation,
 		})
 	}
 
 	return nil
 }
 
+// mountPropagation maps the propagation modes of bind mounts to mount flags
+var mountPropagation = map[string]int{
+	"":         0,
+	"private":  mountpkg.PRIVATE,
+	"rprivate": mountpkg.RPRIVATE,
+	"slave":    mountpkg.SLAVE,
+	"rslave":   mountpkg.RSLAVE,
+	"shared":   mountpkg.SHARED,
+	"rshared":  mountpkg.RSHARED,
+}
+
+// setupInit mounts dockerinit in containers running with --init, to be run
+// as PID 1 in place of the command of the container.
+func (d *driver) setupInit(container *libcontainer.Config, c *execdriver.Command) {
+	if !c.Init {
+		return
+	}
+	container.MountConfig.Mounts = append(container.MountConfig.Mounts, &mount.Mount{
+		Type:        "bind",
+		Source:      d.initPath,
+		Destination: ContainerInitPath,
+		Private:     true,
+	})
+}
+
 func (d *driver) setupLabels(container *libcontainer.Config, c *execdriver.Command) error {
 	container.ProcessLabel = c.ProcessLabel
 	container.MountConfig.MountLabel = c.MountLabel
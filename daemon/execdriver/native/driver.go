@@ -16,6 +16,7 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/idtools"
 	sysinfo "github.com/docker/docker/pkg/system"
 	"github.com/docker/docker/pkg/term"
 	"github.com/docker/libcontainer"
@@ -46,13 +47,15 @@ type driver struct {
 	sync.Mutex
 }
 
-func NewDriver(root, initPath string) (*driver, error) {
+func NewDriver(root, initPath string, rootUID, rootGID int) (*driver, error) {
 	meminfo, err := sysinfo.ReadMemInfo()
 	if err != nil {
 		return nil, err
 	}
 
-	if err := os.MkdirAll(root, 0700); err != nil {
+	// the container init reads its configuration from below root, so
+	// it has to be reachable by a remapped root as well
+	if err := idtools.MkdirAllAs(root, 0700, rootUID, rootGID); err != nil {
 		return nil, err
 	}
 	// native driver root is at docker_root/execdriver/native. Put apparmor at docker_root
@@ -79,10 +82,15 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 		return execdriver.ExitStatus{ExitCode: -1}, err
 	}
 
+	rootUID, rootGID, err := idtools.GetRootUIDGID(c.UIDMapping, c.GIDMapping)
+	if err != nil {
+		return execdriver.ExitStatus{ExitCode: -1}, err
+	}
+
 	var term execdriver.Terminal
 
 	if c.ProcessConfig.Tty {
-		term, err = NewTtyConsole(&c.ProcessConfig, pipes)
+		term, err = NewTtyConsole(&c.ProcessConfig, pipes, rootUID, rootGID)
 	} else {
 		term, err = execdriver.NewStdConsole(&c.ProcessConfig, pipes)
 	}
@@ -102,6 +110,9 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 		dataPath = filepath.Join(d.root, c.ID)
 		args     = append([]string{c.ProcessConfig.Entrypoint}, c.ProcessConfig.Arguments...)
 	)
+	if c.Init {
+		args = append([]string{ContainerInitPath}, args...)
+	}
 
 	if err := d.createContainerRoot(c.ID); err != nil {
 		return execdriver.ExitStatus{ExitCode: -1}, err
@@ -128,7 +139,9 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 
 			// set this to nil so that when we set the clone flags anything else is reset
 			c.ProcessConfig.SysProcAttr = &syscall.SysProcAttr{
-				Cloneflags: uintptr(namespaces.GetNamespaceFlags(container.Namespaces)),
+				Cloneflags:  uintptr(namespaces.GetNamespaceFlags(container.Namespaces)),
+				UidMappings: sysProcIDMaps(c.UIDMapping),
+				GidMappings: sysProcIDMaps(c.GIDMapping),
 			}
 			c.ProcessConfig.ExtraFiles = []*os.File{child}
 
@@ -280,6 +293,23 @@ func (d *driver) cleanContainer(id string) error {
 	return os.RemoveAll(filepath.Join(d.root, id, "container.json"))
 }
 
+// sysProcIDMaps converts the user namespace mappings of a container to the
+// form the kernel is handed when cloning its init process.
+func sysProcIDMaps(idMap []idtools.IDMap) []syscall.SysProcIDMap {
+	if idMap == nil {
+		return nil
+	}
+	sysMaps := make([]syscall.SysProcIDMap, len(idMap))
+	for i, m := range idMap {
+		sysMaps[i] = syscall.SysProcIDMap{
+			ContainerID: m.ContainerID,
+			HostID:      m.HostID,
+			Size:        m.Size,
+		}
+	}
+	return sysMaps
+}
+
 func (d *driver) createContainerRoot(id string) error {
 	return os.MkdirAll(filepath.Join(d.root, id), 0655)
 }
@@ -296,12 +326,18 @@ type TtyConsole struct {
 	MasterPty *os.File
 }
 
-func NewTtyConsole(processConfig *execdriver.ProcessConfig, pipes *execdriver.Pipes) (*TtyConsole, error) {
+func NewTtyConsole(processConfig *execdriver.ProcessConfig, pipes *execdriver.Pipes, rootUID, rootGID int) (*TtyConsole, error) {
 	ptyMaster, console, err := consolepkg.CreateMasterAndConsole()
 	if err != nil {
 		return nil, err
 	}
 
+	// the console is opened by the container's root, which may be remapped
+	if err := os.Chown(console, rootUID, rootGID); err != nil {
+		ptyMaster.Close()
+		return nil, err
+	}
+
 	tty := &TtyConsole{
 		MasterPty: ptyMaster,
 	}
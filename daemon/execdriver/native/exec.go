@@ -11,6 +11,7 @@ import (
 	"runtime"
 
 	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/reexec"
 	"github.com/docker/libcontainer"
 	"github.com/docker/libcontainer/namespaces"
@@ -49,10 +50,15 @@ func (d *driver) Exec(c *execdriver.Command, processConfig *execdriver.ProcessCo
 		return -1, fmt.Errorf("State unavailable for container with ID %s. The container may have been cleaned up already. Error: %s", c.ID, err)
 	}
 
+	rootUID, rootGID, err := idtools.GetRootUIDGID(c.UIDMapping, c.GIDMapping)
+	if err != nil {
+		return -1, err
+	}
+
 	var term execdriver.Terminal
 
 	if processConfig.Tty {
-		term, err = NewTtyConsole(processConfig, pipes)
+		term, err = NewTtyConsole(processConfig, pipes, rootUID, rootGID)
 	} else {
 		term, err = execdriver.NewStdConsole(processConfig, pipes)
 	}
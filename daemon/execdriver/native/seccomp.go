@@ -0,0 +1,133 @@
+// +build linux,cgo
+
+package native
+
+import (
+	"encoding/json"
+	"fmt"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/seccomp"
+	"github.com/docker/libcontainer"
+	libseccomp "github.com/docker/libcontainer/seccomp"
+)
+
+// setupSeccomp loads the seccomp profile of the container: the default
+// profile unless one was given with --security-opt, and none for
+// "unconfined" or privileged containers without an explicit profile. The
+// default profile lets containers granted CAP_SYS_ADMIN mount, and is
+// skipped on architectures the filters can't be compiled for.
+//
+// It returns the profile applied: "unconfined", "default", or the JSON of
+// the profile otherwise.
+func (d *driver) setupSeccomp(container *libcontainer.Config, c *execdriver.Command) (string, error) {
+	if c.SeccompProfile == "unconfined" || (c.SeccompProfile == "" && c.ProcessConfig.Privileged) {
+		return "unconfined", nil
+	}
+
+	if !libseccomp.IsEnabled() {
+		if c.SeccompProfile != "" {
+			return "", fmt.Errorf("seccomp profiles are not supported by the kernel")
+		}
+		return "unconfined", nil
+	}
+
+	applied := c.SeccompProfile
+	profile := seccomp.DefaultProfileFor(container.Capabilities)
+	if c.SeccompProfile != "" {
+		var err error
+		if profile, err = seccomp.LoadProfile(c.SeccompProfile); err != nil {
+			return "", err
+		}
+	} else if profile == seccomp.DefaultProfile {
+		applied = "default"
+	} else {
+		b, err := json.Marshal(profile)
+		if err != nil {
+			return "", err
+		}
+		applied = string(b)
+	}
+
+	config, err := seccompConfig(profile)
+	if err != nil {
+		return "", err
+	}
+	if _, err := libseccomp.Compile(config); err != nil {
+		if err == libseccomp.ErrUnsupportedArch && c.SeccompProfile == "" {
+			log.Warnf("Running container %s without the default seccomp profile: %s", c.ID, err)
+			return "unconfined", nil
+		}
+		return "", err
+	}
+	container.Seccomp = config
+	return applied, nil
+}
+
+// seccompConfig converts a profile to the filter configuration of
+// libcontainer.
+func seccompConfig(p *seccomp.Profile) (*libseccomp.Config, error) {
+	defaultAction, err := seccompAction(p.DefaultAction)
+	if err != nil {
+		return nil, err
+	}
+	config := &libseccomp.Config{DefaultAction: defaultAction}
+
+	for _, s := range p.Syscalls {
+		action, err := seccompAction(s.Action)
+		if err != nil {
+			return nil, err
+		}
+		rule := &libseccomp.Syscall{Name: s.Name, Action: action}
+		for _, a := range s.Args {
+			op, err := seccompOperator(a.Op)
+			if err != nil {
+				return nil, err
+			}
+			rule.Args = append(rule.Args, &libseccomp.Arg{
+				Index:    a.Index,
+				Value:    a.Value,
+				ValueTwo: a.ValueTwo,
+				Op:       op,
+			})
+		}
+		config.Syscalls = append(config.Syscalls, rule)
+	}
+	return config, nil
+}
+
+func seccompAction(a seccomp.Action) (libseccomp.Action, error) {
+	switch a {
+	case seccomp.ActKill:
+		return libseccomp.Kill, nil
+	case seccomp.ActTrap:
+		return libseccomp.Trap, nil
+	case seccomp.ActErrno:
+		return libseccomp.Errno(syscall.EPERM), nil
+	case seccomp.ActAllow:
+		return libseccomp.Allow, nil
+	}
+	return 0, fmt.Errorf("invalid seccomp action %q", a)
+}
+
+func seccompOperator(op seccomp.Operator) (libseccomp.Operator, error) {
+	switch op {
+	case seccomp.OpNotEqual:
+		return libseccomp.NotEqualTo, nil
+	case seccomp.OpLessThan:
+		return libseccomp.LessThan, nil
+	case seccomp.OpLessEqual:
+		return libseccomp.LessThanOrEqualTo, nil
+	case seccomp.OpEqualTo:
+		return libseccomp.EqualTo, nil
+	case seccomp.OpGreaterEqual:
+		return libseccomp.GreaterThanOrEqualTo, nil
+	case seccomp.OpGreaterThan:
+		return libseccomp.GreaterThan, nil
+	case seccomp.OpMaskedEqual:
+		return libseccomp.MaskEqualTo, nil
+	}
+	return 0, fmt.Errorf("invalid seccomp operator %q", op)
+}
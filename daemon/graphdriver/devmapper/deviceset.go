@@ -33,6 +33,7 @@ var (
 	DefaultThinpBlockSize       uint32 = 128      // 64K = 128 512b sectors
 	MaxDeviceId                 int    = 0xffffff // 24 bit, pool limit
 	DeviceIdMapSz               int    = (MaxDeviceId + 1) / 8
+	DefaultMinFreeSpacePercent  uint32 = 10
 )
 
 const deviceSetMetaFile string = "deviceset-metadata"
@@ -50,6 +51,7 @@ type DevInfo struct {
 	Size          uint64 `json:"size"`
 	TransactionId uint64 `json:"transaction_id"`
 	Initialized   bool   `json:"initialized"`
+	Deleted       bool   `json:"deleted"`
 	devices       *DeviceSet
 
 	mountCount int
@@ -96,7 +98,20 @@ type DeviceSet struct {
 	doBlkDiscard         bool
 	thinpBlockSize       uint32
 	thinPoolDevice       string
+	deferredRemove       bool   // use deferred removal of devices
+	deferredDelete       bool   // use deferred deletion of devices
+	minFreeSpacePercent  uint32 // new devices are refused below this free space in the pool
+	loopAutoExtendSize   int64  // the loopback data file grows by this size when the pool is full
 	Transaction          `json:"-"`
+
+	// whether the free space of the pool was low at the last check, to
+	// log an event when it gets low
+	lowSpace bool
+
+	// devices which could not be deleted while busy, and which the
+	// deletion worker retries to delete
+	nrDeletedDevices     uint
+	deletionWorkerTicker *time.Ticker
 }
 
 type DiskUsage struct {
@@ -115,6 +130,12 @@ type Status struct {
 	Metadata          DiskUsage
 	SectorSize        uint64
 	UdevSyncSupported bool
+
+	DeferredRemoveEnabled      bool
+	DeferredDeleteEnabled      bool
+	DeferredDeletedDeviceCount uint
+	MinFreeSpacePercent        uint32
+	LoopAutoExtendSize         int64
 }
 
 type DevStatus struct {
@@ -353,6 +374,13 @@ func (devices *DeviceSet) deviceFileWalkFunction(path string, finfo os.FileInfo)
 
 	devices.Lock()
 	devices.markDeviceIdUsed(dinfo.DeviceId)
+	if dinfo.Deleted {
+		// the deletion worker needs to find it
+		devices.devicesLock.Lock()
+		devices.Devices[hash] = dinfo
+		devices.devicesLock.Unlock()
+		devices.nrDeletedDevices++
+	}
 	devices.Unlock()
 
 	log.Debugf("Added deviceId=%d to DeviceIdMap", dinfo.DeviceId)
@@ -428,6 +456,16 @@ func (devices *DeviceSet) registerDevice(id int, hash string, size uint64, trans
 func (devices *DeviceSet) activateDeviceIfNeeded(info *DevInfo) error {
 	log.Debugf("activateDeviceIfNeeded(%v)", info.Hash)
 
+	if info.Deleted {
+		return fmt.Errorf("Can't activate device %v as it is marked for deletion", info.Hash)
+	}
+
+	// The device may still exist with a removal scheduled, which must
+	// not happen once it is in use again
+	if err := devices.cancelDeferredRemoval(info); err != nil {
+		return fmt.Errorf("Device Deferred Removal Cancellation Failed: %s", err)
+	}
+
 	if devinfo, _ := devicemapper.GetInfo(info.Name()); devinfo != nil && devinfo.Exists != 0 {
 		return nil
 	}
@@ -587,7 +625,7 @@ func (devices *DeviceSet) createRegisterDevice(hash string) (*DevInfo, error) {
 	return info, nil
 }
 
-func (devices *DeviceSet) createRegisterSnapDevice(hash string, baseInfo *DevInfo) error {
+func (devices *DeviceSet) createRegisterSnapDevice(hash string, baseInfo *DevInfo, size uint64) error {
 	deviceId, err := devices.getNextFreeDeviceId()
 	if err != nil {
 		return err
@@ -622,7 +660,7 @@ func (devices *DeviceSet) createRegisterSnapDevice(hash string, baseInfo *DevInf
 		break
 	}
 
-	if _, err := devices.registerDevice(deviceId, hash, baseInfo.Size, devices.OpenTransactionId); err != nil {
+	if _, err := devices.registerDevice(deviceId, hash, size, devices.OpenTransactionId); err != nil {
 		devicemapper.DeleteDevice(devices.getPoolDevName(), deviceId)
 		devices.markDeviceIdFree(deviceId)
 		log.Debugf("Error registering device: %s", err)
@@ -661,7 +699,7 @@ func (devices *DeviceSet) setupBaseImage() error {
 
 	if oldInfo != nil && !oldInfo.Initialized {
 		log.Debugf("Removing uninitialized base image")
-		if err := devices.DeleteDevice(""); err != nil {
+		if err := devices.DeleteDevice("", true); err != nil {
 			return err
 		}
 	}
@@ -733,6 +771,16 @@ func (devices *DeviceSet) DMLog(level int, file string, line int, dmError int, m
 	log.Debugf("libdevmapper(%d): %s:%d (%d) %s", level, file, line, dmError, message)
 }
 
+// deferredRemoveSupported returns whether the kernel driver of version
+// driverVersion, such as "4.27.0", supports the deferred removal of devices.
+func deferredRemoveSupported(driverVersion string) (bool, error) {
+	var major, minor int
+	if _, err := fmt.Sscanf(driverVersion, "%d.%d", &major, &minor); err != nil {
+		return false, fmt.Errorf("Invalid device-mapper driver version %q: %v", driverVersion, err)
+	}
+	return major > 4 || (major == 4 && minor >= 27), nil
+}
+
 func major(device uint64) uint64 {
 	return (device >> 8) & 0xfff
 }
@@ -744,11 +792,15 @@ func minor(device uint64) uint64 {
 func (devices *DeviceSet) ResizePool(size int64) error {
 	dirname := devices.loopbackDir()
 	datafilename := path.Join(dirname, "data")
-	if len(devices.dataDevice) > 0 {
+	if len(devices.dataLoopFile) > 0 {
+		datafilename = devices.dataLoopFile
+	} else if len(devices.dataDevice) > 0 {
 		datafilename = devices.dataDevice
 	}
 	metadatafilename := path.Join(dirname, "metadata")
-	if len(devices.metadataDevice) > 0 {
+	if len(devices.metadataLoopFile) > 0 {
+		metadatafilename = devices.metadataLoopFile
+	} else if len(devices.metadataDevice) > 0 {
 		metadatafilename = devices.metadataDevice
 	}
 
@@ -813,6 +865,103 @@ func (devices *DeviceSet) ResizePool(size int64) error {
 	return nil
 }
 
+// minFreeBlocks returns how many of the total blocks of the pool must be
+// left free, at least one.
+func (devices *DeviceSet) minFreeBlocks(total uint64) uint64 {
+	min := total * uint64(devices.minFreeSpacePercent) / 100
+	if min == 0 {
+		min = 1
+	}
+	return min
+}
+
+// checkFreeSpace returns an error when the free data or metadata space of
+// the pool is below dm.min_free_space, after growing the loopback data
+// file when dm.loopautoextend is set. It logs an event when the free space
+// drops below twice the minimum.
+func (devices *DeviceSet) checkFreeSpace() error {
+	if devices.minFreeSpacePercent == 0 {
+		return nil
+	}
+
+	_, _, dataUsed, dataTotal, metadataUsed, metadataTotal, err := devices.poolStatus()
+	if err != nil {
+		return err
+	}
+
+	minFreeData := devices.minFreeBlocks(dataTotal)
+	if dataTotal-dataUsed < minFreeData && devices.loopAutoExtendSize > 0 {
+		if err := devices.extendPool(); err != nil {
+			log.Errorf("Error extending thin pool %s: %s", devices.getPoolName(), err)
+		} else if _, _, dataUsed, dataTotal, metadataUsed, metadataTotal, err = devices.poolStatus(); err != nil {
+			return err
+		}
+		minFreeData = devices.minFreeBlocks(dataTotal)
+	}
+	minFreeMetadata := devices.minFreeBlocks(metadataTotal)
+
+	lowSpace := dataTotal-dataUsed < 2*minFreeData || metadataTotal-metadataUsed < 2*minFreeMetadata
+	if lowSpace && !devices.lowSpace {
+		log.Warnf("Thin pool %s is running out of space", devices.getPoolName())
+		graphdriver.LogEvent("pool_low_space", devices.getPoolName())
+	}
+	devices.lowSpace = lowSpace
+
+	if dataTotal-dataUsed < minFreeData {
+		return fmt.Errorf("Thin pool has %v free data blocks which is less than minimum required %v free data blocks. Create more free space in thin pool or use dm.min_free_space option to change behavior", dataTotal-dataUsed, minFreeData)
+	}
+	if metadataTotal-metadataUsed < minFreeMetadata {
+		return fmt.Errorf("Thin pool has %v free metadata blocks which is less than minimum required %v free metadata blocks. Create more free metadata space in thin pool or use dm.min_free_space option to change behavior", metadataTotal-metadataUsed, minFreeMetadata)
+	}
+	return nil
+}
+
+// extendPool grows the loopback data file of the pool by dm.loopautoextend.
+func (devices *DeviceSet) extendPool() error {
+	datafilename := devices.dataLoopFile
+	if datafilename == "" {
+		datafilename = path.Join(devices.loopbackDir(), "data")
+	}
+	fi, err := os.Stat(datafilename)
+	if err != nil {
+		return err
+	}
+
+	size := fi.Size() + devices.loopAutoExtendSize
+	if err := devices.ResizePool(size); err != nil {
+		return err
+	}
+
+	log.Infof("Extended thin pool %s to %s", devices.getPoolName(), units.HumanSize(float64(size)))
+	graphdriver.LogEvent("pool_extend", devices.getPoolName())
+	return nil
+}
+
+// Warnings returns a warning when the free space of the pool is below
+// twice dm.min_free_space, new devices being refused below it.
+func (devices *DeviceSet) Warnings() []string {
+	devices.Lock()
+	defer devices.Unlock()
+
+	if devices.minFreeSpacePercent == 0 {
+		return nil
+	}
+
+	_, _, dataUsed, dataTotal, metadataUsed, metadataTotal, err := devices.poolStatus()
+	if err != nil {
+		return nil
+	}
+
+	var warnings []string
+	if dataTotal-dataUsed < 2*devices.minFreeBlocks(dataTotal) {
+		warnings = append(warnings, fmt.Sprintf("Thin pool %s is running out of data space: %d of %d blocks free, new containers are refused below %d%%", devices.getPoolName(), dataTotal-dataUsed, dataTotal, devices.minFreeSpacePercent))
+	}
+	if metadataTotal-metadataUsed < 2*devices.minFreeBlocks(metadataTotal) {
+		warnings = append(warnings, fmt.Sprintf("Thin pool %s is running out of metadata space: %d of %d blocks free, new containers are refused below %d%%", devices.getPoolName(), metadataTotal-metadataUsed, metadataTotal, devices.minFreeSpacePercent))
+	}
+	return warnings
+}
+
 func (devices *DeviceSet) loadTransactionMetaData() error {
 	jsonData, err := ioutil.ReadFile(devices.transactionMetaFile())
 	if err != nil {
@@ -955,12 +1104,25 @@ func (devices *DeviceSet) initDevmapper(doInit bool) error {
 	// give ourselves to libdm as a log handler
 	devicemapper.LogInit(devices)
 
-	_, err := devicemapper.GetDriverVersion()
+	driverVersion, err := devicemapper.GetDriverVersion()
 	if err != nil {
 		// Can't even get driver version, assume not supported
 		return graphdriver.ErrNotSupported
 	}
 
+	if devices.deferredRemove {
+		if !devicemapper.LibraryDeferredRemovalSupport {
+			return fmt.Errorf("Deferred removal can not be enabled as libdevmapper does not support it, 1.02.89 or later is needed")
+		}
+		supported, err := deferredRemoveSupported(driverVersion)
+		if err != nil {
+			return err
+		}
+		if !supported {
+			return fmt.Errorf("Deferred removal can not be enabled as the kernel device-mapper driver version %s does not support it, 4.27.0 or later is needed", driverVersion)
+		}
+	}
+
 	// https://github.com/docker/docker/issues/4036
 	if supported := devicemapper.UdevSetSyncSupport(true); !supported {
 		log.Warnf("WARNING: Udev sync is not supported. This will lead to unexpected behavior, data loss and errors")
@@ -1110,9 +1272,11 @@ func (devices *DeviceSet) initDevmapper(doInit bool) error {
 	return nil
 }
 
-func (devices *DeviceSet) AddDevice(hash, baseHash string) error {
-	log.Debugf("[deviceset] AddDevice(hash=%s basehash=%s)", hash, baseHash)
-	defer log.Debugf("[deviceset] AddDevice(hash=%s basehash=%s) END", hash, baseHash)
+// AddDevice adds a snapshot of the device baseHash named hash. The
+// snapshot is size bytes large, or as large as its base if size is 0.
+func (devices *DeviceSet) AddDevice(hash, baseHash string, size uint64) error {
+	log.Debugf("[deviceset] AddDevice(hash=%s basehash=%s size=%d)", hash, baseHash, size)
+	defer log.Debugf("[deviceset] AddDevice(hash=%s basehash=%s size=%d) END", hash, baseHash, size)
 
 	baseInfo, err := devices.lookupDevice(baseHash)
 	if err != nil {
@@ -1129,15 +1293,90 @@ func (devices *DeviceSet) AddDevice(hash, baseHash string) error {
 		return fmt.Errorf("device %s already exists", hash)
 	}
 
-	if err := devices.createRegisterSnapDevice(hash, baseInfo); err != nil {
+	if size == 0 {
+		size = baseInfo.Size
+	}
+	if size < baseInfo.Size {
+		return fmt.Errorf("Container size cannot be smaller than %s", units.HumanSize(float64(baseInfo.Size)))
+	}
+
+	if baseInfo.Deleted {
+		return fmt.Errorf("Base device %v has been marked for deferred deletion", baseInfo.Hash)
+	}
+
+	if err := devices.checkFreeSpace(); err != nil {
 		return err
 	}
 
+	if err := devices.createRegisterSnapDevice(hash, baseInfo, size); err != nil {
+		return err
+	}
+
+	// the filesystem of the snapshot is still the size of its base
+	if size > baseInfo.Size {
+		info, err := devices.lookupDevice(hash)
+		if err != nil {
+			return err
+		}
+		if err := devices.growFS(info); err != nil {
+			devices.deleteDevice(info, true)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// growFS grows the filesystem of the device to the size of the device.
+func (devices *DeviceSet) growFS(info *DevInfo) error {
+	if err := devices.activateDeviceIfNeeded(info); err != nil {
+		return fmt.Errorf("Error activating devmapper device: %s", err)
+	}
+	defer devices.deactivateDevice(info)
+
+	fsMountPoint := path.Join(devices.root, "mnt", info.Hash)
+	if err := os.MkdirAll(fsMountPoint, 0700); err != nil {
+		return err
+	}
+	defer os.RemoveAll(fsMountPoint)
+
+	fstype, err := ProbeFsType(info.DevName())
+	if err != nil {
+		return err
+	}
+
+	options := ""
+	if fstype == "xfs" {
+		// XFS needs nouuid or it can't mount filesystems with the same fs
+		options = joinMountOptions(options, "nouuid")
+	}
+	options = joinMountOptions(options, devices.mountOptions)
+
+	if err := syscall.Mount(info.DevName(), fsMountPoint, fstype, syscall.MS_MGC_VAL, options); err != nil {
+		return fmt.Errorf("Error mounting '%s' on '%s': %s", info.DevName(), fsMountPoint, err)
+	}
+	defer syscall.Unmount(fsMountPoint, syscall.MNT_DETACH)
+
+	switch fstype {
+	case "ext4":
+		if out, err := exec.Command("resize2fs", info.DevName()).CombinedOutput(); err != nil {
+			return fmt.Errorf("Failed to grow rootfs:%v:%s", err, string(out))
+		}
+	case "xfs":
+		if out, err := exec.Command("xfs_growfs", fsMountPoint).CombinedOutput(); err != nil {
+			return fmt.Errorf("Failed to grow rootfs:%v:%s", err, string(out))
+		}
+	default:
+		return fmt.Errorf("Unsupported filesystem type %s", fstype)
+	}
 	return nil
 }
 
-func (devices *DeviceSet) deleteDevice(info *DevInfo) error {
-	if devices.doBlkDiscard {
+// deleteDevice deletes the thin device of info. Unless syncDelete is set,
+// a busy device is marked for deletion when deferred deletion is enabled,
+// and deleted later by the deletion worker.
+func (devices *DeviceSet) deleteDevice(info *DevInfo, syncDelete bool) error {
+	if devices.doBlkDiscard && !info.Deleted {
 		// This is a workaround for the kernel not discarding block so
 		// on the thin pool when we remove a thinp device, so we do it
 		// manually
@@ -1150,7 +1389,7 @@ func (devices *DeviceSet) deleteDevice(info *DevInfo) error {
 
 	devinfo, _ := devicemapper.GetInfo(info.Name())
 	if devinfo != nil && devinfo.Exists != 0 {
-		if err := devices.removeDeviceAndWait(info.Name()); err != nil {
+		if err := devices.deactivateDevice(info); err != nil {
 			log.Debugf("Error removing device: %s", err)
 			return err
 		}
@@ -1163,7 +1402,15 @@ func (devices *DeviceSet) deleteDevice(info *DevInfo) error {
 
 	if err := devicemapper.DeleteDevice(devices.getPoolDevName(), info.DeviceId); err != nil {
 		log.Debugf("Error deleting device: %s", err)
-		return err
+		if syncDelete || !devices.deferredDelete || err != devicemapper.ErrBusy {
+			return err
+		}
+		// the pool keeps a thin device while it is open in another mount
+		// namespace, try again later
+		if err := devices.markForDeferredDeletion(info); err != nil {
+			return err
+		}
+		return devices.closeTransaction()
 	}
 
 	if err := devices.unregisterDevice(info.DeviceId, info.Hash); err != nil {
@@ -1174,12 +1421,34 @@ func (devices *DeviceSet) deleteDevice(info *DevInfo) error {
 		return err
 	}
 
+	if info.Deleted {
+		devices.nrDeletedDevices--
+	}
 	devices.markDeviceIdFree(info.DeviceId)
 
 	return nil
 }
 
-func (devices *DeviceSet) DeleteDevice(hash string) error {
+func (devices *DeviceSet) markForDeferredDeletion(info *DevInfo) error {
+	if info.Deleted {
+		return nil
+	}
+
+	log.Debugf("[devmapper] Marking device %s for deferred deletion", info.Hash)
+
+	info.Deleted = true
+	if err := devices.saveMetadata(info); err != nil {
+		info.Deleted = false
+		return err
+	}
+
+	devices.nrDeletedDevices++
+	return nil
+}
+
+// DeleteDevice deletes the device hash. With syncDelete, the device is
+// deleted now or an error is returned, even if deferred deletion is enabled.
+func (devices *DeviceSet) DeleteDevice(hash string, syncDelete bool) error {
 	info, err := devices.lookupDevice(hash)
 	if err != nil {
 		return err
@@ -1191,7 +1460,49 @@ func (devices *DeviceSet) DeleteDevice(hash string) error {
 	devices.Lock()
 	defer devices.Unlock()
 
-	return devices.deleteDevice(info)
+	return devices.deleteDevice(info, syncDelete)
+}
+
+// cleanupDeletedDevices retries to delete the devices marked for deletion.
+func (devices *DeviceSet) cleanupDeletedDevices() {
+	devices.Lock()
+	if devices.nrDeletedDevices == 0 {
+		devices.Unlock()
+		return
+	}
+	devices.Unlock()
+
+	var deletedDevices []*DevInfo
+	devices.devicesLock.Lock()
+	for _, info := range devices.Devices {
+		if info.Deleted {
+			deletedDevices = append(deletedDevices, info)
+		}
+	}
+	devices.devicesLock.Unlock()
+
+	// DeleteDevice takes the device lock before the global lock, so
+	// none can be held here
+	for _, info := range deletedDevices {
+		if err := devices.DeleteDevice(info.Hash, false); err != nil {
+			log.Warnf("Deletion of device %s, device_id=%v failed: %v", info.Hash, info.DeviceId, err)
+		}
+	}
+}
+
+func (devices *DeviceSet) startDeviceDeletionWorker() {
+	// Deferred deletion is not enabled. Don't do anything.
+	if !devices.deferredDelete {
+		return
+	}
+
+	log.Debugf("[devmapper] Worker to cleanup deleted devices started")
+	devices.deletionWorkerTicker = time.NewTicker(30 * time.Second)
+	go func(ticker *time.Ticker) {
+		for range ticker.C {
+			devices.cleanupDeletedDevices()
+		}
+	}(devices.deletionWorkerTicker)
 }
 
 func (devices *DeviceSet) deactivatePool() error {
@@ -1218,23 +1529,63 @@ func (devices *DeviceSet) deactivateDevice(info *DevInfo) error {
 	log.Debugf("[devmapper] deactivateDevice(%s)", info.Hash)
 	defer log.Debugf("[devmapper] deactivateDevice END(%s)", info.Hash)
 
+	devinfo, err := devicemapper.GetInfo(info.Name())
+	if err != nil {
+		return err
+	}
+	if devinfo.Exists == 0 {
+		return nil
+	}
+
+	// The device goes away when the last user closes it, whatever
+	// mount namespace it is open in
+	if devices.deferredRemove {
+		return devicemapper.RemoveDeviceDeferred(info.Name())
+	}
+
 	// Wait for the unmount to be effective,
 	// by watching the value of Info.OpenCount for the device
 	if err := devices.waitClose(info); err != nil {
 		log.Errorf("Warning: error waiting for device %s to close: %s", info.Hash, err)
 	}
 
-	devinfo, err := devicemapper.GetInfo(info.Name())
-	if err != nil {
-		return err
+	return devices.removeDeviceAndWait(info.Name())
+}
+
+// cancelDeferredRemoval cancels the removal scheduled on the device of
+// info by an earlier deactivation, if any.
+func (devices *DeviceSet) cancelDeferredRemoval(info *DevInfo) error {
+	if !devices.deferredRemove {
+		return nil
 	}
-	if devinfo.Exists != 0 {
-		if err := devices.removeDeviceAndWait(info.Name()); err != nil {
+
+	devinfo, err := devicemapper.GetInfoWithDeferred(info.Name())
+	if err != nil || devinfo.Exists == 0 || devinfo.DeferredRemove == 0 {
+		return nil
+	}
+
+	log.Debugf("[devmapper] cancelDeferredRemoval(%s)", info.Hash)
+
+	for i := 0; i < 100; i++ {
+		err = devicemapper.CancelDeferredRemove(info.Name())
+		if err == nil {
+			return nil
+		}
+		if err == devicemapper.ErrEnxio {
+			// the device was removed meanwhile
+			return nil
+		}
+		if err != devicemapper.ErrBusy {
 			return err
 		}
-	}
 
-	return nil
+		// If we see EBUSY it may be a transient error,
+		// sleep a bit a retry a few times.
+		devices.Unlock()
+		time.Sleep(100 * time.Millisecond)
+		devices.Lock()
+	}
+	return err
 }
 
 // Issues the underlying dm remove operation and then waits
@@ -1329,6 +1680,12 @@ func (devices *DeviceSet) Shutdown() error {
 	log.Debugf("[devmapper] Shutting down DeviceSet: %s", devices.root)
 	defer log.Debugf("[deviceset %s] Shutdown() END", devices.devicePrefix)
 
+	// No deletion of the devices marked for deletion starts after this,
+	// a running one holds the locks taken below
+	if devices.deletionWorkerTicker != nil {
+		devices.deletionWorkerTicker.Stop()
+	}
+
 	var devs []*DevInfo
 
 	devices.devicesLock.Lock()
@@ -1480,7 +1837,8 @@ func (devices *DeviceSet) HasDevice(hash string) bool {
 	defer devices.Unlock()
 
 	info, _ := devices.lookupDevice(hash)
-	return info != nil
+	// devices marked for deletion are gone for their users
+	return info != nil && !info.Deleted
 }
 
 func (devices *DeviceSet) HasActivatedDevice(hash string) bool {
@@ -1615,6 +1973,11 @@ func (devices *DeviceSet) Status() *Status {
 	status.MetadataFile = devices.MetadataDevicePath()
 	status.MetadataLoopback = devices.metadataLoopFile
 	status.UdevSyncSupported = devicemapper.UdevSyncSupported()
+	status.DeferredRemoveEnabled = devices.deferredRemove
+	status.DeferredDeleteEnabled = devices.deferredDelete
+	status.DeferredDeletedDeviceCount = devices.nrDeletedDevices
+	status.MinFreeSpacePercent = devices.minFreeSpacePercent
+	status.LoopAutoExtendSize = devices.loopAutoExtendSize
 
 	totalSizeInSectors, _, dataUsed, dataTotal, metadataUsed, metadataTotal, err := devices.poolStatus()
 	if err == nil {
@@ -1662,6 +2025,7 @@ func NewDeviceSet(root string, doInit bool, options []string) (*DeviceSet, error
 		filesystem:           "ext4",
 		doBlkDiscard:         true,
 		thinpBlockSize:       DefaultThinpBlockSize,
+		minFreeSpacePercent:  DefaultMinFreeSpacePercent,
 		deviceIdMap:          make([]byte, DeviceIdMapSz),
 	}
 
@@ -1712,6 +2076,34 @@ func NewDeviceSet(root string, doInit bool, options []string) (*DeviceSet, error
 			if err != nil {
 				return nil, err
 			}
+		case "dm.use_deferred_removal":
+			devices.deferredRemove, err = strconv.ParseBool(val)
+			if err != nil {
+				return nil, err
+			}
+		case "dm.use_deferred_deletion":
+			devices.deferredDelete, err = strconv.ParseBool(val)
+			if err != nil {
+				return nil, err
+			}
+		case "dm.min_free_space":
+			if !strings.HasSuffix(val, "%") {
+				return nil, fmt.Errorf("Invalid value %s for dm.min_free_space, it must be a percentage such as 10%%", val)
+			}
+			percent, err := strconv.ParseUint(strings.TrimSuffix(val, "%"), 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			if percent >= 100 {
+				return nil, fmt.Errorf("Invalid value %s for dm.min_free_space, it must be below 100%%", val)
+			}
+			devices.minFreeSpacePercent = uint32(percent)
+		case "dm.loopautoextend":
+			size, err := units.RAMInBytes(val)
+			if err != nil {
+				return nil, err
+			}
+			devices.loopAutoExtendSize = size
 		case "dm.blocksize":
 			size, err := units.RAMInBytes(val)
 			if err != nil {
@@ -1729,9 +2121,22 @@ func NewDeviceSet(root string, doInit bool, options []string) (*DeviceSet, error
 		devices.doBlkDiscard = false
 	}
 
+	// only the sparse loopback files can be grown by the daemon
+	if devices.loopAutoExtendSize > 0 && (devices.dataDevice != "" || devices.thinPoolDevice != "") {
+		return nil, fmt.Errorf("dm.loopautoextend can only be used with loopback files, not with dm.datadev or dm.thinpooldev")
+	}
+
+	// a device is not deleted until it is removed, which may take a while
+	// when it is still open
+	if devices.deferredDelete && !devices.deferredRemove {
+		return nil, fmt.Errorf("Deferred deletion can not be enabled as deferred removal is not enabled. Enable deferred removal using --storage-opt dm.use_deferred_removal=true")
+	}
+
 	if err := devices.initDevmapper(doInit); err != nil {
 		return nil, err
 	}
 
+	devices.startDeviceDeletionWorker()
+
 	return devices, nil
 }
@@ -0,0 +1,41 @@
+// +build linux
+
+package devmapper
+
+import "testing"
+
+func TestDeferredRemoveSupported(t *testing.T) {
+	for version, expected := range map[string]bool{
+		"4.27.0":  true,
+		"4.33.0":  true,
+		"5.0.0":   true,
+		"4.26.0":  false,
+		"4.7.12":  false,
+		"3.100.0": false,
+	} {
+		supported, err := deferredRemoveSupported(version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if supported != expected {
+			t.Fatalf("Expected deferred removal support %v for driver version %s, got %v", expected, version, supported)
+		}
+	}
+
+	if _, err := deferredRemoveSupported("invalid"); err == nil {
+		t.Fatal("Expected an error for an invalid driver version")
+	}
+}
+
+func TestMinFreeBlocks(t *testing.T) {
+	devices := &DeviceSet{minFreeSpacePercent: 10}
+	for total, expected := range map[uint64]uint64{
+		1000: 100,
+		15:   1,
+		5:    1,
+	} {
+		if min := devices.minFreeBlocks(total); min != expected {
+			t.Fatalf("Expected %d minimum free blocks out of %d, got %d", expected, total, min)
+		}
+	}
+}
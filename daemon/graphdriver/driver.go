@@ -9,6 +9,9 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/plugins"
+	"github.com/docker/docker/pkg/units"
 )
 
 type FsMagic uint32
@@ -42,12 +45,18 @@ var (
 		"vfs",
 		// experimental, has to be enabled manually for now
 		"overlay",
+		// overlay2 is never picked by default, it keeps its images apart
+		// from the ones of overlay and has to be selected with -s overlay2
 	}
 
 	ErrNotSupported   = errors.New("driver not supported")
 	ErrPrerequisites  = errors.New("prerequisites for driver not satisfied (wrong filesystem?)")
 	ErrIncompatibleFS = fmt.Errorf("backing file system is unsupported for this graph driver")
 
+	// ErrQuotaNotSupported is returned when creating a layer with a size
+	// that the driver can't enforce.
+	ErrQuotaNotSupported = errors.New("the size of containers can't be limited with this storage driver and backing filesystem")
+
 	FsNames = map[FsMagic]string{
 		FsMagicAufs:        "aufs",
 		FsMagicBtrfs:       "btrfs",
@@ -66,7 +75,10 @@ var (
 	}
 )
 
-type InitFunc func(root string, options []string) (Driver, error)
+// InitFunc initializes the storage driver. The uid and gid maps describe the
+// remapped root the daemon runs containers as; drivers must create the
+// directories they hand out owned by that root.
+type InitFunc func(root string, options []string, uidMaps, gidMaps []idtools.IDMap) (Driver, error)
 
 // ProtoDriver defines the basic capabilities of a driver.
 // This interface exists solely to be a minimum set of methods
@@ -78,8 +90,10 @@ type ProtoDriver interface {
 	// String returns a string representation of this driver.
 	String() string
 	// Create creates a new, empty, filesystem layer with the
-	// specified id and parent. Parent may be "".
-	Create(id, parent string) error
+	// specified id and parent. Parent may be "". storageOpt holds
+	// the options of the layer, such as its "size"; drivers refuse
+	// the options they do not support.
+	Create(id, parent string, storageOpt map[string]string) error
 	// Remove attempts to remove the filesystem layer with this id.
 	Remove(id string) error
 	// Get returns the mountpoint for the layered filesystem referred
@@ -120,6 +134,18 @@ type Driver interface {
 	DiffSize(id, parent string) (size int64, err error)
 }
 
+// Warner is implemented by the drivers which report conditions of their
+// storage needing attention, such as a storage pool running out of space.
+type Warner interface {
+	// Warnings returns a message for each such condition.
+	Warnings() []string
+}
+
+// LogEvent logs an event of the storage of a driver, such as a storage
+// pool running low on space. id names the storage. It is set by the
+// daemon, and discards events until then.
+var LogEvent = func(action, id string) {}
+
 func init() {
 	drivers = make(map[string]InitFunc)
 }
@@ -133,23 +159,31 @@ func Register(name string, initFunc InitFunc) error {
 	return nil
 }
 
-func GetDriver(name, home string, options []string) (Driver, error) {
+func GetDriver(name, home string, options []string, uidMaps, gidMaps []idtools.IDMap) (Driver, error) {
 	if initFunc, exists := drivers[name]; exists {
-		return initFunc(path.Join(home, name), options)
+		return initFunc(path.Join(home, name), options, uidMaps, gidMaps)
 	}
-	return nil, ErrNotSupported
+	// drivers which aren't built in may be plugins
+	driver, err := lookupPlugin(name, home, options, uidMaps, gidMaps)
+	if err != nil {
+		if err == plugins.ErrNotFound {
+			return nil, ErrNotSupported
+		}
+		return nil, err
+	}
+	return driver, nil
 }
 
-func New(root string, options []string) (driver Driver, err error) {
+func New(root string, options []string, uidMaps, gidMaps []idtools.IDMap) (driver Driver, err error) {
 	for _, name := range []string{os.Getenv("DOCKER_DRIVER"), DefaultDriver} {
 		if name != "" {
-			return GetDriver(name, root, options)
+			return GetDriver(name, root, options, uidMaps, gidMaps)
 		}
 	}
 
 	// Check for priority drivers first
 	for _, name := range priority {
-		driver, err = GetDriver(name, root, options)
+		driver, err = GetDriver(name, root, options, uidMaps, gidMaps)
 		if err != nil {
 			if err == ErrNotSupported || err == ErrPrerequisites || err == ErrIncompatibleFS {
 				continue
@@ -162,7 +196,10 @@ func New(root string, options []string) (driver Driver, err error) {
 
 	// Check all registered drivers if no priority driver is found
 	for name, initFunc := range drivers {
-		if driver, err = initFunc(root, options); err != nil {
+		if name == "overlay2" {
+			continue
+		}
+		if driver, err = initFunc(root, options, uidMaps, gidMaps); err != nil {
 			if err == ErrNotSupported || err == ErrPrerequisites || err == ErrIncompatibleFS {
 				continue
 			}
@@ -174,6 +211,29 @@ func New(root string, options []string) (driver Driver, err error) {
 	return nil, fmt.Errorf("No supported storage backend found")
 }
 
+// ParseStorageOptSize returns the size limit in bytes that the storage
+// options of a layer give, or 0 if they give none. Options other than
+// "size" are refused.
+func ParseStorageOptSize(storageOpt map[string]string) (uint64, error) {
+	var size uint64
+	for key, val := range storageOpt {
+		switch strings.ToLower(key) {
+		case "size":
+			s, err := units.RAMInBytes(val)
+			if err != nil {
+				return 0, fmt.Errorf("Invalid storage size %q: %v", val, err)
+			}
+			if s <= 0 {
+				return 0, fmt.Errorf("Invalid storage size %q: must be positive", val)
+			}
+			size = uint64(s)
+		default:
+			return 0, fmt.Errorf("Unknown storage option: %s", key)
+		}
+	}
+	return size, nil
+}
+
 func checkPriorDriver(name, root string) {
 	priorDrivers := []string{}
 	for prior := range drivers {
@@ -0,0 +1,371 @@
+// +build linux
+
+package overlay2
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/daemon/graphdriver/quota"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/parsers/kernel"
+	"github.com/docker/libcontainer/label"
+)
+
+// This backend uses the overlay union filesystem with one lower directory
+// per layer, which needs the multiple lower layers of Linux 4.0.
+
+// Each layer has a "diff" directory with its own changes, and a "link"
+// file with a short name of the layer. The short name is a symlink in the
+// "l" directory of the driver to the "diff" directory of the layer.
+
+// Layers with a parent also have a "lower" file, which lists the short
+// names of all their ancestors, the parent first, separated by colons.
+// The overlay is mounted in their "merged" directory with the "diff"
+// directory as the upper layer, the ancestors as the lower layers, and the
+// "work" directory needed by overlay. Layers without a parent are used
+// as is: their "diff" directory is the root filesystem.
+
+// The short names keep the mount options of deep image chains under the
+// size of a page. If they still exceed it, the overlay is mounted from the
+// driver directory, with paths relative to it.
+
+const (
+	linkDir   = "l"
+	lowerFile = "lower"
+	maxDepth  = 128
+
+	// idLength is the length of the short names of the layers, a
+	// base32 encoded 128 bits random value
+	idLength = 26
+)
+
+type ActiveMount struct {
+	count   int
+	path    string
+	mounted bool
+}
+
+type Driver struct {
+	home       string
+	sync.Mutex // Protects concurrent modification to active
+	active     map[string]*ActiveMount
+	uidMaps    []idtools.IDMap
+	gidMaps    []idtools.IDMap
+	quotaCtl   *quota.Control
+}
+
+var backingFs = "<unknown>"
+
+func init() {
+	graphdriver.Register("overlay2", Init)
+}
+
+func Init(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (graphdriver.Driver, error) {
+	if err := supportsOverlay(); err != nil {
+		return nil, graphdriver.ErrNotSupported
+	}
+
+	// multiple lower layers are supported since 4.0
+	v, err := kernel.GetKernelVersion()
+	if err != nil {
+		return nil, err
+	}
+	if kernel.CompareKernelVersion(v, &kernel.KernelVersionInfo{Kernel: 4, Major: 0, Minor: 0}) < 0 {
+		log.Errorf("'overlay2' needs a kernel with multiple lower layers support (4.0 or later), this is %s.", v)
+		return nil, graphdriver.ErrNotSupported
+	}
+
+	fsMagic, err := graphdriver.GetFSMagic(home)
+	if err != nil {
+		return nil, err
+	}
+	if fsName, ok := graphdriver.FsNames[fsMagic]; ok {
+		backingFs = fsName
+	}
+
+	// check if they are running over btrfs or aufs
+	switch fsMagic {
+	case graphdriver.FsMagicBtrfs:
+		log.Error("'overlay2' is not supported over btrfs.")
+		return nil, graphdriver.ErrIncompatibleFS
+	case graphdriver.FsMagicAufs:
+		log.Error("'overlay2' is not supported over aufs.")
+		return nil, graphdriver.ErrIncompatibleFS
+	case graphdriver.FsMagicZfs:
+		log.Error("'overlay2' is not supported over zfs.")
+		return nil, graphdriver.ErrIncompatibleFS
+	}
+
+	rootUID, rootGID, err := idtools.GetRootUIDGID(uidMaps, gidMaps)
+	if err != nil {
+		return nil, err
+	}
+	// Create the driver home dir, and the dir of the short names
+	if err := idtools.MkdirAllAs(path.Join(home, linkDir), 0700, rootUID, rootGID); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+
+	d := &Driver{
+		home:    home,
+		active:  make(map[string]*ActiveMount),
+		uidMaps: uidMaps,
+		gidMaps: gidMaps,
+	}
+
+	if fsMagic == graphdriver.FsMagicXfs {
+		if d.quotaCtl, err = quota.NewControl(home); err != nil {
+			log.Debugf("%s: project quotas are not enforced on %s: %v", d, home, err)
+		}
+	}
+
+	return graphdriver.NaiveDiffDriver(d, uidMaps, gidMaps), nil
+}
+
+func supportsOverlay() error {
+	// We can try to modprobe overlay first before looking at
+	// proc/filesystems for when overlay is supported
+	exec.Command("modprobe", "overlay").Run()
+
+	f, err := os.Open("/proc/filesystems")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if s.Text() == "nodev\toverlay" {
+			return nil
+		}
+	}
+	log.Error("'overlay' not found as a supported filesystem on this host. Please ensure kernel is new enough and has overlay support loaded.")
+	return graphdriver.ErrNotSupported
+}
+
+func (d *Driver) String() string {
+	return "overlay2"
+}
+
+func (d *Driver) Status() [][2]string {
+	return [][2]string{
+		{"Backing Filesystem", backingFs},
+	}
+}
+
+func (d *Driver) Cleanup() error {
+	return nil
+}
+
+func (d *Driver) Create(id string, parent string, storageOpt map[string]string) (retErr error) {
+	size, err := graphdriver.ParseStorageOptSize(storageOpt)
+	if err != nil {
+		return err
+	}
+	if size > 0 && d.quotaCtl == nil {
+		return graphdriver.ErrQuotaNotSupported
+	}
+
+	dir := d.dir(id)
+
+	rootUID, rootGID, err := idtools.GetRootUIDGID(d.uidMaps, d.gidMaps)
+	if err != nil {
+		return err
+	}
+	if err := idtools.MkdirAllAs(path.Dir(dir), 0700, rootUID, rootGID); err != nil {
+		return err
+	}
+	if err := idtools.MkdirAs(dir, 0700, rootUID, rootGID); err != nil {
+		return err
+	}
+
+	defer func() {
+		// Clean up on failure
+		if retErr != nil {
+			d.removeLink(id)
+			os.RemoveAll(dir)
+		}
+	}()
+
+	if size > 0 {
+		if err := d.quotaCtl.SetQuota(dir, size); err != nil {
+			return err
+		}
+	}
+
+	if err := idtools.MkdirAs(path.Join(dir, "diff"), 0755, rootUID, rootGID); err != nil {
+		return err
+	}
+
+	lid := generateID()
+	if err := os.Symlink(path.Join("..", id, "diff"), path.Join(d.home, linkDir, lid)); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "link"), []byte(lid), 0644); err != nil {
+		return err
+	}
+
+	// Toplevel images are just a "diff" dir
+	if parent == "" {
+		return nil
+	}
+
+	lower, err := d.getLower(parent)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path.Join(dir, lowerFile), []byte(lower), 0644); err != nil {
+		return err
+	}
+	if err := idtools.MkdirAs(path.Join(dir, "work"), 0700, rootUID, rootGID); err != nil {
+		return err
+	}
+	return idtools.MkdirAs(path.Join(dir, "merged"), 0700, rootUID, rootGID)
+}
+
+// getLower returns the lower layers of a child of parent: the parent
+// followed by its own lower layers.
+func (d *Driver) getLower(parent string) (string, error) {
+	parentDir := d.dir(parent)
+
+	parentLink, err := ioutil.ReadFile(path.Join(parentDir, "link"))
+	if err != nil {
+		return "", err
+	}
+	lowers := []string{path.Join(linkDir, string(parentLink))}
+
+	parentLower, err := ioutil.ReadFile(path.Join(parentDir, lowerFile))
+	if err == nil {
+		lowers = append(lowers, strings.Split(string(parentLower), ":")...)
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	if len(lowers) > maxDepth {
+		return "", fmt.Errorf("max depth of %d layers exceeded", maxDepth)
+	}
+	return strings.Join(lowers, ":"), nil
+}
+
+func (d *Driver) dir(id string) string {
+	return path.Join(d.home, id)
+}
+
+// removeLink removes the short name of the layer, if it has one
+func (d *Driver) removeLink(id string) {
+	lid, err := ioutil.ReadFile(path.Join(d.dir(id), "link"))
+	if err != nil || len(lid) == 0 {
+		return
+	}
+	if err := os.Remove(path.Join(d.home, linkDir, string(lid))); err != nil {
+		log.Debugf("Failed to remove the link of %s: %v", id, err)
+	}
+}
+
+func (d *Driver) Remove(id string) error {
+	dir := d.dir(id)
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	d.removeLink(id)
+	return os.RemoveAll(dir)
+}
+
+func (d *Driver) Get(id string, mountLabel string) (string, error) {
+	// Protect the d.active from concurrent access
+	d.Lock()
+	defer d.Unlock()
+
+	mount := d.active[id]
+	if mount != nil {
+		mount.count++
+		return mount.path, nil
+	} else {
+		mount = &ActiveMount{count: 1}
+	}
+
+	dir := d.dir(id)
+	if _, err := os.Stat(dir); err != nil {
+		return "", err
+	}
+
+	// If id has no lower layers, just return its diff
+	lowers, err := ioutil.ReadFile(path.Join(dir, lowerFile))
+	if os.IsNotExist(err) {
+		mount.path = path.Join(dir, "diff")
+		d.active[id] = mount
+		return mount.path, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	var absLowers []string
+	for _, l := range strings.Split(string(lowers), ":") {
+		absLowers = append(absLowers, path.Join(d.home, l))
+	}
+	mergedDir := path.Join(dir, "merged")
+
+	opts := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", strings.Join(absLowers, ":"), path.Join(dir, "diff"), path.Join(dir, "work"))
+	mountData := label.FormatMountLabel(opts, mountLabel)
+	if len(mountData) < syscall.Getpagesize() {
+		if err := syscall.Mount("overlay", mergedDir, "overlay", 0, mountData); err != nil {
+			return "", fmt.Errorf("error creating overlay mount to %s: %v", mergedDir, err)
+		}
+	} else {
+		// paths relative to the driver directory are shorter
+		opts = fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", string(lowers), path.Join(id, "diff"), path.Join(id, "work"))
+		mountData = label.FormatMountLabel(opts, mountLabel)
+		if len(mountData) >= syscall.Getpagesize() {
+			return "", fmt.Errorf("cannot mount layer, mount label too large %d", len(mountData))
+		}
+		if err := mountFrom(d.home, "overlay", path.Join(id, "merged"), "overlay", mountData); err != nil {
+			return "", fmt.Errorf("error creating overlay mount to %s: %v", mergedDir, err)
+		}
+	}
+
+	mount.path = mergedDir
+	mount.mounted = true
+	d.active[id] = mount
+
+	return mount.path, nil
+}
+
+func (d *Driver) Put(id string) error {
+	// Protect the d.active from concurrent access
+	d.Lock()
+	defer d.Unlock()
+
+	mount := d.active[id]
+	if mount == nil {
+		log.Debugf("Put on a non-mounted device %s", id)
+		return nil
+	}
+
+	mount.count--
+	if mount.count > 0 {
+		return nil
+	}
+
+	defer delete(d.active, id)
+	if mount.mounted {
+		err := syscall.Unmount(mount.path, 0)
+		if err != nil {
+			log.Debugf("Failed to unmount %s overlay: %v", id, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (d *Driver) Exists(id string) bool {
+	_, err := os.Stat(d.dir(id))
+	return err == nil
+}
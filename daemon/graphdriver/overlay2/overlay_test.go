@@ -0,0 +1,94 @@
+// +build linux
+
+package overlay2
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/docker/docker/daemon/graphdriver/graphtest"
+	"github.com/docker/docker/pkg/reexec"
+)
+
+func init() {
+	reexec.Init()
+}
+
+// This avoids creating a new driver for each test if all tests are run
+// Make sure to put new tests between TestOverlaySetup and TestOverlayTeardown
+func TestOverlaySetup(t *testing.T) {
+	graphtest.GetDriver(t, "overlay2")
+}
+
+func TestOverlayCreateEmpty(t *testing.T) {
+	graphtest.DriverTestCreateEmpty(t, "overlay2")
+}
+
+func TestOverlayCreateBase(t *testing.T) {
+	graphtest.DriverTestCreateBase(t, "overlay2")
+}
+
+func TestOverlayCreateSnap(t *testing.T) {
+	graphtest.DriverTestCreateSnap(t, "overlay2")
+}
+
+// A chain deep enough for its absolute lower dirs to exceed a page is
+// mounted with paths relative to the driver directory.
+func TestOverlayDeepChain(t *testing.T) {
+	driver := graphtest.GetDriver(t, "overlay2")
+	defer graphtest.PutDriver(t)
+
+	parent := ""
+	for i := 0; i <= maxDepth; i++ {
+		id := fmt.Sprintf("deep%d", i)
+		if err := driver.Create(id, parent, nil); err != nil {
+			t.Fatal(err)
+		}
+		defer driver.Remove(id)
+
+		dir, err := driver.Get(id, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path.Join(dir, id), []byte(id), 0644); err != nil {
+			driver.Put(id)
+			t.Fatal(err)
+		}
+		if i > 0 {
+			// hide a file of the parent
+			if err := os.Remove(path.Join(dir, parent)); err != nil {
+				driver.Put(id)
+				t.Fatal(err)
+			}
+		}
+		if err := driver.Put(id); err != nil {
+			t.Fatal(err)
+		}
+		parent = id
+	}
+
+	dir, err := driver.Get(parent, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Put(parent)
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != parent {
+		t.Fatalf("Expected only %s in the top layer, got %v", parent, files)
+	}
+
+	if err := driver.Create("toodeep", parent, nil); err == nil {
+		t.Fatalf("Expected an error for a chain deeper than %d layers", maxDepth)
+	}
+}
+
+func TestOverlayTeardown(t *testing.T) {
+	graphtest.PutDriver(t)
+}
@@ -0,0 +1,18 @@
+// +build linux
+
+package overlay2
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"io"
+)
+
+// generateID creates a short name for a layer, idLength characters long
+func generateID() string {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic(err) // This shouldn't happen
+	}
+	return base32.StdEncoding.EncodeToString(b)[:idLength]
+}
@@ -0,0 +1,33 @@
+package graphdriver
+
+import (
+	"io"
+	"path"
+
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/plugins"
+)
+
+type pluginClient interface {
+	// Call calls the specified method with the specified arguments for the plugin.
+	Call(string, interface{}, interface{}) error
+	// Stream calls the specified method with the specified arguments for the plugin and returns the response IO stream
+	Stream(string, interface{}) (io.ReadCloser, error)
+	// SendFile calls the specified method, and passes through the IO stream
+	SendFile(string, io.Reader, interface{}) error
+}
+
+// lookupPlugin returns the driver of the graphdriver plugin name, if
+// there is one on the host.
+func lookupPlugin(name, home string, opts []string, uidMaps, gidMaps []idtools.IDMap) (Driver, error) {
+	pl, err := plugins.Get(name, "GraphDriver")
+	if err != nil {
+		return nil, err
+	}
+	return newPluginDriver(name, home, opts, uidMaps, gidMaps, pl.Client)
+}
+
+func newPluginDriver(name, home string, opts []string, uidMaps, gidMaps []idtools.IDMap, c pluginClient) (Driver, error) {
+	proxy := &graphDriverProxy{name, c}
+	return proxy, proxy.Init(path.Join(home, name), opts, uidMaps, gidMaps)
+}
@@ -0,0 +1,236 @@
+package graphdriver_test
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/daemon/graphdriver/graphtest"
+	"github.com/docker/docker/daemon/graphdriver/vfs"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/plugins"
+	"github.com/docker/docker/pkg/reexec"
+)
+
+const pluginName = "vfs-plugin"
+
+var (
+	pluginDir      string
+	pluginListener net.Listener
+)
+
+func init() {
+	reexec.Init()
+}
+
+type pluginRequest struct {
+	ID         string
+	Parent     string
+	MountLabel string
+	StorageOpt map[string]string
+}
+
+type pluginInitRequest struct {
+	Home    string
+	Opts    []string
+	UIDMaps []idtools.IDMap
+	GIDMaps []idtools.IDMap
+}
+
+func respond(w http.ResponseWriter, ret map[string]interface{}, err error) {
+	w.Header().Set("Content-Type", "application/vnd.docker.plugins.v1+json")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		ret = map[string]interface{}{"Err": err.Error()}
+	}
+	json.NewEncoder(w).Encode(ret)
+}
+
+// serveVfsPlugin serves a vfs driver as a graphdriver plugin on l.
+func serveVfsPlugin(l net.Listener) {
+	var driver graphdriver.Driver
+
+	mux := http.NewServeMux()
+	handle := func(method string, h func(req *pluginRequest) (map[string]interface{}, error)) {
+		mux.HandleFunc("/GraphDriver."+method, func(w http.ResponseWriter, r *http.Request) {
+			var req pluginRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				respond(w, nil, err)
+				return
+			}
+			ret, err := h(&req)
+			respond(w, ret, err)
+		})
+	}
+
+	mux.HandleFunc("/Plugin.Activate", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, map[string]interface{}{"Implements": []string{"GraphDriver"}}, nil)
+	})
+	mux.HandleFunc("/GraphDriver.Init", func(w http.ResponseWriter, r *http.Request) {
+		var req pluginInitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respond(w, nil, err)
+			return
+		}
+		var err error
+		driver, err = vfs.Init(req.Home, req.Opts, req.UIDMaps, req.GIDMaps)
+		respond(w, nil, err)
+	})
+	handle("Create", func(req *pluginRequest) (map[string]interface{}, error) {
+		return nil, driver.Create(req.ID, req.Parent, req.StorageOpt)
+	})
+	handle("Remove", func(req *pluginRequest) (map[string]interface{}, error) {
+		return nil, driver.Remove(req.ID)
+	})
+	handle("Get", func(req *pluginRequest) (map[string]interface{}, error) {
+		dir, err := driver.Get(req.ID, req.MountLabel)
+		return map[string]interface{}{"Dir": dir}, err
+	})
+	handle("Put", func(req *pluginRequest) (map[string]interface{}, error) {
+		return nil, driver.Put(req.ID)
+	})
+	handle("Exists", func(req *pluginRequest) (map[string]interface{}, error) {
+		return map[string]interface{}{"Exists": driver.Exists(req.ID)}, nil
+	})
+	handle("Status", func(req *pluginRequest) (map[string]interface{}, error) {
+		return map[string]interface{}{"Status": driver.Status()}, nil
+	})
+	handle("Cleanup", func(req *pluginRequest) (map[string]interface{}, error) {
+		return nil, driver.Cleanup()
+	})
+	handle("Changes", func(req *pluginRequest) (map[string]interface{}, error) {
+		changes, err := driver.Changes(req.ID, req.Parent)
+		return map[string]interface{}{"Changes": changes}, err
+	})
+	handle("DiffSize", func(req *pluginRequest) (map[string]interface{}, error) {
+		size, err := driver.DiffSize(req.ID, req.Parent)
+		return map[string]interface{}{"Size": size}, err
+	})
+	mux.HandleFunc("/GraphDriver.Diff", func(w http.ResponseWriter, r *http.Request) {
+		var req pluginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respond(w, nil, err)
+			return
+		}
+		diff, err := driver.Diff(req.ID, req.Parent)
+		if err != nil {
+			respond(w, nil, err)
+			return
+		}
+		defer diff.Close()
+		w.Header().Set("Content-Type", "application/x-tar")
+		io.Copy(w, diff)
+	})
+	mux.HandleFunc("/GraphDriver.ApplyDiff", func(w http.ResponseWriter, r *http.Request) {
+		size, err := driver.ApplyDiff(r.URL.Query().Get("id"), r.URL.Query().Get("parent"), r.Body)
+		respond(w, map[string]interface{}{"Size": size}, err)
+	})
+
+	http.Serve(l, mux)
+}
+
+// This avoids creating a new driver for each test if all tests are run
+// Make sure to put new tests between TestPluginSetup and TestPluginTeardown
+func TestPluginSetup(t *testing.T) {
+	var err error
+	pluginDir, err = ioutil.TempDir("", "docker-graphdriver-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugins.SocketsPath = pluginDir
+	pluginListener, err = net.Listen("unix", path.Join(pluginDir, pluginName+".sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go serveVfsPlugin(pluginListener)
+
+	graphtest.GetDriver(t, pluginName)
+}
+
+func TestPluginCreateEmpty(t *testing.T) {
+	graphtest.DriverTestCreateEmpty(t, pluginName)
+}
+
+func TestPluginCreateBase(t *testing.T) {
+	graphtest.DriverTestCreateBase(t, pluginName)
+}
+
+func TestPluginCreateSnap(t *testing.T) {
+	graphtest.DriverTestCreateSnap(t, pluginName)
+}
+
+func TestPluginDiffApplyDiff(t *testing.T) {
+	driver := graphtest.GetDriver(t, pluginName)
+	defer graphtest.PutDriver(t)
+
+	if err := driver.Create("Diff", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := driver.Get("Diff", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "file"), []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	driver.Put("Diff")
+
+	diff, err := driver.Diff("Diff", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer diff.Close()
+
+	if err := driver.Create("ApplyDiff", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := driver.ApplyDiff("ApplyDiff", "", diff); err != nil {
+		t.Fatal(err)
+	}
+	dir, err = driver.Get("ApplyDiff", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Put("ApplyDiff")
+	content, err := ioutil.ReadFile(path.Join(dir, "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "content" {
+		t.Fatalf("Expected the diff to be applied, got %q", content)
+	}
+
+	changes, err := driver.Changes("ApplyDiff", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, c := range changes {
+		if c.Path == "/file" && c.Kind == archive.ChangeAdd {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("Expected /file to be added, got %v", changes)
+	}
+
+	if err := driver.Remove("Diff"); err != nil {
+		t.Fatal(err)
+	}
+	if driver.Exists("Diff") {
+		t.Fatal("Expected the layer to be removed")
+	}
+}
+
+func TestPluginTeardown(t *testing.T) {
+	graphtest.PutDriver(t)
+	pluginListener.Close()
+	os.RemoveAll(pluginDir)
+}
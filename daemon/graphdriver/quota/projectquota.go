@@ -0,0 +1,272 @@
+// +build linux
+
+// Package quota limits the size of directories on XFS filesystems mounted
+// with project quotas (the "pquota" or "prjquota" mount option): every
+// directory is made a project of its own, with a block limit. The drivers
+// storing their layers as plain directories, vfs, overlay and overlay2, use
+// it to limit the size of the layers when their home is on such a
+// filesystem.
+//
+// The project ids of the directories of a driver are above the project id
+// of the driver home directory, so that the home directory can be put in a
+// project of its own with ids to spare below it.
+package quota
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// quotactl commands, from linux/dqblk_xfs.h and linux/quota.h
+	qXGetQuota = 0x5803 // Q_XGETQUOTA
+	qXSetQLim  = 0x5804 // Q_XSETQLIM
+	prjQuota   = 2      // PRJQUOTA
+
+	fsDquotVersion = 1 // FS_DQUOT_VERSION
+	fsProjQuota    = 2 // FS_PROJ_QUOTA
+	fsDqBSoft      = 4 // FS_DQ_BSOFT
+	fsDqBHard      = 8 // FS_DQ_BHARD
+
+	// ioctls on the extended attributes of inodes, from linux/fs.h
+	fsIocFsGetXattr    = 0x801c581f // FS_IOC_FSGETXATTR
+	fsIocFsSetXattr    = 0x401c5820 // FS_IOC_FSSETXATTR
+	fsXflagProjInherit = 0x200      // FS_XFLAG_PROJINHERIT
+
+	// quota limits are counted in basic blocks of 512 bytes
+	basicBlockSize = 512
+)
+
+// fsDiskQuota is struct fs_disk_quota of linux/dqblk_xfs.h
+type fsDiskQuota struct {
+	version      int8
+	flags        int8
+	fieldmask    uint16
+	id           uint32
+	blkHardlimit uint64
+	blkSoftlimit uint64
+	inoHardlimit uint64
+	inoSoftlimit uint64
+	bcount       uint64
+	icount       uint64
+	itimer       int32
+	btimer       int32
+	iwarns       uint16
+	bwarns       uint16
+	padding2     int32
+	rtbHardlimit uint64
+	rtbSoftlimit uint64
+	rtbcount     uint64
+	rtbtimer     int32
+	rtbwarns     uint16
+	padding3     int16
+	padding4     [8]byte
+}
+
+// fsXattr is struct fsxattr of linux/fs.h
+type fsXattr struct {
+	xflags     uint32
+	extsize    uint32
+	nextents   uint32
+	projid     uint32
+	cowextsize uint32
+	pad        [8]byte
+}
+
+// Control sets the size limits of the directories of a driver.
+type Control struct {
+	sync.Mutex        // protects nextProjectID and quotas
+	backingFsBlockDev string
+	nextProjectID     uint32
+	quotas            map[string]uint32
+}
+
+// NewControl returns a Control for the directories under basePath, or an
+// error if the filesystem of basePath does not enforce project quotas.
+func NewControl(basePath string) (*Control, error) {
+	minProjectID, err := getProjectID(basePath)
+	if err != nil {
+		return nil, err
+	}
+	minProjectID++
+
+	// quotactl needs the block device of the filesystem
+	backingFsBlockDev, err := makeBackingFsDev(basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// setting a limit fails unless project quotas are enforced
+	if err := setProjectQuota(backingFsBlockDev, minProjectID, 0); err != nil {
+		return nil, err
+	}
+
+	q := &Control{
+		backingFsBlockDev: backingFsBlockDev,
+		nextProjectID:     minProjectID + 1,
+		quotas:            make(map[string]uint32),
+	}
+	if err := q.findNextProjectID(basePath); err != nil {
+		return nil, err
+	}
+
+	log.Debugf("NewControl(%s): nextProjectID = %d", basePath, q.nextProjectID)
+	return q, nil
+}
+
+// SetQuota limits the size of the directory at targetPath to size bytes.
+// The directory gets a project id of its own the first time.
+func (q *Control) SetQuota(targetPath string, size uint64) error {
+	q.Lock()
+	defer q.Unlock()
+	projectID, ok := q.quotas[targetPath]
+	if !ok {
+		projectID = q.nextProjectID
+		if err := setProjectID(targetPath, projectID); err != nil {
+			return err
+		}
+		q.quotas[targetPath] = projectID
+		q.nextProjectID++
+	}
+
+	log.Debugf("SetQuota(%s, %d): projectID=%d", targetPath, size, projectID)
+	return setProjectQuota(q.backingFsBlockDev, projectID, size)
+}
+
+// GetQuota returns the size limit of the directory at targetPath.
+func (q *Control) GetQuota(targetPath string) (uint64, error) {
+	q.Lock()
+	defer q.Unlock()
+	projectID, ok := q.quotas[targetPath]
+	if !ok {
+		return 0, fmt.Errorf("quota not found for path: %s", targetPath)
+	}
+
+	d := fsDiskQuota{
+		version: fsDquotVersion,
+		flags:   fsProjQuota,
+		id:      projectID,
+	}
+	if err := quotactl(qXGetQuota, q.backingFsBlockDev, projectID, unsafe.Pointer(&d)); err != nil {
+		return 0, fmt.Errorf("Failed to get quota limit for projid %d on %s: %v", projectID, q.backingFsBlockDev, err)
+	}
+	return d.blkHardlimit * basicBlockSize, nil
+}
+
+func setProjectQuota(backingFsBlockDev string, projectID uint32, size uint64) error {
+	d := fsDiskQuota{
+		version:      fsDquotVersion,
+		flags:        fsProjQuota,
+		fieldmask:    fsDqBHard | fsDqBSoft,
+		id:           projectID,
+		blkHardlimit: size / basicBlockSize,
+		blkSoftlimit: size / basicBlockSize,
+	}
+	if err := quotactl(qXSetQLim, backingFsBlockDev, projectID, unsafe.Pointer(&d)); err != nil {
+		return fmt.Errorf("Failed to set quota limit for projid %d on %s: %v", projectID, backingFsBlockDev, err)
+	}
+	return nil
+}
+
+func quotactl(cmd int, special string, id uint32, addr unsafe.Pointer) error {
+	p, err := syscall.BytePtrFromString(special)
+	if err != nil {
+		return err
+	}
+	// QCMD(cmd, PRJQUOTA)
+	qcmd := uintptr(cmd<<8 | prjQuota&0xff)
+	if _, _, errno := syscall.Syscall6(syscall.SYS_QUOTACTL, qcmd, uintptr(unsafe.Pointer(p)), uintptr(id), uintptr(addr), 0, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func getFsXattr(targetPath string) (*fsXattr, *os.File, error) {
+	dir, err := os.Open(targetPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fsx fsXattr
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dir.Fd(), fsIocFsGetXattr, uintptr(unsafe.Pointer(&fsx))); errno != 0 {
+		dir.Close()
+		return nil, nil, fmt.Errorf("Failed to get projid for %s: %v", targetPath, errno)
+	}
+	return &fsx, dir, nil
+}
+
+func getProjectID(targetPath string) (uint32, error) {
+	fsx, dir, err := getFsXattr(targetPath)
+	if err != nil {
+		return 0, err
+	}
+	dir.Close()
+	return fsx.projid, nil
+}
+
+// setProjectID puts the directory in the project, along with everything
+// created in it later
+func setProjectID(targetPath string, projectID uint32) error {
+	fsx, dir, err := getFsXattr(targetPath)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	fsx.projid = projectID
+	fsx.xflags |= fsXflagProjInherit
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dir.Fd(), fsIocFsSetXattr, uintptr(unsafe.Pointer(fsx))); errno != 0 {
+		return fmt.Errorf("Failed to set projid for %s: %v", targetPath, errno)
+	}
+	return nil
+}
+
+// findNextProjectID moves nextProjectID past the project ids already used
+// by the directories under basePath
+func (q *Control) findNextProjectID(basePath string) error {
+	files, err := ioutil.ReadDir(basePath)
+	if err != nil {
+		return fmt.Errorf("read directory failed: %s", basePath)
+	}
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+		p := path.Join(basePath, file.Name())
+		projid, err := getProjectID(p)
+		if err != nil {
+			return err
+		}
+		if projid > 0 {
+			q.quotas[p] = projid
+		}
+		if q.nextProjectID <= projid {
+			q.nextProjectID = projid + 1
+		}
+	}
+	return nil
+}
+
+// makeBackingFsDev creates a block device node for the filesystem of home
+func makeBackingFsDev(home string) (string, error) {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(home, &stat); err != nil {
+		return "", err
+	}
+
+	backingFsBlockDev := path.Join(home, "backingFsBlockDev")
+	// re-create it, the filesystem may have been moved to another device
+	if err := os.Remove(backingFsBlockDev); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if err := syscall.Mknod(backingFsBlockDev, syscall.S_IFBLK|0600, int(stat.Dev)); err != nil {
+		return "", fmt.Errorf("Failed to mknod %s: %v", backingFsBlockDev, err)
+	}
+	return backingFsBlockDev, nil
+}
@@ -0,0 +1,18 @@
+// +build linux
+
+package quota
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// The structures are passed to the kernel as is
+func TestStructSizes(t *testing.T) {
+	if size := unsafe.Sizeof(fsDiskQuota{}); size != 112 {
+		t.Fatalf("Expected struct fs_disk_quota to be 112 bytes, got %d", size)
+	}
+	if size := unsafe.Sizeof(fsXattr{}); size != 28 {
+		t.Fatalf("Expected struct fsxattr to be 28 bytes, got %d", size)
+	}
+}
@@ -0,0 +1,25 @@
+// +build !linux
+
+package quota
+
+import "errors"
+
+var errNotSupported = errors.New("project quotas are only supported on Linux")
+
+// Control sets the size limits of the directories of a driver.
+type Control struct{}
+
+// NewControl returns an error, project quotas are not supported.
+func NewControl(basePath string) (*Control, error) {
+	return nil, errNotSupported
+}
+
+// SetQuota returns an error, project quotas are not supported.
+func (q *Control) SetQuota(targetPath string, size uint64) error {
+	return errNotSupported
+}
+
+// GetQuota returns an error, project quotas are not supported.
+func (q *Control) GetQuota(targetPath string) (uint64, error) {
+	return 0, errNotSupported
+}
@@ -0,0 +1,16 @@
+package vfs
+
+// copyMode selects how dirCopy copies the content of regular files. Each
+// mode falls back to the following ones when the filesystem doesn't
+// support it.
+type copyMode int
+
+const (
+	// copyAuto clones the files with the FICLONE ioctl, on btrfs and on
+	// xfs with reflinks, so that they share their extents
+	copyAuto copyMode = iota
+	// copyFileRange copies the files in the kernel with copy_file_range
+	copyFileRange
+	// copySendfile copies the files in the kernel with sendfile
+	copySendfile
+)
@@ -0,0 +1,212 @@
+package vfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/docker/docker/pkg/system"
+)
+
+// ficlone is the FICLONE ioctl, _IOW(0x94, 9, int)
+const ficlone = 0x40049409
+
+// fileCopier copies the content of regular files, with the fastest
+// strategy its copy mode allows that the filesystem supports. Strategies
+// failing on a file aren't tried again on the following ones.
+type fileCopier struct {
+	clone     bool
+	fileRange bool
+}
+
+func (c *fileCopier) copyRegular(srcPath, dstPath string, mode os.FileMode) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	if c.clone {
+		// the clone shares the extents of the file until either changes
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dst.Fd(), ficlone, src.Fd()); errno == 0 {
+			return nil
+		}
+		c.clone = false
+	}
+
+	if c.fileRange {
+		err := copyWithFileRange(dst, src)
+		if err == nil {
+			return nil
+		}
+		if err != syscall.ENOSYS && err != syscall.EXDEV && err != syscall.EINVAL && err != syscall.EOPNOTSUPP {
+			return err
+		}
+		c.fileRange = false
+		// start over, the call may have copied part of the file
+		if _, err := src.Seek(0, 0); err != nil {
+			return err
+		}
+		if err := dst.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := dst.Seek(0, 0); err != nil {
+			return err
+		}
+	}
+
+	return copyWithSendfile(dst, src)
+}
+
+// copyWithFileRange copies src to dst in the kernel, which copies the extents
+// without reading them on the filesystems supporting it, such as nfs.
+func copyWithFileRange(dst, src *os.File) error {
+	if sysCopyFileRange == 0 {
+		return syscall.ENOSYS
+	}
+	for {
+		n, _, errno := syscall.Syscall6(sysCopyFileRange, src.Fd(), 0, dst.Fd(), 0, 1<<30, 0)
+		if errno != 0 {
+			return errno
+		}
+		if n == 0 {
+			return nil
+		}
+	}
+}
+
+func copyWithSendfile(dst, src *os.File) error {
+	for {
+		n, err := syscall.Sendfile(int(dst.Fd()), int(src.Fd()), nil, 1<<30)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+	}
+}
+
+// copyXattrs copies the xattrs of srcPath, such as file capabilities and
+// selinux labels, to dstPath.
+func copyXattrs(srcPath, dstPath string) error {
+	attrs, err := system.Llistxattr(srcPath)
+	if err != nil {
+		if err == syscall.EOPNOTSUPP {
+			return nil
+		}
+		return err
+	}
+	for _, attr := range attrs {
+		data, err := system.Lgetxattr(srcPath, attr)
+		if err != nil {
+			return err
+		}
+		if err := system.Lsetxattr(dstPath, attr, data, 0); err != nil {
+			return fmt.Errorf("Copying xattr %s of %s: %v", attr, srcPath, err)
+		}
+	}
+	return nil
+}
+
+// dirCopy copies the tree of srcDir into dstDir, which may exist, keeping
+// the owners, permissions, times, xattrs and hardlinks of the files.
+func dirCopy(srcDir, dstDir string, mode copyMode) error {
+	c := &fileCopier{
+		clone:     mode == copyAuto,
+		fileRange: mode == copyAuto || mode == copyFileRange,
+	}
+	// files with several links, by inode, to link their other names to
+	// the first copy
+	copiedInodes := make(map[uint64]string)
+	// the times of the directories are set once their content is copied
+	type dirTimes struct {
+		path  string
+		times []syscall.Timespec
+	}
+	var dirs []dirTimes
+
+	err := filepath.Walk(srcDir, func(srcPath string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(srcDir, srcPath)
+		if err != nil {
+			return err
+		}
+		dstPath := filepath.Join(dstDir, relPath)
+
+		stat, ok := f.Sys().(*syscall.Stat_t)
+		if !ok {
+			return fmt.Errorf("Unable to get raw syscall.Stat_t data for %s", srcPath)
+		}
+
+		switch f.Mode() & os.ModeType {
+		case 0:
+			if stat.Nlink > 1 {
+				if linked, ok := copiedInodes[stat.Ino]; ok {
+					// the metadata is the one of the first copy
+					return os.Link(linked, dstPath)
+				}
+				copiedInodes[stat.Ino] = dstPath
+			}
+			if err := c.copyRegular(srcPath, dstPath, f.Mode()); err != nil {
+				return err
+			}
+		case os.ModeDir:
+			if err := os.Mkdir(dstPath, f.Mode()); err != nil && !os.IsExist(err) {
+				return err
+			}
+			dirs = append(dirs, dirTimes{dstPath, []syscall.Timespec{stat.Atim, stat.Mtim}})
+		case os.ModeSymlink:
+			link, err := os.Readlink(srcPath)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, dstPath); err != nil {
+				return err
+			}
+		case os.ModeNamedPipe, os.ModeSocket, os.ModeDevice, os.ModeDevice | os.ModeCharDevice:
+			if err := system.Mknod(dstPath, stat.Mode, int(stat.Rdev)); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("Unknown file type of %s", srcPath)
+		}
+
+		if err := os.Lchown(dstPath, int(stat.Uid), int(stat.Gid)); err != nil {
+			return err
+		}
+		if err := copyXattrs(srcPath, dstPath); err != nil {
+			return err
+		}
+		if f.Mode()&os.ModeSymlink == 0 {
+			// after chown, which clears the setuid bits
+			if err := os.Chmod(dstPath, f.Mode()); err != nil {
+				return err
+			}
+		}
+		if f.IsDir() {
+			return nil
+		}
+		return system.LUtimesNano(dstPath, []syscall.Timespec{stat.Atim, stat.Mtim})
+	})
+	if err != nil {
+		return err
+	}
+
+	// deepest first, setting the times of a directory doesn't change
+	// the times of its parent
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := system.LUtimesNano(dirs[i].path, dirs[i].times); err != nil {
+			return err
+		}
+	}
+	return nil
+}
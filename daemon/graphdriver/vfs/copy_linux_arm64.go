@@ -0,0 +1,4 @@
+package vfs
+
+// sysCopyFileRange is the number of the copy_file_range syscall
+const sysCopyFileRange = 285
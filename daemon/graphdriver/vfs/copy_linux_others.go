@@ -0,0 +1,7 @@
+// +build linux,!amd64,!386,!arm,!arm64,!ppc64le,!s390x
+
+package vfs
+
+// sysCopyFileRange is unknown on the other architectures, the files are
+// copied with sendfile
+const sysCopyFileRange = 0
@@ -0,0 +1,11 @@
+// +build !linux
+
+package vfs
+
+import "github.com/docker/docker/pkg/chrootarchive"
+
+// dirCopy copies the tree of srcDir into dstDir as a tar stream, the
+// faster strategies of the copy modes need linux.
+func dirCopy(srcDir, dstDir string, mode copyMode) error {
+	return chrootarchive.CopyWithTar(srcDir, dstDir)
+}
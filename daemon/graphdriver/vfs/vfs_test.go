@@ -1,9 +1,18 @@
 package vfs
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"syscall"
 	"testing"
 
 	"github.com/docker/docker/daemon/graphdriver/graphtest"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/chrootarchive"
+	"github.com/docker/docker/pkg/system"
 
 	"github.com/docker/docker/pkg/reexec"
 )
@@ -33,3 +42,146 @@ func TestVfsCreateSnap(t *testing.T) {
 func TestVfsTeardown(t *testing.T) {
 	graphtest.PutDriver(t)
 }
+
+// createCopySource creates a tree with the kinds of files dirCopy copies,
+// and files of size bytes.
+func createCopySource(t testing.TB, files, size int) string {
+	src, err := ioutil.TempDir("/var/tmp", "docker-vfs-copy-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := bytes.Repeat([]byte{'a'}, size)
+	for i := 0; i < files; i++ {
+		dir := path.Join(src, fmt.Sprintf("dir%d", i%10))
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path.Join(dir, fmt.Sprintf("file%d", i)), content, 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(path.Join(src, "suid"), content, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(path.Join(src, "suid"), 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path.Join(src, "suid"), 0755|os.ModeSetuid); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(path.Join(src, "suid"), path.Join(src, "hardlink")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("suid", path.Join(src, "symlink")); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mkfifo(path.Join(src, "fifo"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+func testDirCopy(t *testing.T, mode copyMode) {
+	src := createCopySource(t, 20, 4096)
+	defer os.RemoveAll(src)
+	// larger than the first buffer Lgetxattr reads the value in
+	xattr := bytes.Repeat([]byte("value"), 200)
+	xattrs := system.Lsetxattr(path.Join(src, "suid"), "user.test", xattr, 0) == nil
+
+	dst, err := ioutil.TempDir("/var/tmp", "docker-vfs-copy-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dst)
+
+	if err := dirCopy(src, dst, mode); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := archive.ChangesDirs(dst, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("Expected the copy to be the same as its source, got changes %v", changes)
+	}
+
+	fi, err := os.Stat(path.Join(dst, "suid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode() != 0755|os.ModeSetuid {
+		t.Fatalf("Expected mode %v, got %v", 0755|os.ModeSetuid, fi.Mode())
+	}
+	link, err := os.Stat(path.Join(dst, "hardlink"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(fi, link) {
+		t.Fatal("Expected the hardlink to be kept")
+	}
+	if xattrs {
+		value, err := system.Lgetxattr(path.Join(dst, "suid"), "user.test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(value, xattr) {
+			t.Fatalf("Expected xattr user.test to be copied, got %q", value)
+		}
+	}
+}
+
+func TestDirCopyAuto(t *testing.T) {
+	testDirCopy(t, copyAuto)
+}
+
+func TestDirCopyFileRange(t *testing.T) {
+	testDirCopy(t, copyFileRange)
+}
+
+func TestDirCopySendfile(t *testing.T) {
+	testDirCopy(t, copySendfile)
+}
+
+func benchmarkCopy(b *testing.B, copy func(src, dst string) error) {
+	src := createCopySource(b, 100, 1<<20)
+	defer os.RemoveAll(src)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		dst, err := ioutil.TempDir("/var/tmp", "docker-vfs-copy-")
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if err := copy(src, dst); err != nil {
+			b.Fatal(err)
+		}
+		b.StopTimer()
+		os.RemoveAll(dst)
+		b.StartTimer()
+	}
+}
+
+func BenchmarkCopyWithTar(b *testing.B) {
+	benchmarkCopy(b, chrootarchive.CopyWithTar)
+}
+
+func BenchmarkCopyAuto(b *testing.B) {
+	benchmarkCopy(b, func(src, dst string) error {
+		return dirCopy(src, dst, copyAuto)
+	})
+}
+
+func BenchmarkCopyFileRange(b *testing.B) {
+	benchmarkCopy(b, func(src, dst string) error {
+		return dirCopy(src, dst, copyFileRange)
+	})
+}
+
+func BenchmarkCopySendfile(b *testing.B) {
+	benchmarkCopy(b, func(src, dst string) error {
+		return dirCopy(src, dst, copySendfile)
+	})
+}
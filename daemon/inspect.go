@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/runconfig"
 )
@@ -41,6 +42,13 @@ func (daemon *Daemon) ContainerInspect(job *engine.Job) engine.Status {
 	out.SetJson("State", container.State)
 	out.Set("Image", container.ImageID)
 	out.SetJson("NetworkSettings", container.NetworkSettings)
+	var shaping *NetworkShaping
+	if container.Running {
+		if shaping, err = container.networkShaping(); err != nil {
+			log.Errorf("%v: Failed to get the network shaping: %s", container.ID, err)
+		}
+	}
+	out.SetJson("NetworkShaping", shaping)
 	out.Set("ResolvConfPath", container.ResolvConfPath)
 	out.Set("HostnamePath", container.HostnamePath)
 	out.Set("HostsPath", container.HostsPath)
@@ -54,6 +62,7 @@ func (daemon *Daemon) ContainerInspect(job *engine.Job) engine.Status {
 	out.SetJson("Volumes", container.Volumes)
 	out.SetJson("VolumesRW", container.VolumesRW)
 	out.SetJson("AppArmorProfile", container.AppArmorProfile)
+	out.Set("SeccompProfile", container.GetSeccompProfile())
 
 	out.SetList("ExecIDs", container.GetExecIDs())
 
@@ -1,8 +1,6 @@
 package daemon
 
 import (
-	"strconv"
-	"strings"
 	"syscall"
 
 	"github.com/docker/docker/engine"
@@ -19,22 +17,14 @@ func (daemon *Daemon) ContainerKill(job *engine.Job) engine.Status {
 	}
 	var (
 		name = job.Args[0]
-		sig  uint64
+		sig  syscall.Signal
 		err  error
 	)
 
 	// If we have a signal, look at it. Otherwise, do nothing
 	if len(job.Args) == 2 && job.Args[1] != "" {
-		// Check if we passed the signal as a number:
-		// The largest legal signal is 31, so let's parse on 5 bits
-		sig, err = strconv.ParseUint(job.Args[1], 10, 5)
-		if err != nil {
-			// The signal is not a number, treat it as a string (either like "KILL" or like "SIGKILL")
-			sig = uint64(signal.SignalMap[strings.TrimPrefix(job.Args[1], "SIG")])
-		}
-
-		if sig == 0 {
-			return job.Errorf("Invalid signal: %s", job.Args[1])
+		if sig, err = signal.ParseSignal(job.Args[1]); err != nil {
+			return job.Error(err)
 		}
 	}
 
@@ -44,7 +34,7 @@ func (daemon *Daemon) ContainerKill(job *engine.Job) engine.Status {
 	}
 
 	// If no signal is passed, or SIGKILL, perform regular Kill (SIGKILL + wait())
-	if sig == 0 || syscall.Signal(sig) == syscall.SIGKILL {
+	if sig == 0 || sig == syscall.SIGKILL {
 		if err := container.Kill(); err != nil {
 			return job.Errorf("Cannot kill container %s: %s", name, err)
 		}
@@ -1,9 +1,11 @@
 package daemon
 
 import (
+	"fmt"
 	"io"
 	"os/exec"
 	"sync"
+	"syscall"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -50,6 +52,10 @@ type containerMonitor struct {
 
 	// lastStartTime is the time which the monitor last exec'd the container's process
 	lastStartTime time.Time
+
+	// setupErr is the error which made the callback kill the process it
+	// was called for
+	setupErr error
 }
 
 // newContainerMonitor returns an initialized containerMonitor for the provided container
@@ -135,7 +141,11 @@ func (m *containerMonitor) Start() error {
 
 		m.lastStartTime = time.Now()
 
-		if exitStatus, err = m.container.daemon.Run(m.container, pipes, m.callback); err != nil {
+		exitStatus, err = m.container.daemon.Run(m.container, pipes, m.callback)
+		if err == nil && m.setupErr != nil {
+			err, m.setupErr = m.setupErr, nil
+		}
+		if err != nil {
 			// if we receive an internal error from the initial start of a container then lets
 			// return it instead of entering the restart loop
 			if m.container.RestartCount == 0 {
@@ -252,6 +262,15 @@ func (m *containerMonitor) callback(processConfig *execdriver.ProcessConfig, pid
 		}
 	}
 
+	// the veth pair of the container is created again by every start, the
+	// process must not run without the shaping of its traffic
+	if err := m.container.setNetworkRate(); err != nil {
+		m.setupErr = fmt.Errorf("Failed to shape the network traffic: %s", err)
+		syscall.Kill(pid, syscall.SIGKILL)
+		return
+	}
+
+	m.container.AppliedSeccompProfile = m.container.command.SeccompApplied
 	m.container.setRunning(pid)
 
 	// signal that the process has started
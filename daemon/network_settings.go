@@ -19,10 +19,20 @@ type NetworkSettings struct {
 	Gateway                string
 	IPv6Gateway            string
 	Bridge                 string
+	HairpinMode            bool
 	PortMapping            map[string]PortMapping // Deprecated
 	Ports                  nat.PortMap
 }
 
+// NetworkShaping is the shaping of the traffic of a running container on
+// the bridge, with the packets it dropped.
+type NetworkShaping struct {
+	IngressRate    int64 // in bits per second, 0 is unlimited
+	EgressRate     int64
+	IngressDropped uint64
+	EgressDropped  uint64
+}
+
 func (settings *NetworkSettings) PortMappingAPI() *engine.Table {
 	var outs = engine.NewTable("", 0)
 	for port, bindings := range settings.Ports {
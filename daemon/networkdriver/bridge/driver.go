@@ -12,11 +12,12 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/daemon/networkdriver/firewall"
 	"github.com/docker/docker/daemon/networkdriver/ipallocator"
 	"github.com/docker/docker/daemon/networkdriver/portmapper"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/nat"
-	"github.com/docker/docker/pkg/iptables"
+	"github.com/docker/docker/pkg/common"
 	"github.com/docker/docker/pkg/networkfs/resolvconf"
 	"github.com/docker/docker/pkg/parsers/kernel"
 	"github.com/docker/libcontainer/netlink"
@@ -32,6 +33,9 @@ type networkInterface struct {
 	IP           net.IP
 	IPv6         net.IP
 	PortMappings []net.Addr // There are mappings to the host interfaces
+	VethHost     string     // The host side of the veth pair
+	IngressRate  int64      // The shaping of the traffic received by the container
+	EgressRate   int64      // The shaping of the traffic sent by the container
 }
 
 type ifaces struct {
@@ -52,6 +56,24 @@ func (i *ifaces) Get(key string) *networkInterface {
 	return res
 }
 
+func (i *ifaces) Delete(key string) {
+	i.Lock()
+	delete(i.c, key)
+	i.Unlock()
+}
+
+// reservation is a static address of a container, held in the ip
+// allocator for as long as the container exists
+type reservation struct {
+	id      string
+	network *net.IPNet
+}
+
+type reservations struct {
+	c map[string]reservation
+	sync.Mutex
+}
+
 var (
 	addrs = []string{
 		// Here we don't follow the convention of using the 1st IP of the range for the gateway.
@@ -75,11 +97,15 @@ var (
 
 	bridgeIface       string
 	bridgeIPv4Network *net.IPNet
+	fixedIPv4Subnet   *net.IPNet
+	hairpinMode       bool
+	fw                *firewall.Firewall
 	bridgeIPv6Addr    net.IP
 	globalIPv6Network *net.IPNet
 
 	defaultBindingIP  = net.ParseIP("0.0.0.0")
 	currentInterfaces = ifaces{c: make(map[string]*networkInterface)}
+	reservedIPs       = reservations{c: make(map[string]reservation)}
 )
 
 func InitDriver(job *engine.Job) engine.Status {
@@ -99,6 +125,11 @@ func InitDriver(job *engine.Job) engine.Status {
 		fixedCIDRv6    = job.Getenv("FixedCIDRv6")
 	)
 
+	// Without the userland proxy, the published ports are reached from
+	// the containers and from the loopback addresses through hairpin NAT
+	hairpinMode = job.EnvExists("EnableUserlandProxy") && !job.GetenvBool("EnableUserlandProxy")
+	portmapper.SetUserlandProxy(!hairpinMode)
+
 	if defaultIP := job.Getenv("DefaultBindingIP"); defaultIP != "" {
 		defaultBindingIP = net.ParseIP(defaultIP)
 	}
@@ -110,7 +141,12 @@ func InitDriver(job *engine.Job) engine.Status {
 		bridgeIface = DefaultNetworkBridge
 	}
 
-	addrv4, addrsv6, err := networkdriver.GetIfaceAddr(bridgeIface)
+	var err error
+	if fw, err = firewall.New(job.Getenv("FirewallBackend"), bridgeIface, hairpinMode); err != nil {
+		return job.Error(err)
+	}
+
+	addrv4, addrsv6, err = networkdriver.GetIfaceAddr(bridgeIface)
 
 	if err != nil {
 		// No Bridge existent, create one
@@ -195,12 +231,20 @@ func InitDriver(job *engine.Job) engine.Status {
 		bridgeIPv6Addr = networkv6.IP
 	}
 
-	// Configure iptables for link support
+	// Configure the firewall for link support
 	if enableIPTables {
-		if err := setupIPTables(addrv4, icc, ipMasq); err != nil {
+		if err := fw.Setup(networkv4, icc, ipMasq); err != nil {
 			return job.Error(err)
 		}
+	}
 
+	if hairpinMode {
+		// Let the traffic to the loopback addresses be routed to the
+		// containers once DNATed
+		procFile := "/proc/sys/net/ipv4/conf/" + bridgeIface + "/route_localnet"
+		if err := ioutil.WriteFile(procFile, []byte{'1', '\n'}, 0644); err != nil {
+			job.Logf("WARNING: unable to enable route_localnet on %s: %s\n", bridgeIface, err)
+		}
 	}
 
 	if ipForward {
@@ -220,21 +264,27 @@ func InitDriver(job *engine.Job) engine.Status {
 		}
 	}
 
-	// We can always try removing the iptables
-	if err := iptables.RemoveExistingChain("DOCKER", iptables.Nat); err != nil {
+	// We can always try removing the chains
+	if err := fw.RemoveChains(firewall.IPv4); err != nil {
 		return job.Error(err)
 	}
 
 	if enableIPTables {
-		_, err := iptables.NewChain("DOCKER", bridgeIface, iptables.Nat)
-		if err != nil {
+		if err := fw.NewChains(firewall.IPv4); err != nil {
 			return job.Error(err)
 		}
-		chain, err := iptables.NewChain("DOCKER", bridgeIface, iptables.Filter)
-		if err != nil {
-			return job.Error(err)
+		portmapper.SetFirewall(fw)
+
+		if enableIPv6 && fixedCIDRv6 != "" {
+			// The IPv6 published ports are served by the userland proxy
+			// alone when the kernel has no IPv6 NAT
+			fw.RemoveChains(firewall.IPv6)
+			if err := fw.NewChains(firewall.IPv6); err != nil {
+				job.Logf("WARNING: unable to forward the IPv6 published ports with the firewall: %s\n", err)
+			} else {
+				portmapper.SetFirewall6(fw)
+			}
 		}
-		portmapper.SetIptablesChain(chain)
 	}
 
 	bridgeIPv4Network = networkv4
@@ -247,6 +297,7 @@ func InitDriver(job *engine.Job) engine.Status {
 		if err := ipallocator.RegisterSubnet(bridgeIPv4Network, subnet); err != nil {
 			return job.Error(err)
 		}
+		fixedIPv4Subnet = subnet
 	}
 
 	if fixedCIDRv6 != "" {
@@ -264,14 +315,25 @@ func InitDriver(job *engine.Job) engine.Status {
 	// Block BridgeIP in IP allocator
 	ipallocator.RequestIP(bridgeIPv4Network, bridgeIPv4Network.IP)
 
+	// Hold the addresses and ports of the containers of the last run until
+	// they are restored
+	if err := loadAllocations(job.Getenv("StateDir")); err != nil {
+		job.Logf("WARNING: unable to restore the network allocations: %s\n", err)
+	}
+
 	// https://github.com/docker/docker/issues/2768
 	job.Eng.Hack_SetGlobalVar("httpapi.bridgeIP", bridgeIPv4Network.IP)
 
 	for name, f := range map[string]engine.Handler{
-		"allocate_interface": Allocate,
-		"release_interface":  Release,
-		"allocate_port":      AllocatePort,
-		"link":               LinkContainers,
+		"allocate_interface":    Allocate,
+		"release_interface":     Release,
+		"allocate_port":         AllocatePort,
+		"reserve_ip":            ReserveIP,
+		"unreserve_ip":          UnreserveIP,
+		"reconcile_allocations": ReconcileAllocations,
+		"link":                  LinkContainers,
+		"set_interface_rate":    SetInterfaceRate,
+		"interface_shaping":     InterfaceShaping,
 	} {
 		if err := job.Eng.Register(name, f); err != nil {
 			return job.Error(err)
@@ -280,74 +342,6 @@ func InitDriver(job *engine.Job) engine.Status {
 	return engine.StatusOK
 }
 
-func setupIPTables(addr net.Addr, icc, ipmasq bool) error {
-	// Enable NAT
-
-	if ipmasq {
-		natArgs := []string{"POSTROUTING", "-t", "nat", "-s", addr.String(), "!", "-o", bridgeIface, "-j", "MASQUERADE"}
-
-		if !iptables.Exists(natArgs...) {
-			if output, err := iptables.Raw(append([]string{"-I"}, natArgs...)...); err != nil {
-				return fmt.Errorf("Unable to enable network bridge NAT: %s", err)
-			} else if len(output) != 0 {
-				return &iptables.ChainError{Chain: "POSTROUTING", Output: output}
-			}
-		}
-	}
-
-	var (
-		args       = []string{"FORWARD", "-i", bridgeIface, "-o", bridgeIface, "-j"}
-		acceptArgs = append(args, "ACCEPT")
-		dropArgs   = append(args, "DROP")
-	)
-
-	if !icc {
-		iptables.Raw(append([]string{"-D"}, acceptArgs...)...)
-
-		if !iptables.Exists(dropArgs...) {
-			log.Debugf("Disable inter-container communication")
-			if output, err := iptables.Raw(append([]string{"-I"}, dropArgs...)...); err != nil {
-				return fmt.Errorf("Unable to prevent intercontainer communication: %s", err)
-			} else if len(output) != 0 {
-				return fmt.Errorf("Error disabling intercontainer communication: %s", output)
-			}
-		}
-	} else {
-		iptables.Raw(append([]string{"-D"}, dropArgs...)...)
-
-		if !iptables.Exists(acceptArgs...) {
-			log.Debugf("Enable inter-container communication")
-			if output, err := iptables.Raw(append([]string{"-I"}, acceptArgs...)...); err != nil {
-				return fmt.Errorf("Unable to allow intercontainer communication: %s", err)
-			} else if len(output) != 0 {
-				return fmt.Errorf("Error enabling intercontainer communication: %s", output)
-			}
-		}
-	}
-
-	// Accept all non-intercontainer outgoing packets
-	outgoingArgs := []string{"FORWARD", "-i", bridgeIface, "!", "-o", bridgeIface, "-j", "ACCEPT"}
-	if !iptables.Exists(outgoingArgs...) {
-		if output, err := iptables.Raw(append([]string{"-I"}, outgoingArgs...)...); err != nil {
-			return fmt.Errorf("Unable to allow outgoing packets: %s", err)
-		} else if len(output) != 0 {
-			return &iptables.ChainError{Chain: "FORWARD outgoing", Output: output}
-		}
-	}
-
-	// Accept incoming packets for existing connections
-	existingArgs := []string{"FORWARD", "-o", bridgeIface, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"}
-
-	if !iptables.Exists(existingArgs...) {
-		if output, err := iptables.Raw(append([]string{"-I"}, existingArgs...)...); err != nil {
-			return fmt.Errorf("Unable to allow incoming packets: %s", err)
-		} else if len(output) != 0 {
-			return &iptables.ChainError{Chain: "FORWARD incoming", Output: output}
-		}
-	}
-	return nil
-}
-
 // configureBridge attempts to create and configure a network bridge interface named `bridgeIface` on the host
 // If bridgeIP is empty, it will try to find a non-conflicting IP from the Docker-specified private ranges
 // If the bridge `bridgeIface` already exists, it will only perform the IP address association with the existing
@@ -459,31 +453,6 @@ func createBridgeIface(name string) error {
 	return netlink.CreateBridge(name, setBridgeMacAddr)
 }
 
-// Generate a IEEE802 compliant MAC address from the given IP address.
-//
-// The generator is guaranteed to be consistent: the same IP will always yield the same
-// MAC address. This is to avoid ARP cache issues.
-func generateMacAddr(ip net.IP) net.HardwareAddr {
-	hw := make(net.HardwareAddr, 6)
-
-	// The first byte of the MAC address has to comply with these rules:
-	// 1. Unicast: Set the least-significant bit to 0.
-	// 2. Address is locally administered: Set the second-least-significant bit (U/L) to 1.
-	// 3. As "small" as possible: The veth address has to be "smaller" than the bridge address.
-	hw[0] = 0x02
-
-	// The first 24 bits of the MAC represent the Organizationally Unique Identifier (OUI).
-	// Since this address is locally administered, we can do whatever we want as long as
-	// it doesn't conflict with other addresses.
-	hw[1] = 0x42
-
-	// Insert the IP address into the last 32 bits of the MAC address.
-	// This is a simple way to guarantee the address will be consistent and unique.
-	copy(hw[2:], ip.To4())
-
-	return hw
-}
-
 func linkLocalIPv6FromMac(mac string) (string, error) {
 	hx := strings.Replace(mac, ":", "", -1)
 	hw, err := hex.DecodeString(hx)
@@ -508,14 +477,30 @@ func Allocate(job *engine.Job) engine.Status {
 		globalIPv6    net.IP
 	)
 
-	ip, err = ipallocator.RequestIP(bridgeIPv4Network, requestedIP)
+	// Give the container its addresses of the last run back, when they
+	// were restored
+	if prev := releaseRestored(id); prev != nil {
+		if requestedIP == nil {
+			requestedIP = prev.IP
+		}
+		if requestedIPv6 == nil {
+			requestedIPv6 = prev.IPv6
+		}
+	}
+
+	veth, err := vethName()
+	if err != nil {
+		return job.Error(err)
+	}
+
+	ip, err = requestIP(id, bridgeIPv4Network, requestedIP)
 	if err != nil {
 		return job.Error(err)
 	}
 
 	// If no explicit mac address was given, generate a random one.
 	if mac, err = net.ParseMAC(job.Getenv("RequestedMac")); err != nil {
-		mac = generateMacAddr(ip)
+		mac = networkdriver.GenerateMacAddr(ip)
 	}
 
 	if globalIPv6Network != nil {
@@ -528,7 +513,7 @@ func Allocate(job *engine.Job) engine.Status {
 			}
 		}
 
-		globalIPv6, err = ipallocator.RequestIP(globalIPv6Network, requestedIPv6)
+		globalIPv6, err = requestIP(id, globalIPv6Network, requestedIPv6)
 		if err != nil {
 			log.Errorf("Allocator: RequestIP v6: %s", err.Error())
 			return job.Error(err)
@@ -542,6 +527,8 @@ func Allocate(job *engine.Job) engine.Status {
 	out.Set("Gateway", bridgeIPv4Network.IP.String())
 	out.Set("MacAddress", mac.String())
 	out.Set("Bridge", bridgeIface)
+	out.Set("VethHost", veth)
+	out.SetBool("HairpinMode", hairpinMode)
 
 	size, _ := bridgeIPv4Network.Mask.Size()
 	out.SetInt("IPPrefixLen", size)
@@ -563,9 +550,11 @@ func Allocate(job *engine.Job) engine.Status {
 	}
 
 	currentInterfaces.Set(id, &networkInterface{
-		IP:   ip,
-		IPv6: globalIPv6,
+		IP:       ip,
+		IPv6:     globalIPv6,
+		VethHost: veth,
 	})
+	checkpointAllocations()
 
 	out.WriteTo(job.Stdout)
 
@@ -589,22 +578,131 @@ func Release(job *engine.Job) engine.Status {
 		}
 	}
 
-	if err := ipallocator.ReleaseIP(bridgeIPv4Network, containerInterface.IP); err != nil {
+	if err := releaseIP(id, bridgeIPv4Network, containerInterface.IP); err != nil {
 		log.Infof("Unable to release IPv4 %s", err)
 	}
 	if globalIPv6Network != nil {
-		if err := ipallocator.ReleaseIP(globalIPv6Network, containerInterface.IPv6); err != nil {
+		if err := releaseIP(id, globalIPv6Network, containerInterface.IPv6); err != nil {
 			log.Infof("Unable to release IPv6 %s", err)
 		}
 	}
+	currentInterfaces.Delete(id)
+	checkpointAllocations()
 	return engine.StatusOK
 }
 
+// requestIP requests ip for the container id from the allocator, unless
+// it is reserved for the container.
+func requestIP(id string, network *net.IPNet, ip net.IP) (net.IP, error) {
+	if ip != nil {
+		reservedIPs.Lock()
+		r, ok := reservedIPs.c[ip.String()]
+		reservedIPs.Unlock()
+		if ok && r.id == id {
+			return ip, nil
+		}
+	}
+	return ipallocator.RequestIP(network, ip)
+}
+
+// releaseIP releases ip of the container id, unless it is reserved for
+// the container.
+func releaseIP(id string, network *net.IPNet, ip net.IP) error {
+	if ip != nil {
+		reservedIPs.Lock()
+		r, ok := reservedIPs.c[ip.String()]
+		reservedIPs.Unlock()
+		if ok && r.id == id {
+			return nil
+		}
+	}
+	return ipallocator.ReleaseIP(network, ip)
+}
+
+// Reserve the static addresses of a container until it is removed
+func ReserveIP(job *engine.Job) engine.Status {
+	var (
+		id   = job.Args[0]
+		ip   = job.Getenv("IP")
+		ipv6 = job.Getenv("IPv6")
+	)
+
+	if ip != "" {
+		if err := reserveIP(id, bridgeIPv4Network, ip); err != nil {
+			return job.Error(err)
+		}
+	}
+	if ipv6 != "" {
+		if globalIPv6Network == nil {
+			unreserveIPs(id)
+			return job.Errorf("Static IPv6 addresses require the daemon to run with --ipv6 and --fixed-cidr-v6")
+		}
+		if err := reserveIP(id, globalIPv6Network, ipv6); err != nil {
+			unreserveIPs(id)
+			return job.Error(err)
+		}
+	}
+	return engine.StatusOK
+}
+
+// Release the static addresses of a removed container
+func UnreserveIP(job *engine.Job) engine.Status {
+	unreserveIPs(job.Args[0])
+	return engine.StatusOK
+}
+
+func reserveIP(id string, network *net.IPNet, rawIP string) error {
+	ip := net.ParseIP(rawIP)
+	if ip == nil {
+		return fmt.Errorf("Invalid IP address %s", rawIP)
+	}
+
+	reservedIPs.Lock()
+	defer reservedIPs.Unlock()
+	if r, ok := reservedIPs.c[ip.String()]; ok {
+		if r.id == id {
+			return nil
+		}
+		return fmt.Errorf("IP address %s is already reserved by container %s", ip, common.TruncateID(r.id))
+	}
+
+	if _, err := ipallocator.RequestIP(network, ip); err != nil {
+		switch {
+		case err == ipallocator.ErrIPAlreadyAllocated && takeRestoredIP(id, ip):
+			// the address of the last run of the container is kept
+		case err == ipallocator.ErrIPAlreadyAllocated:
+			return fmt.Errorf("IP address %s is already in use", ip)
+		case err == ipallocator.ErrIPOutOfRange:
+			subnet := network
+			if network == bridgeIPv4Network && fixedIPv4Subnet != nil {
+				subnet = fixedIPv4Subnet
+			}
+			return fmt.Errorf("IP address %s is out of the range of the container addresses %s", ip, subnet)
+		default:
+			return err
+		}
+	}
+	reservedIPs.c[ip.String()] = reservation{id: id, network: network}
+	return nil
+}
+
+func unreserveIPs(id string) {
+	reservedIPs.Lock()
+	defer reservedIPs.Unlock()
+	for ip, r := range reservedIPs.c {
+		if r.id != id {
+			continue
+		}
+		if err := ipallocator.ReleaseIP(r.network, net.ParseIP(ip)); err != nil {
+			log.Infof("Unable to release IP %s: %s", ip, err)
+		}
+		delete(reservedIPs.c, ip)
+	}
+}
+
 // Allocate an external port and map it to the interface
 func AllocatePort(job *engine.Job) engine.Status {
 	var (
-		err error
-
 		ip            = defaultBindingIP
 		id            = job.Args[0]
 		hostIP        = job.Getenv("HostIP")
@@ -621,15 +719,62 @@ func AllocatePort(job *engine.Job) engine.Status {
 		}
 	}
 
+	// The ports published on the default ip are published on the IPv6
+	// addresses too, when the container has a global IPv6 address
+	dualStack := hostIP == "" && ip.Equal(net.IPv4zero) && network.IPv6 != nil
+
+	host, err := mapPort(job, network, proto, ip, hostPort, containerPort, dualStack)
+	if err != nil {
+		return job.Error(err)
+	}
+	network.PortMappings = append(network.PortMappings, host)
+
+	out := engine.Env{}
+
+	if dualStack {
+		if host6, err := mapPort(job, network, proto, net.IPv6unspecified, hostPortOf(host), containerPort, false); err != nil {
+			job.Logf("WARNING: unable to publish port %d on IPv6: %s", hostPortOf(host), err)
+		} else {
+			network.PortMappings = append(network.PortMappings, host6)
+			out.Set("HostIPv6", net.IPv6unspecified.String())
+		}
+	}
+
+	switch netAddr := host.(type) {
+	case *net.TCPAddr:
+		out.Set("HostIP", netAddr.IP.String())
+		out.SetInt("HostPort", netAddr.Port)
+	case *net.UDPAddr:
+		out.Set("HostIP", netAddr.IP.String())
+		out.SetInt("HostPort", netAddr.Port)
+	}
+	checkpointAllocations()
+
+	if _, err := out.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+
+	return engine.StatusOK
+}
+
+// mapPort maps hostPort on ip to containerPort, on the address of the
+// container of the same family as ip if any. With ipv4Only, the port is
+// left free on the IPv6 addresses for their own mapping.
+func mapPort(job *engine.Job, network *networkInterface, proto string, ip net.IP, hostPort, containerPort int, ipv4Only bool) (net.Addr, error) {
+	containerIP := network.IP
+	if ip.To4() == nil && network.IPv6 != nil {
+		containerIP = network.IPv6
+	}
+
 	// host ip, proto, and host port
 	var container net.Addr
 	switch proto {
 	case "tcp":
-		container = &net.TCPAddr{IP: network.IP, Port: containerPort}
+		container = &net.TCPAddr{IP: containerIP, Port: containerPort}
 	case "udp":
-		container = &net.UDPAddr{IP: network.IP, Port: containerPort}
+		container = &net.UDPAddr{IP: containerIP, Port: containerPort}
 	default:
-		return job.Errorf("unsupported address type %s", proto)
+		return nil, fmt.Errorf("unsupported address type %s", proto)
 	}
 
 	//
@@ -639,9 +784,16 @@ func AllocatePort(job *engine.Job) engine.Status {
 	// yields.
 	//
 
-	var host net.Addr
+	var (
+		host    net.Addr
+		err     error
+		mapFunc = portmapper.Map
+	)
+	if ipv4Only {
+		mapFunc = portmapper.MapIPv4Only
+	}
 	for i := 0; i < MaxAllocatedPortAttempts; i++ {
-		if host, err = portmapper.Map(container, ip, hostPort); err == nil {
+		if host, err = mapFunc(container, ip, hostPort); err == nil {
 			break
 		}
 		// There is no point in immediately retrying to map an explicitly
@@ -652,33 +804,23 @@ func AllocatePort(job *engine.Job) engine.Status {
 		}
 		job.Logf("Failed to allocate and map port: %s, retry: %d", err, i+1)
 	}
+	return host, err
+}
 
-	if err != nil {
-		return job.Error(err)
-	}
-
-	network.PortMappings = append(network.PortMappings, host)
-
-	out := engine.Env{}
+func hostPortOf(host net.Addr) int {
 	switch netAddr := host.(type) {
 	case *net.TCPAddr:
-		out.Set("HostIP", netAddr.IP.String())
-		out.SetInt("HostPort", netAddr.Port)
+		return netAddr.Port
 	case *net.UDPAddr:
-		out.Set("HostIP", netAddr.IP.String())
-		out.SetInt("HostPort", netAddr.Port)
+		return netAddr.Port
 	}
-	if _, err := out.WriteTo(job.Stdout); err != nil {
-		return job.Error(err)
-	}
-
-	return engine.StatusOK
+	return 0
 }
 
 func LinkContainers(job *engine.Job) engine.Status {
 	var (
 		action       = job.Args[0]
-		nfAction     iptables.Action
+		nfAction     firewall.Action
 		childIP      = job.Getenv("ChildIP")
 		parentIP     = job.Getenv("ParentIP")
 		ignoreErrors = job.GetenvBool("IgnoreErrors")
@@ -687,11 +829,11 @@ func LinkContainers(job *engine.Job) engine.Status {
 
 	switch action {
 	case "-A":
-		nfAction = iptables.Append
+		nfAction = firewall.Append
 	case "-I":
-		nfAction = iptables.Insert
+		nfAction = firewall.Insert
 	case "-D":
-		nfAction = iptables.Delete
+		nfAction = firewall.Delete
 	default:
 		return job.Errorf("Invalid action '%s' specified", action)
 	}
@@ -705,10 +847,9 @@ func LinkContainers(job *engine.Job) engine.Status {
 		return job.Errorf("Child IP '%s' is invalid", childIP)
 	}
 
-	chain := iptables.Chain{Name: "DOCKER", Bridge: bridgeIface}
 	for _, p := range ports {
 		port := nat.Port(p)
-		if err := chain.Link(nfAction, ip1, ip2, port.Int(), port.Proto()); !ignoreErrors && err != nil {
+		if err := fw.Link(nfAction, port.Proto(), ip1, ip2, port.Int()); !ignoreErrors && err != nil {
 			return job.Error(err)
 		}
 	}
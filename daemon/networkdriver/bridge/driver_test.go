@@ -1,10 +1,12 @@
 package bridge
 
 import (
+	"bytes"
 	"net"
 	"strconv"
 	"testing"
 
+	"github.com/docker/docker/daemon/networkdriver/firewall"
 	"github.com/docker/docker/daemon/networkdriver/portmapper"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/pkg/iptables"
@@ -104,20 +106,62 @@ func TestHostnameFormatChecking(t *testing.T) {
 	}
 }
 
-func TestMacAddrGeneration(t *testing.T) {
-	ip := net.ParseIP("192.168.0.1")
-	mac := generateMacAddr(ip).String()
+func TestReserveIP(t *testing.T) {
+	eng := engine.New()
+	eng.Logging = false
+
+	defer func(n *net.IPNet) { bridgeIPv4Network = n }(bridgeIPv4Network)
+	_, bridgeIPv4Network, _ = net.ParseCIDR("192.168.99.0/24")
+
+	reserve := func(id, ip string) engine.Status {
+		job := eng.Job("reserve_ip", id)
+		job.Setenv("IP", ip)
+		return ReserveIP(job)
+	}
+
+	if res := reserve("c1", "192.168.99.10"); res != engine.StatusOK {
+		t.Fatal("Failed to reserve a free IP")
+	}
+	if res := reserve("c1", "192.168.99.10"); res != engine.StatusOK {
+		t.Fatal("Failed to reserve an IP again for the same container")
+	}
+	if res := reserve("c2", "192.168.99.10"); res == engine.StatusOK {
+		t.Fatal("Reserved an IP already reserved by another container")
+	}
+	if res := reserve("c2", "10.0.0.10"); res == engine.StatusOK {
+		t.Fatal("Reserved an IP out of the network")
+	}
 
-	// Should be consistent.
-	if generateMacAddr(ip).String() != mac {
-		t.Fatal("Inconsistent MAC address")
+	// the container gets its reserved IP, and keeps it reserved when
+	// its interface is released
+	job := eng.Job("allocate_interface", "c1")
+	job.Setenv("RequestedIP", "192.168.99.10")
+	out := &bytes.Buffer{}
+	job.Stdout.Add(out)
+	if res := Allocate(job); res != engine.StatusOK {
+		t.Fatal("Failed to allocate the reserved IP")
+	}
+	env := engine.Env{}
+	if err := env.Decode(out); err != nil {
+		t.Fatal(err)
+	}
+	if ip := env.Get("IP"); ip != "192.168.99.10" {
+		t.Fatalf("Expected the reserved IP, got %s", ip)
+	}
+	if res := Release(eng.Job("release_interface", "c1")); res != engine.StatusOK {
+		t.Fatal("Failed to release the interface")
+	}
+	if res := reserve("c2", "192.168.99.10"); res == engine.StatusOK {
+		t.Fatal("Reserved an IP still reserved by another container")
 	}
 
-	// Should be unique.
-	ip2 := net.ParseIP("192.168.0.2")
-	if generateMacAddr(ip2).String() == mac {
-		t.Fatal("Non-unique MAC address")
+	if res := UnreserveIP(eng.Job("unreserve_ip", "c1")); res != engine.StatusOK {
+		t.Fatal("Failed to release the reservations")
+	}
+	if res := reserve("c2", "192.168.99.10"); res != engine.StatusOK {
+		t.Fatal("Failed to reserve a released IP")
 	}
+	UnreserveIP(eng.Job("unreserve_ip", "c2"))
 }
 
 func TestLinkContainers(t *testing.T) {
@@ -144,8 +188,11 @@ func TestLinkContainers(t *testing.T) {
 	job.SetenvList("Ports", []string{"1234"})
 
 	bridgeIface = "lo"
-	_, err := iptables.NewChain("DOCKER", bridgeIface, iptables.Filter)
-	if err != nil {
+	var err error
+	if fw, err = firewall.New("iptables", bridgeIface, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.NewChains(firewall.IPv4); err != nil {
 		t.Fatal(err)
 	}
 
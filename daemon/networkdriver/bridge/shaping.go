@@ -0,0 +1,98 @@
+package bridge
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/tc"
+	"github.com/docker/libcontainer/utils"
+)
+
+// vethName returns a name for the host side of the veth pair of a
+// container, unused on the host and by the other containers.
+func vethName() (string, error) {
+	for i := 0; i < 10; i++ {
+		name, err := utils.GenerateRandomName("veth", 7)
+		if err != nil {
+			return "", err
+		}
+		if _, err := net.InterfaceByName(name); err == nil {
+			continue
+		}
+		if currentInterfaces.hasVeth(name) {
+			continue
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("Unable to find a free name for the veth pair")
+}
+
+func (i *ifaces) hasVeth(name string) bool {
+	i.Lock()
+	defer i.Unlock()
+	for _, iface := range i.c {
+		if iface.VethHost == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Shape the traffic of a container on the host side of its veth pair. The
+// rates are in bits per second, 0 is unlimited.
+func SetInterfaceRate(job *engine.Job) engine.Status {
+	var (
+		id          = job.Args[0]
+		ingressRate = job.GetenvInt64("IngressRate")
+		egressRate  = job.GetenvInt64("EgressRate")
+		iface       = currentInterfaces.Get(id)
+	)
+
+	if iface == nil {
+		return job.Errorf("No network information for %s", id)
+	}
+
+	// The traffic received by the container is sent out of the host side
+	// of the veth pair, and the traffic it sends is received on it.
+	if err := tc.SetRate(iface.VethHost, ingressRate, egressRate); err != nil {
+		return job.Error(err)
+	}
+
+	currentInterfaces.Lock()
+	iface.IngressRate = ingressRate
+	iface.EgressRate = egressRate
+	currentInterfaces.Unlock()
+	return engine.StatusOK
+}
+
+// Return the traffic shaping of a container, and the packets it dropped
+func InterfaceShaping(job *engine.Job) engine.Status {
+	var (
+		id    = job.Args[0]
+		iface = currentInterfaces.Get(id)
+	)
+
+	if iface == nil {
+		return job.Errorf("No network information for %s", id)
+	}
+
+	currentInterfaces.Lock()
+	ingressRate, egressRate := iface.IngressRate, iface.EgressRate
+	currentInterfaces.Unlock()
+
+	out := engine.Env{}
+	out.SetInt64("IngressRate", ingressRate)
+	out.SetInt64("EgressRate", egressRate)
+	if ingressRate > 0 || egressRate > 0 {
+		stats, err := tc.GetStats(iface.VethHost)
+		if err != nil {
+			return job.Error(err)
+		}
+		out.SetInt64("IngressDropped", int64(stats.Dropped))
+		out.SetInt64("EgressDropped", int64(stats.IngressDropped))
+	}
+	out.WriteTo(job.Stdout)
+
+	return engine.StatusOK
+}
@@ -0,0 +1,71 @@
+package bridge
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/engine"
+)
+
+func interfaceShaping(t *testing.T, eng *engine.Engine, id string) engine.Env {
+	job := eng.Job("interface_shaping", id)
+	out := &bytes.Buffer{}
+	job.Stdout.Add(out)
+	if res := InterfaceShaping(job); res != engine.StatusOK {
+		t.Fatalf("Failed to get the shaping of %s", id)
+	}
+	env := engine.Env{}
+	if err := env.Decode(out); err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+func TestInterfaceShaping(t *testing.T) {
+	eng := engine.New()
+	eng.Logging = false
+
+	defer func(n *net.IPNet) { bridgeIPv4Network = n }(bridgeIPv4Network)
+	_, bridgeIPv4Network, _ = net.ParseCIDR("192.168.96.0/24")
+
+	allocateInterface(t, eng, "c1")
+	defer Release(eng.Job("release_interface", "c1"))
+	allocateInterface(t, eng, "c2")
+	defer Release(eng.Job("release_interface", "c2"))
+
+	veth := currentInterfaces.Get("c1").VethHost
+	if !strings.HasPrefix(veth, "veth") || veth == currentInterfaces.Get("c2").VethHost {
+		t.Fatalf("Expected a veth name of its own for each container, got %s", veth)
+	}
+
+	if env := interfaceShaping(t, eng, "c1"); env.GetInt64("IngressRate") != 0 || env.Exists("IngressDropped") {
+		t.Fatalf("Expected no shaping, got %v", env)
+	}
+	if res := SetInterfaceRate(eng.Job("set_interface_rate", "unknown")); res == engine.StatusOK {
+		t.Fatal("Shaped the traffic of a container without network")
+	}
+
+	if os.Getuid() != 0 {
+		t.Skip("Shaping an interface requires root")
+	}
+	// stand in for the veth pair the execdriver creates when the
+	// container starts
+	if out, err := exec.Command("ip", "link", "add", veth, "type", "veth", "peer", "name", veth+"p").CombinedOutput(); err != nil {
+		t.Skipf("Could not create a veth pair: %s, %v", out, err)
+	}
+	defer exec.Command("ip", "link", "del", veth).Run()
+
+	job := eng.Job("set_interface_rate", "c1")
+	job.SetenvInt64("IngressRate", 10000000)
+	if res := SetInterfaceRate(job); res != engine.StatusOK {
+		t.Fatal("Failed to shape the traffic of c1")
+	}
+	env := interfaceShaping(t, eng, "c1")
+	if env.GetInt64("IngressRate") != 10000000 || env.GetInt64("EgressRate") != 0 || !env.Exists("IngressDropped") {
+		t.Fatalf("Expected the ingress rate and drop counters, got %v", env)
+	}
+}
@@ -0,0 +1,235 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/networkdriver/ipallocator"
+	"github.com/docker/docker/daemon/networkdriver/portallocator"
+	"github.com/docker/docker/engine"
+)
+
+const allocationsFile = "allocations.json"
+
+// allocation is the checkpointed network allocation of a container
+type allocation struct {
+	IP    net.IP           `json:",omitempty"`
+	IPv6  net.IP           `json:",omitempty"`
+	Ports []portAllocation `json:",omitempty"`
+}
+
+type portAllocation struct {
+	Proto    string
+	HostIP   net.IP
+	HostPort int
+}
+
+type allocations struct {
+	c map[string]*allocation
+	sync.Mutex
+}
+
+var (
+	// stateDir is where the allocations are checkpointed, no checkpoint
+	// is kept when it is empty
+	stateDir string
+
+	// restoredAllocations are the allocations loaded from the checkpoint,
+	// held in the allocators until their container allocates its
+	// interface again or the allocations are reconciled
+	restoredAllocations = allocations{c: make(map[string]*allocation)}
+)
+
+// loadAllocations holds the checkpointed allocations in dir in the
+// allocators, so that they aren't handed out to other containers before
+// their own container is restored.
+func loadAllocations(dir string) error {
+	stateDir = dir
+	if stateDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(stateDir, 0700); err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(stateDir, allocationsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	saved := make(map[string]*allocation)
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("Error loading the network allocations: %s", err)
+	}
+
+	restoredAllocations.Lock()
+	defer restoredAllocations.Unlock()
+	for id, a := range saved {
+		held := &allocation{}
+		if a.IP != nil {
+			if _, err := ipallocator.RequestIP(bridgeIPv4Network, a.IP); err != nil {
+				log.Infof("Unable to restore IP %s of %s: %s", a.IP, id, err)
+			} else {
+				held.IP = a.IP
+			}
+		}
+		if a.IPv6 != nil && globalIPv6Network != nil {
+			if _, err := ipallocator.RequestIP(globalIPv6Network, a.IPv6); err != nil {
+				log.Infof("Unable to restore IPv6 %s of %s: %s", a.IPv6, id, err)
+			} else {
+				held.IPv6 = a.IPv6
+			}
+		}
+		for _, p := range a.Ports {
+			if _, err := portallocator.RequestPort(p.HostIP, p.Proto, p.HostPort); err != nil {
+				log.Infof("Unable to restore port %s/%d of %s: %s", p.Proto, p.HostPort, id, err)
+				continue
+			}
+			held.Ports = append(held.Ports, p)
+		}
+		restoredAllocations.c[id] = held
+	}
+	return nil
+}
+
+// releaseRestored frees the restored allocation of the container id, and
+// returns it.
+func releaseRestored(id string) *allocation {
+	restoredAllocations.Lock()
+	a := restoredAllocations.c[id]
+	delete(restoredAllocations.c, id)
+	restoredAllocations.Unlock()
+
+	if a != nil {
+		releaseAllocation(id, a)
+	}
+	return a
+}
+
+func releaseAllocation(id string, a *allocation) {
+	if a.IP != nil {
+		if err := releaseIP(id, bridgeIPv4Network, a.IP); err != nil {
+			log.Infof("Unable to release IPv4 %s", err)
+		}
+	}
+	if a.IPv6 != nil && globalIPv6Network != nil {
+		if err := releaseIP(id, globalIPv6Network, a.IPv6); err != nil {
+			log.Infof("Unable to release IPv6 %s", err)
+		}
+	}
+	for _, p := range a.Ports {
+		if err := portallocator.ReleasePort(p.HostIP, p.Proto, p.HostPort); err != nil {
+			log.Infof("Unable to release port %s/%d: %s", p.Proto, p.HostPort, err)
+		}
+	}
+}
+
+// takeRestoredIP removes ip from the restored allocation of the container
+// id, so it can be reserved for the container, and reports whether it was
+// held for it.
+func takeRestoredIP(id string, ip net.IP) bool {
+	restoredAllocations.Lock()
+	defer restoredAllocations.Unlock()
+	a := restoredAllocations.c[id]
+	switch {
+	case a == nil:
+		return false
+	case a.IP.Equal(ip):
+		a.IP = nil
+	case a.IPv6.Equal(ip):
+		a.IPv6 = nil
+	default:
+		return false
+	}
+	return true
+}
+
+// checkpointAllocations saves the allocations of the containers, and the
+// restored allocations still held, to disk.
+func checkpointAllocations() {
+	if stateDir == "" {
+		return
+	}
+
+	saved := make(map[string]*allocation)
+
+	restoredAllocations.Lock()
+	for id, a := range restoredAllocations.c {
+		saved[id] = a
+	}
+	restoredAllocations.Unlock()
+
+	currentInterfaces.Lock()
+	for id, iface := range currentInterfaces.c {
+		a := &allocation{IP: iface.IP, IPv6: iface.IPv6}
+		for _, addr := range iface.PortMappings {
+			switch addr := addr.(type) {
+			case *net.TCPAddr:
+				a.Ports = append(a.Ports, portAllocation{Proto: "tcp", HostIP: addr.IP, HostPort: addr.Port})
+			case *net.UDPAddr:
+				a.Ports = append(a.Ports, portAllocation{Proto: "udp", HostIP: addr.IP, HostPort: addr.Port})
+			}
+		}
+		saved[id] = a
+	}
+	currentInterfaces.Unlock()
+
+	if err := writeAllocations(saved); err != nil {
+		log.Errorf("Unable to checkpoint the network allocations: %s", err)
+	}
+}
+
+func writeAllocations(saved map[string]*allocation) error {
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	tmpFile, err := ioutil.TempFile(stateDir, ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	return os.Rename(tmpFile.Name(), filepath.Join(stateDir, allocationsFile))
+}
+
+// Free the restored allocations of the containers which are not running
+// once the daemon restored its containers
+func ReconcileAllocations(job *engine.Job) engine.Status {
+	running := make(map[string]bool)
+	for _, id := range job.GetenvList("Containers") {
+		running[id] = true
+	}
+
+	restoredAllocations.Lock()
+	stale := make(map[string]*allocation)
+	for id, a := range restoredAllocations.c {
+		if !running[id] {
+			stale[id] = a
+			delete(restoredAllocations.c, id)
+		}
+	}
+	restoredAllocations.Unlock()
+
+	for id, a := range stale {
+		log.Debugf("Releasing the stale network allocation of %s", id)
+		releaseAllocation(id, a)
+	}
+	checkpointAllocations()
+	return engine.StatusOK
+}
@@ -0,0 +1,138 @@
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/daemon/networkdriver/ipallocator"
+	"github.com/docker/docker/daemon/networkdriver/portallocator"
+	"github.com/docker/docker/engine"
+)
+
+func allocateInterface(t *testing.T, eng *engine.Engine, id string) net.IP {
+	job := eng.Job("allocate_interface", id)
+	out := &bytes.Buffer{}
+	job.Stdout.Add(out)
+	if res := Allocate(job); res != engine.StatusOK {
+		t.Fatalf("Failed to allocate the interface of %s", id)
+	}
+	env := engine.Env{}
+	if err := env.Decode(out); err != nil {
+		t.Fatal(err)
+	}
+	return net.ParseIP(env.Get("IP"))
+}
+
+func TestCheckpointAllocations(t *testing.T) {
+	eng := engine.New()
+	eng.Logging = false
+
+	dir, err := ioutil.TempDir("", "docker-bridge-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() { stateDir = "" }()
+
+	defer func(n *net.IPNet) { bridgeIPv4Network = n }(bridgeIPv4Network)
+	_, bridgeIPv4Network, _ = net.ParseCIDR("192.168.98.0/24")
+
+	if err := loadAllocations(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer ReconcileAllocations(eng.Job("reconcile_allocations"))
+
+	ip := allocateInterface(t, eng, "c1")
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, allocationsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := make(map[string]*allocation)
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if a := saved["c1"]; a == nil || !a.IP.Equal(ip) {
+		t.Fatalf("Expected the allocation of c1 to be checkpointed, got %s", data)
+	}
+
+	// forget the in memory state, as a restart of the daemon does
+	currentInterfaces.Delete("c1")
+	ipallocator.ReleaseIP(bridgeIPv4Network, ip)
+
+	if err := loadAllocations(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ipallocator.RequestIP(bridgeIPv4Network, ip); err != ipallocator.ErrIPAlreadyAllocated {
+		t.Fatalf("Expected the restored IP to be held, got %v", err)
+	}
+	if other := allocateInterface(t, eng, "c2"); other.Equal(ip) {
+		t.Fatal("Allocated the restored IP of c1 to c2")
+	}
+	if restored := allocateInterface(t, eng, "c1"); !restored.Equal(ip) {
+		t.Fatalf("Expected c1 to get its IP %s back, got %s", ip, restored)
+	}
+
+	Release(eng.Job("release_interface", "c1"))
+	Release(eng.Job("release_interface", "c2"))
+}
+
+func TestReconcileAllocations(t *testing.T) {
+	eng := engine.New()
+	eng.Logging = false
+
+	dir, err := ioutil.TempDir("", "docker-bridge-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() { stateDir = "" }()
+
+	defer func(n *net.IPNet) { bridgeIPv4Network = n }(bridgeIPv4Network)
+	_, bridgeIPv4Network, _ = net.ParseCIDR("192.168.97.0/24")
+
+	var (
+		hostIP = net.ParseIP("127.0.0.1")
+		port   = findFreePort(t)
+	)
+	saved := map[string]*allocation{
+		"stale": {
+			IP:    net.ParseIP("192.168.97.10"),
+			Ports: []portAllocation{{Proto: "tcp", HostIP: hostIP, HostPort: port}},
+		},
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, allocationsFile), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := loadAllocations(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := portallocator.RequestPort(hostIP, "tcp", port); err == nil {
+		t.Fatal("Expected the restored port to be held")
+	}
+
+	job := eng.Job("reconcile_allocations")
+	job.SetenvList("Containers", []string{"running"})
+	if res := ReconcileAllocations(job); res != engine.StatusOK {
+		t.Fatal("Failed to reconcile the allocations")
+	}
+
+	if _, err := portallocator.RequestPort(hostIP, "tcp", port); err != nil {
+		t.Fatalf("Expected the stale port to be released, got %v", err)
+	}
+	portallocator.ReleasePort(hostIP, "tcp", port)
+	if _, err := ipallocator.RequestIP(bridgeIPv4Network, net.ParseIP("192.168.97.10")); err != nil {
+		t.Fatalf("Expected the stale IP to be released, got %v", err)
+	}
+	ipallocator.ReleaseIP(bridgeIPv4Network, net.ParseIP("192.168.97.10"))
+}
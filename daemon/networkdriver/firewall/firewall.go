@@ -0,0 +1,317 @@
+// Package firewall sets up the firewall rules of the bridge network: the NAT
+// of the traffic of the containers, the forwarding of their published ports
+// and the links between them.
+//
+// The rules are described once, independently of the firewall of the host,
+// and installed by a backend: iptables, which runs a command per rule, or
+// nftables, which keeps the rules in a table of its own updated atomically.
+package firewall
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+type Action string
+type Table string
+
+// Family is the address family of a rule, which iptables and ip6tables
+// keep apart.
+type Family string
+
+const (
+	Append Action = "append"
+	Insert Action = "insert"
+	Delete Action = "delete"
+	Nat    Table  = "nat"
+	Filter Table  = "filter"
+
+	IPv4 Family = "ipv4"
+	IPv6 Family = "ipv6"
+
+	// DockerChain is the chain of the rules of the containers, in the nat
+	// and filter tables
+	DockerChain = "DOCKER"
+)
+
+// Backends are the names of the backends, as given to New.
+var Backends = []string{"iptables", "nftables"}
+
+// Match is a value a rule matches packets on, or doesn't when Not is set.
+// The zero Match matches every packet.
+type Match struct {
+	Value string
+	Not   bool
+}
+
+func Is(value string) Match {
+	return Match{Value: value}
+}
+
+func IsNot(value string) Match {
+	return Match{Value: value, Not: true}
+}
+
+// Rule is a rule of a chain, as iptables describes it. Target is ACCEPT,
+// DROP, MASQUERADE, DNAT or the name of a chain to jump to.
+type Rule struct {
+	Family        Family
+	Table         Table
+	Chain         string
+	Proto         string
+	InIface       Match
+	OutIface      Match
+	Src           Match
+	Dst           Match
+	SrcType       string // address type of the source, such as LOCAL
+	DstType       string
+	SrcPort       int
+	DstPort       int
+	CtState       string // conntrack states, such as RELATED,ESTABLISHED
+	Target        string
+	ToDestination string // address and port of DNAT
+}
+
+// backend installs the rules in the firewall of the host.
+type backend interface {
+	// NewChain creates the chain of the family in table, if missing.
+	NewChain(family Family, table Table, name string) error
+	// RemoveChain flushes then removes the chain of the family in table.
+	RemoveChain(family Family, table Table, name string) error
+	// Exists returns whether the rule is in its chain.
+	Exists(r Rule) bool
+	// Apply appends, inserts or deletes the rules one after the other.
+	Apply(action Action, rules ...Rule) error
+}
+
+// Firewall sets up the rules of a bridge with a backend.
+type Firewall struct {
+	backend backend
+	bridge  string
+	// hairpinMode forwards the traffic coming from the bridge, and from
+	// the loopback addresses, to the published ports too
+	hairpinMode bool
+}
+
+// New returns the firewall of bridge, with the backend name.
+func New(name, bridge string, hairpinMode bool) (*Firewall, error) {
+	var b backend
+	switch name {
+	case "", "iptables":
+		b = &iptablesBackend{}
+	case "nftables":
+		b = newNftablesBackend()
+	default:
+		return nil, fmt.Errorf("Unknown firewall backend %s, use iptables or nftables", name)
+	}
+	return &Firewall{backend: b, bridge: bridge, hairpinMode: hairpinMode}, nil
+}
+
+// Setup sets up the rules of the traffic of the bridge network: the NAT of
+// the outgoing traffic when ipMasq is set, the communication between
+// containers when icc is set, and accepts the traffic to and from the
+// outside. The rules of the other settings are removed.
+func (fw *Firewall) Setup(network *net.IPNet, icc, ipMasq bool) error {
+	var (
+		rules []Rule
+		stale []Rule
+	)
+
+	if ipMasq {
+		subnet := &net.IPNet{IP: network.IP.Mask(network.Mask), Mask: network.Mask}
+		rules = append(rules, Rule{Family: IPv4, Table: Nat, Chain: "POSTROUTING",
+			Src:      Is(subnet.String()),
+			OutIface: IsNot(fw.bridge),
+			Target:   "MASQUERADE"})
+	}
+
+	// The traffic from the loopback addresses to the published ports
+	// must leave with an address the containers can answer to
+	hairpin := Rule{Family: IPv4, Table: Nat, Chain: "POSTROUTING",
+		SrcType:  "LOCAL",
+		OutIface: Is(fw.bridge),
+		Target:   "MASQUERADE"}
+	if fw.hairpinMode {
+		rules = append(rules, hairpin)
+	} else {
+		stale = append(stale, hairpin)
+	}
+
+	var (
+		accept = Rule{Family: IPv4, Table: Filter, Chain: "FORWARD", InIface: Is(fw.bridge), OutIface: Is(fw.bridge), Target: "ACCEPT"}
+		drop   = Rule{Family: IPv4, Table: Filter, Chain: "FORWARD", InIface: Is(fw.bridge), OutIface: Is(fw.bridge), Target: "DROP"}
+	)
+	if icc {
+		rules = append(rules, accept)
+		stale = append(stale, drop)
+	} else {
+		rules = append(rules, drop)
+		stale = append(stale, accept)
+	}
+
+	rules = append(rules,
+		// Accept all non-intercontainer outgoing packets
+		Rule{Family: IPv4, Table: Filter, Chain: "FORWARD", InIface: Is(fw.bridge), OutIface: IsNot(fw.bridge), Target: "ACCEPT"},
+		// Accept incoming packets for existing connections
+		Rule{Family: IPv4, Table: Filter, Chain: "FORWARD", OutIface: Is(fw.bridge), CtState: "RELATED,ESTABLISHED", Target: "ACCEPT"})
+
+	// Ignore errors - the stale rules are usually missing
+	for _, r := range stale {
+		fw.backend.Apply(Delete, r)
+	}
+	if err := fw.insertMissing(rules...); err != nil {
+		return fmt.Errorf("Unable to set up the firewall rules of %s: %s", fw.bridge, err)
+	}
+	return nil
+}
+
+// NewChains creates the DOCKER chains of the family, and the rules sending
+// the traffic to the host and to the bridge to them.
+func (fw *Firewall) NewChains(family Family) error {
+	if err := fw.backend.NewChain(family, Nat, DockerChain); err != nil {
+		return err
+	}
+	for _, r := range fw.natJumps(family) {
+		if fw.backend.Exists(r) {
+			continue
+		}
+		if err := fw.backend.Apply(Append, r); err != nil {
+			return fmt.Errorf("Failed to inject docker in %s chain: %s", r.Chain, err)
+		}
+	}
+
+	if err := fw.backend.NewChain(family, Filter, DockerChain); err != nil {
+		return err
+	}
+	if err := fw.insertMissing(fw.filterJump(family)); err != nil {
+		return fmt.Errorf("Could not create linking rule to %s/%s: %s", Filter, DockerChain, err)
+	}
+	return nil
+}
+
+// RemoveChains removes the DOCKER chain of the nat table of the family,
+// with the rules jumping to it. The chain may not exist, errors are
+// ignored.
+func (fw *Firewall) RemoveChains(family Family) error {
+	jumps := []Rule{
+		{Family: family, Table: Nat, Chain: "PREROUTING", DstType: "LOCAL", Target: DockerChain},
+		{Family: family, Table: Nat, Chain: "OUTPUT", DstType: "LOCAL", Dst: IsNot(loopback(family)), Target: DockerChain},
+		// Created with hairpin mode, and in versions <= 0.1.6
+		{Family: family, Table: Nat, Chain: "OUTPUT", DstType: "LOCAL", Target: DockerChain},
+		{Family: family, Table: Nat, Chain: "PREROUTING", Target: DockerChain},
+		{Family: family, Table: Nat, Chain: "OUTPUT", Target: DockerChain},
+	}
+	for _, r := range jumps {
+		fw.backend.Apply(Delete, r)
+	}
+	fw.backend.RemoveChain(family, Nat, DockerChain)
+	return nil
+}
+
+// Forward adds or deletes the rules forwarding hostIP:hostPort to the port
+// of a container.
+func (fw *Firewall) Forward(action Action, proto string, hostIP net.IP, hostPort int, containerIP net.IP, containerPort int) error {
+	family := familyOf(containerIP)
+
+	dnat := Rule{Family: family, Table: Nat, Chain: DockerChain,
+		Proto:         proto,
+		DstPort:       hostPort,
+		Target:        "DNAT",
+		ToDestination: net.JoinHostPort(containerIP.String(), strconv.Itoa(containerPort))}
+	if !hostIP.IsUnspecified() {
+		dnat.Dst = Is(hostIP.String())
+	}
+	if !fw.hairpinMode {
+		// without hairpin mode, the userland proxy handles the
+		// traffic coming from the bridge
+		dnat.InIface = IsNot(fw.bridge)
+	}
+
+	return fw.backend.Apply(action, dnat,
+		Rule{Family: family, Table: Filter, Chain: DockerChain,
+			InIface:  IsNot(fw.bridge),
+			OutIface: Is(fw.bridge),
+			Proto:    proto,
+			Dst:      Is(containerIP.String()),
+			DstPort:  containerPort,
+			Target:   "ACCEPT"},
+		Rule{Family: family, Table: Nat, Chain: "POSTROUTING",
+			Proto:   proto,
+			Src:     Is(containerIP.String()),
+			Dst:     Is(containerIP.String()),
+			DstPort: containerPort,
+			Target:  "MASQUERADE"})
+}
+
+// Link adds or deletes the rules accepting the traffic from ip1 to port of
+// ip2, and its replies.
+func (fw *Firewall) Link(action Action, proto string, ip1, ip2 net.IP, port int) error {
+	family := familyOf(ip1)
+	return fw.backend.Apply(action,
+		Rule{Family: family, Table: Filter, Chain: DockerChain,
+			InIface:  Is(fw.bridge),
+			OutIface: Is(fw.bridge),
+			Proto:    proto,
+			Src:      Is(ip1.String()),
+			Dst:      Is(ip2.String()),
+			DstPort:  port,
+			Target:   "ACCEPT"},
+		Rule{Family: family, Table: Filter, Chain: DockerChain,
+			InIface:  Is(fw.bridge),
+			OutIface: Is(fw.bridge),
+			Proto:    proto,
+			Src:      Is(ip2.String()),
+			Dst:      Is(ip1.String()),
+			SrcPort:  port,
+			Target:   "ACCEPT"})
+}
+
+// natJumps returns the rules sending the traffic to the addresses of the
+// host to the nat DOCKER chain.
+func (fw *Firewall) natJumps(family Family) []Rule {
+	output := Rule{Family: family, Table: Nat, Chain: "OUTPUT", DstType: "LOCAL", Target: DockerChain}
+	// ::1 can't be forwarded to the containers, even in hairpin mode
+	if !fw.hairpinMode || family == IPv6 {
+		output.Dst = IsNot(loopback(family))
+	}
+	return []Rule{
+		{Family: family, Table: Nat, Chain: "PREROUTING", DstType: "LOCAL", Target: DockerChain},
+		output,
+	}
+}
+
+// filterJump returns the rule sending the traffic to the bridge to the
+// filter DOCKER chain.
+func (fw *Firewall) filterJump(family Family) Rule {
+	return Rule{Family: family, Table: Filter, Chain: "FORWARD", OutIface: Is(fw.bridge), Target: DockerChain}
+}
+
+// insertMissing inserts the rules which aren't in their chains yet.
+func (fw *Firewall) insertMissing(rules ...Rule) error {
+	var missing []Rule
+	for _, r := range rules {
+		if !fw.backend.Exists(r) {
+			missing = append(missing, r)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fw.backend.Apply(Insert, missing...)
+}
+
+func familyOf(ip net.IP) Family {
+	if ip.To4() == nil {
+		return IPv6
+	}
+	return IPv4
+}
+
+// loopback returns the loopback network of the family.
+func loopback(family Family) string {
+	if family == IPv6 {
+		return "::1/128"
+	}
+	return "127.0.0.0/8"
+}
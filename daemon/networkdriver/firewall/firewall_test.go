@@ -0,0 +1,275 @@
+package firewall
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recorder is a backend recording the rules applied, in order.
+type recorder struct {
+	rules []Rule
+}
+
+func (r *recorder) NewChain(family Family, table Table, name string) error    { return nil }
+func (r *recorder) RemoveChain(family Family, table Table, name string) error { return nil }
+func (r *recorder) Exists(rule Rule) bool                                     { return false }
+
+func (r *recorder) Apply(action Action, rules ...Rule) error {
+	if action != Delete {
+		r.rules = append(r.rules, rules...)
+	}
+	return nil
+}
+
+// canonicalIptables returns the matches and the target of an iptables rule, as
+// produced by iptablesRule.
+func canonicalIptables(family Family, args []string) map[string]string {
+	rule := map[string]string{"family": string(family), "chain": args[0]}
+	flags := map[string]string{
+		"-t": "table", "-p": "proto", "-s": "src", "-d": "dst", "-i": "iif", "-o": "oif",
+		"--src-type": "srctype", "--dst-type": "dsttype", "--sport": "sport", "--dport": "dport",
+		"--ctstate": "ctstate", "-j": "target", "--to-destination": "to",
+	}
+	not := ""
+	for i := 1; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "!":
+			not = "!"
+		case "-m":
+			i++ // the matches of the modules are parsed on their own
+		default:
+			i++
+			rule[flags[arg]] = not + strings.ToLower(args[i])
+			not = ""
+		}
+	}
+	return rule
+}
+
+// canonicalNft returns the matches and the target of an nft rule, as
+// produced by nftRule, in the form of canonicalIptables.
+func canonicalNft(chain, statement string) map[string]string {
+	parts := strings.SplitN(chain, "-", 2)
+	rule := map[string]string{"table": parts[0], "chain": strings.ToUpper(parts[1])}
+	words := strings.Fields(statement)
+	addrs := map[string]string{"saddr": "src", "daddr": "dst"}
+	families := map[string]string{"ip": "ipv4", "ip6": "ipv6"}
+	value := func(i int) (string, int) {
+		if words[i] == "!=" {
+			return "!" + strings.Trim(words[i+1], `"`), i + 1
+		}
+		return strings.Trim(words[i], `"`), i
+	}
+	for i := 0; i < len(words); i++ {
+		switch w := words[i]; w {
+		case "meta":
+			if words[i+1] == "nfproto" {
+				rule["family"] = words[i+2]
+			} else {
+				rule["proto"] = words[i+2]
+			}
+			i += 2
+		case "iifname", "oifname":
+			rule[w[:3]], i = value(i + 1)
+		case "ip", "ip6":
+			rule["family"] = families[w]
+			rule[addrs[words[i+1]]], i = value(i + 2)
+		case "fib":
+			rule[addrs[words[i+1]]+"type"] = words[i+3]
+			i += 3
+		case "tcp", "udp":
+			rule["proto"] = w
+			rule[words[i+1]] = words[i+2]
+			i += 2
+		case "ct":
+			rule["ctstate"] = words[i+2]
+			i += 2
+		case "accept", "drop", "masquerade":
+			rule["target"] = w
+		case "dnat":
+			rule["family"] = families[words[i+1]]
+			rule["target"] = "dnat"
+			rule["to"] = words[i+3]
+			i += 3
+		case "jump":
+			rule["target"] = strings.ToLower(strings.SplitN(words[i+1], "-", 2)[1])
+			i++
+		}
+	}
+	return rule
+}
+
+func TestBackendsEquivalence(t *testing.T) {
+	_, network, _ := net.ParseCIDR("172.17.42.1/16")
+
+	for _, hairpinMode := range []bool{false, true} {
+		rec := &recorder{}
+		fw := &Firewall{backend: rec, bridge: "docker0", hairpinMode: hairpinMode}
+
+		if err := fw.Setup(network, false, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := fw.Setup(network, true, false); err != nil {
+			t.Fatal(err)
+		}
+		for _, family := range []Family{IPv4, IPv6} {
+			if err := fw.NewChains(family); err != nil {
+				t.Fatal(err)
+			}
+		}
+		fw.Forward(Append, "tcp", net.ParseIP("0.0.0.0"), 8080, net.ParseIP("172.17.0.2"), 80)
+		fw.Forward(Append, "udp", net.ParseIP("127.0.0.1"), 5353, net.ParseIP("172.17.0.2"), 53)
+		fw.Forward(Append, "tcp", net.ParseIP("::"), 8080, net.ParseIP("2001:db8::2"), 80)
+		fw.Link(Insert, "tcp", net.ParseIP("172.17.0.2"), net.ParseIP("172.17.0.3"), 6379)
+
+		for _, r := range rec.rules {
+			iptables := canonicalIptables(r.Family, iptablesRule(r))
+			nft := canonicalNft(nftChain(r.Table, r.Chain), nftRule(r))
+			if !reflect.DeepEqual(iptables, nft) {
+				t.Fatalf("The backends produced different rules for %+v:\niptables: %v\nnftables: %v", r, iptables, nft)
+			}
+		}
+	}
+}
+
+func TestIptablesRule(t *testing.T) {
+	fw := &Firewall{bridge: "docker0"}
+	r := Rule{Family: IPv4, Table: Nat, Chain: DockerChain,
+		Proto:         "tcp",
+		Dst:           Is("127.0.0.1"),
+		InIface:       IsNot(fw.bridge),
+		DstPort:       8080,
+		Target:        "DNAT",
+		ToDestination: "172.17.0.2:80"}
+
+	expected := "DOCKER -t nat -p tcp -d 127.0.0.1 ! -i docker0 --dport 8080 -j DNAT --to-destination 172.17.0.2:80"
+	if args := strings.Join(iptablesRule(r), " "); args != expected {
+		t.Fatalf("Expected %q, got %q", expected, args)
+	}
+}
+
+func TestNftRule(t *testing.T) {
+	for _, c := range []struct {
+		rule     Rule
+		expected string
+	}{
+		{
+			Rule{Family: IPv4, Table: Filter, Chain: "FORWARD", OutIface: Is("docker0"), CtState: "RELATED,ESTABLISHED", Target: "ACCEPT"},
+			`meta nfproto ipv4 oifname "docker0" ct state related,established accept`,
+		},
+		{
+			Rule{Family: IPv6, Table: Nat, Chain: DockerChain, Proto: "tcp", InIface: IsNot("docker0"), DstPort: 8080, Target: "DNAT", ToDestination: "[2001:db8::2]:80"},
+			`meta nfproto ipv6 iifname != "docker0" tcp dport 8080 dnat ip6 to [2001:db8::2]:80`,
+		},
+		{
+			Rule{Family: IPv4, Table: Nat, Chain: "OUTPUT", DstType: "LOCAL", Dst: IsNot("127.0.0.0/8"), Target: DockerChain},
+			`ip daddr != 127.0.0.0/8 fib daddr type local jump nat-docker`,
+		},
+	} {
+		if statement := nftRule(c.rule); statement != c.expected {
+			t.Fatalf("Expected %q, got %q", c.expected, statement)
+		}
+	}
+}
+
+func TestNftablesBackend(t *testing.T) {
+	var scripts []string
+	b := newNftablesBackend()
+	b.commit = func(script string) error {
+		scripts = append(scripts, script)
+		return nil
+	}
+	fw := &Firewall{backend: b, bridge: "docker0"}
+
+	_, network, _ := net.ParseCIDR("172.17.0.0/16")
+	fw.RemoveChains(IPv4)
+	if err := fw.Setup(network, true, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.NewChains(IPv4); err != nil {
+		t.Fatal(err)
+	}
+
+	// one transaction for all the rules of a port
+	n := len(scripts)
+	if err := fw.Forward(Append, "tcp", net.ParseIP("0.0.0.0"), 8080, net.ParseIP("172.17.0.2"), 80); err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) != n+1 {
+		t.Fatalf("Expected the rules to be applied in a single transaction, got %d", len(scripts)-n)
+	}
+	script := scripts[len(scripts)-1]
+	for _, line := range []string{
+		"delete table inet docker\n",
+		"\tchain nat-docker {\n\t\tmeta nfproto ipv4 iifname != \"docker0\" tcp dport 8080 dnat ip to 172.17.0.2:80\n\t}\n",
+		"\tchain filter-forward {\n\t\ttype filter hook forward priority 0; policy accept;\n" +
+			"\t\tmeta nfproto ipv4 oifname \"docker0\" jump filter-docker\n" +
+			"\t\tmeta nfproto ipv4 oifname \"docker0\" ct state related,established accept\n",
+	} {
+		if !strings.Contains(script, line) {
+			t.Fatalf("Expected %q in the script:\n%s", line, script)
+		}
+	}
+	if strings.Index(script, "chain filter-docker") > strings.Index(script, "chain filter-forward") {
+		t.Fatalf("Expected the regular chains before the base chains:\n%s", script)
+	}
+
+	// a failed transaction leaves the rules as they were
+	b.commit = func(string) error { return errors.New("nft failed") }
+	if err := fw.Forward(Delete, "tcp", net.ParseIP("0.0.0.0"), 8080, net.ParseIP("172.17.0.2"), 80); err == nil {
+		t.Fatal("Expected the error of nft")
+	}
+	b.commit = func(string) error { return nil }
+	if err := fw.Forward(Delete, "tcp", net.ParseIP("0.0.0.0"), 8080, net.ParseIP("172.17.0.2"), 80); err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.Forward(Delete, "tcp", net.ParseIP("0.0.0.0"), 8080, net.ParseIP("172.17.0.2"), 80); err == nil {
+		t.Fatal("Deleted the rules of a port twice")
+	}
+
+	// the IPv6 chains are distinct from the IPv4 ones
+	if err := fw.Forward(Append, "tcp", net.ParseIP("::"), 8080, net.ParseIP("2001:db8::2"), 80); err == nil {
+		t.Fatal("Added rules to chains of IPv6 which don't exist")
+	}
+	if err := fw.NewChains(IPv6); err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.RemoveChains(IPv6); err != nil {
+		t.Fatal(err)
+	}
+	if !b.chains[chainKey{Nat, DockerChain}][IPv4] || b.chains[chainKey{Nat, DockerChain}][IPv6] {
+		t.Fatalf("Expected the IPv4 chain to be kept, got %v", b.chains)
+	}
+	if !b.Exists(fw.natJumps(IPv4)[0]) || b.Exists(fw.natJumps(IPv6)[0]) {
+		t.Fatal("Expected the rules jumping to the IPv6 chain only to be removed")
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, name := range append(Backends, "") {
+		if _, err := New(name, "docker0", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := New("pf", "docker0", false); err == nil {
+		t.Fatal("Expected an error for an unknown backend")
+	}
+}
+
+func TestNatJumpsHairpinMode(t *testing.T) {
+	fw := &Firewall{backend: &recorder{}, bridge: "docker0", hairpinMode: true}
+
+	for _, r := range fw.natJumps(IPv4) {
+		if r.Chain == "OUTPUT" && r.Dst != (Match{}) {
+			t.Fatalf("Expected the IPv4 loopback to be forwarded in hairpin mode, got %+v", r)
+		}
+	}
+	for _, r := range fw.natJumps(IPv6) {
+		if r.Chain == "OUTPUT" && r.Dst != IsNot("::1/128") {
+			t.Fatalf("Expected the IPv6 loopback to be excluded in hairpin mode, got %+v", r)
+		}
+	}
+}
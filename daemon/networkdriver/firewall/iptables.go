@@ -0,0 +1,108 @@
+package firewall
+
+import (
+	"strconv"
+
+	"github.com/docker/docker/pkg/iptables"
+)
+
+// iptablesBackend installs the rules with iptables and ip6tables, one
+// command per rule.
+type iptablesBackend struct{}
+
+var iptablesActions = map[Action]string{
+	Append: "-A",
+	Insert: "-I",
+	Delete: "-D",
+}
+
+func (b *iptablesBackend) raw(family Family, args ...string) ([]byte, error) {
+	if family == IPv6 {
+		return iptables.Raw6(args...)
+	}
+	return iptables.Raw(args...)
+}
+
+func (b *iptablesBackend) NewChain(family Family, table Table, name string) error {
+	if _, err := b.raw(family, "-t", string(table), "-n", "-L", name); err == nil {
+		return nil
+	}
+	if output, err := b.raw(family, "-t", string(table), "-N", name); err != nil {
+		return err
+	} else if len(output) != 0 {
+		return &iptables.ChainError{Chain: name, Output: output}
+	}
+	return nil
+}
+
+func (b *iptablesBackend) RemoveChain(family Family, table Table, name string) error {
+	// Ignore errors - This could mean the chain was never set up
+	b.raw(family, "-t", string(table), "-F", name)
+	b.raw(family, "-t", string(table), "-X", name)
+	return nil
+}
+
+func (b *iptablesBackend) Exists(r Rule) bool {
+	if r.Family == IPv6 {
+		return iptables.Exists6(iptablesRule(r)...)
+	}
+	return iptables.Exists(iptablesRule(r)...)
+}
+
+func (b *iptablesBackend) Apply(action Action, rules ...Rule) error {
+	for _, r := range rules {
+		args := append([]string{iptablesActions[action]}, iptablesRule(r)...)
+		if output, err := b.raw(r.Family, args...); err != nil {
+			return err
+		} else if len(output) != 0 {
+			return &iptables.ChainError{Chain: r.Chain, Output: output}
+		}
+	}
+	return nil
+}
+
+// iptablesRule returns the arguments of iptables describing the rule, after
+// the action.
+func iptablesRule(r Rule) []string {
+	args := []string{r.Chain, "-t", string(r.Table)}
+	match := func(flag string, m Match) {
+		if m.Value == "" {
+			return
+		}
+		if m.Not {
+			args = append(args, "!")
+		}
+		args = append(args, flag, m.Value)
+	}
+
+	if r.Proto != "" {
+		args = append(args, "-p", r.Proto)
+	}
+	match("-s", r.Src)
+	match("-d", r.Dst)
+	match("-i", r.InIface)
+	match("-o", r.OutIface)
+	if r.SrcType != "" || r.DstType != "" {
+		args = append(args, "-m", "addrtype")
+		if r.SrcType != "" {
+			args = append(args, "--src-type", r.SrcType)
+		}
+		if r.DstType != "" {
+			args = append(args, "--dst-type", r.DstType)
+		}
+	}
+	if r.SrcPort != 0 {
+		args = append(args, "--sport", strconv.Itoa(r.SrcPort))
+	}
+	if r.DstPort != 0 {
+		args = append(args, "--dport", strconv.Itoa(r.DstPort))
+	}
+	if r.CtState != "" {
+		args = append(args, "-m", "conntrack", "--ctstate", r.CtState)
+	}
+	args = append(args, "-j", r.Target)
+	if r.ToDestination != "" {
+		args = append(args, "--to-destination", r.ToDestination)
+	}
+	return args
+}
@@ -0,0 +1,304 @@
+package firewall
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// nftTable is the table of all the rules of the nftables backend, of both
+// address families.
+const nftTable = "docker"
+
+var (
+	nftPath        string
+	ErrNftNotFound = errors.New("nft not found")
+
+	// nftHooks are the definitions of the chains of the nftables backend
+	// standing for the built-in chains of iptables
+	nftHooks = map[chainKey]string{
+		{Nat, "PREROUTING"}:  "type nat hook prerouting priority -100; policy accept;",
+		{Nat, "OUTPUT"}:      "type nat hook output priority -100; policy accept;",
+		{Nat, "POSTROUTING"}: "type nat hook postrouting priority 100; policy accept;",
+		{Filter, "FORWARD"}:  "type filter hook forward priority 0; policy accept;",
+	}
+	nftHookOrder = []chainKey{{Nat, "PREROUTING"}, {Nat, "OUTPUT"}, {Nat, "POSTROUTING"}, {Filter, "FORWARD"}}
+)
+
+type chainKey struct {
+	table Table
+	name  string
+}
+
+// nftablesBackend keeps the rules in memory, in chains which behave like the
+// ones of iptables, and replaces the whole table at once on every change,
+// with a single nft transaction.
+type nftablesBackend struct {
+	sync.Mutex
+	// chains are the families which created each regular chain
+	chains map[chainKey]map[Family]bool
+	rules  map[chainKey][]Rule
+	// commit replaces the table with the script
+	commit func(script string) error
+}
+
+func newNftablesBackend() *nftablesBackend {
+	return &nftablesBackend{
+		chains: make(map[chainKey]map[Family]bool),
+		rules:  make(map[chainKey][]Rule),
+		commit: nft,
+	}
+}
+
+func (b *nftablesBackend) NewChain(family Family, table Table, name string) error {
+	b.Lock()
+	defer b.Unlock()
+
+	key := chainKey{table, name}
+	if b.chains[key][family] {
+		return nil
+	}
+	chains := b.copyChains()
+	if chains[key] == nil {
+		chains[key] = make(map[Family]bool)
+	}
+	chains[key][family] = true
+	return b.update(chains, b.rules)
+}
+
+func (b *nftablesBackend) RemoveChain(family Family, table Table, name string) error {
+	b.Lock()
+	defer b.Unlock()
+
+	// the table is committed even without the chain, which replaces the
+	// table left by a previous run of the daemon
+	key := chainKey{table, name}
+	chains := b.copyChains()
+	delete(chains[key], family)
+	if len(chains[key]) == 0 {
+		delete(chains, key)
+	}
+
+	// the rules of the family in the chain, and the ones jumping to it, go
+	// with it
+	rules := make(map[chainKey][]Rule)
+	for k, rs := range b.rules {
+		for _, r := range rs {
+			if r.Family == family && r.Table == table && (k == key || r.Target == name) {
+				continue
+			}
+			rules[k] = append(rules[k], r)
+		}
+	}
+	return b.update(chains, rules)
+}
+
+func (b *nftablesBackend) Exists(r Rule) bool {
+	b.Lock()
+	defer b.Unlock()
+	return indexOf(b.rules[chainKey{r.Table, r.Chain}], r) >= 0
+}
+
+func (b *nftablesBackend) Apply(action Action, rules ...Rule) error {
+	b.Lock()
+	defer b.Unlock()
+
+	next := b.copyRules()
+	for _, r := range rules {
+		key := chainKey{r.Table, r.Chain}
+		if _, ok := nftHooks[key]; !ok && !b.chains[key][r.Family] {
+			return fmt.Errorf("No chain %s in table %s", r.Chain, r.Table)
+		}
+		switch action {
+		case Append:
+			next[key] = append(next[key], r)
+		case Insert:
+			next[key] = append([]Rule{r}, next[key]...)
+		case Delete:
+			i := indexOf(next[key], r)
+			if i < 0 {
+				return fmt.Errorf("No such rule in chain %s of table %s: %s", r.Chain, r.Table, nftRule(r))
+			}
+			next[key] = append(next[key][:i:i], next[key][i+1:]...)
+		default:
+			return fmt.Errorf("Invalid action %s", action)
+		}
+	}
+	return b.update(b.chains, next)
+}
+
+// update commits the table made of chains and rules, and keeps them once
+// committed.
+func (b *nftablesBackend) update(chains map[chainKey]map[Family]bool, rules map[chainKey][]Rule) error {
+	if err := b.commit(nftScript(chains, rules)); err != nil {
+		return err
+	}
+	b.chains = chains
+	b.rules = rules
+	return nil
+}
+
+func (b *nftablesBackend) copyChains() map[chainKey]map[Family]bool {
+	chains := make(map[chainKey]map[Family]bool, len(b.chains))
+	for k, families := range b.chains {
+		chains[k] = make(map[Family]bool, len(families))
+		for f := range families {
+			chains[k][f] = true
+		}
+	}
+	return chains
+}
+
+func (b *nftablesBackend) copyRules() map[chainKey][]Rule {
+	rules := make(map[chainKey][]Rule, len(b.rules))
+	for k, rs := range b.rules {
+		rules[k] = append([]Rule(nil), rs...)
+	}
+	return rules
+}
+
+func indexOf(rules []Rule, r Rule) int {
+	for i, rule := range rules {
+		if rule == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// nftChain returns the name of the chain of table in the table of the
+// backend, which holds the chains of all the tables of iptables.
+func nftChain(table Table, name string) string {
+	return strings.ToLower(string(table) + "-" + name)
+}
+
+// nftScript returns the nft script replacing the table of the backend with
+// the chains and their rules. The regular chains come first, for the
+// others to jump to them.
+func nftScript(chains map[chainKey]map[Family]bool, rules map[chainKey][]Rule) string {
+	var regular []chainKey
+	for k := range chains {
+		regular = append(regular, k)
+	}
+	sort.Sort(byName(regular))
+
+	var buf bytes.Buffer
+	// declaring the table first lets it be deleted when it doesn't exist
+	fmt.Fprintf(&buf, "table inet %s\ndelete table inet %s\ntable inet %s {\n", nftTable, nftTable, nftTable)
+	writeChain := func(k chainKey, hook string) {
+		fmt.Fprintf(&buf, "\tchain %s {\n", nftChain(k.table, k.name))
+		if hook != "" {
+			fmt.Fprintf(&buf, "\t\t%s\n", hook)
+		}
+		for _, r := range rules[k] {
+			fmt.Fprintf(&buf, "\t\t%s\n", nftRule(r))
+		}
+		buf.WriteString("\t}\n")
+	}
+	for _, k := range regular {
+		writeChain(k, "")
+	}
+	for _, k := range nftHookOrder {
+		if len(rules[k]) > 0 {
+			writeChain(k, nftHooks[k])
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+type byName []chainKey
+
+func (s byName) Len() int      { return len(s) }
+func (s byName) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byName) Less(i, j int) bool {
+	return nftChain(s[i].table, s[i].name) < nftChain(s[j].table, s[j].name)
+}
+
+// nftRule returns the statement of the rule in an nft chain.
+func nftRule(r Rule) string {
+	var (
+		expr []string
+		ip   = "ip"
+	)
+	if r.Family == IPv6 {
+		ip = "ip6"
+	}
+	match := func(key string, m Match, quote bool) {
+		if m.Value == "" {
+			return
+		}
+		value := m.Value
+		if quote {
+			value = strconv.Quote(value)
+		}
+		if m.Not {
+			expr = append(expr, key, "!=", value)
+		} else {
+			expr = append(expr, key, value)
+		}
+	}
+
+	// the address matches restrict the rule to their family already
+	if r.Src.Value == "" && r.Dst.Value == "" {
+		expr = append(expr, "meta", "nfproto", string(r.Family))
+	}
+	match("iifname", r.InIface, true)
+	match("oifname", r.OutIface, true)
+	match(ip+" saddr", r.Src, false)
+	match(ip+" daddr", r.Dst, false)
+	if r.SrcType != "" {
+		expr = append(expr, "fib", "saddr", "type", strings.ToLower(r.SrcType))
+	}
+	if r.DstType != "" {
+		expr = append(expr, "fib", "daddr", "type", strings.ToLower(r.DstType))
+	}
+	if r.Proto != "" && r.SrcPort == 0 && r.DstPort == 0 {
+		expr = append(expr, "meta", "l4proto", r.Proto)
+	}
+	if r.SrcPort != 0 {
+		expr = append(expr, r.Proto, "sport", strconv.Itoa(r.SrcPort))
+	}
+	if r.DstPort != 0 {
+		expr = append(expr, r.Proto, "dport", strconv.Itoa(r.DstPort))
+	}
+	if r.CtState != "" {
+		expr = append(expr, "ct", "state", strings.ToLower(r.CtState))
+	}
+
+	switch r.Target {
+	case "ACCEPT", "DROP", "MASQUERADE":
+		expr = append(expr, strings.ToLower(r.Target))
+	case "DNAT":
+		expr = append(expr, "dnat", ip, "to", r.ToDestination)
+	default:
+		expr = append(expr, "jump", nftChain(r.Table, r.Target))
+	}
+	return strings.Join(expr, " ")
+}
+
+// nft runs the script as a single nft transaction.
+func nft(script string) error {
+	if nftPath == "" {
+		path, err := exec.LookPath("nft")
+		if err != nil {
+			return ErrNftNotFound
+		}
+		nftPath = path
+	}
+	log.Debugf("%s -f -: %s", nftPath, script)
+
+	cmd := exec.Command(nftPath, "-f", "-")
+	cmd.Stdin = strings.NewReader(script)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("nft failed: %s (%s)", output, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package macvlan
+
+import (
+	"net"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/networkdriver"
+	"github.com/docker/docker/daemon/networkdriver/ipallocator"
+	"github.com/docker/docker/engine"
+)
+
+type ifaces struct {
+	c map[string]net.IP
+	sync.Mutex
+}
+
+var (
+	parentIface string
+	network     *net.IPNet
+	gateway     net.IP
+
+	currentInterfaces = ifaces{c: make(map[string]net.IP)}
+)
+
+// InitDriver sets up the macvlan network: the containers in the macvlan
+// network mode get a macvlan interface of the parent interface, with an
+// address of the subnet of the parent
+func InitDriver(job *engine.Job) engine.Status {
+	var (
+		ipRange = job.Getenv("IPRange")
+		err     error
+	)
+
+	parentIface = job.Getenv("Parent")
+	if _, err := net.InterfaceByName(parentIface); err != nil {
+		return job.Errorf("Unable to find the macvlan parent interface %s: %s", parentIface, err)
+	}
+
+	if job.Getenv("Subnet") == "" {
+		return job.Errorf("The macvlan network requires a subnet (--macvlan-subnet)")
+	}
+	var ip net.IP
+	if ip, network, err = net.ParseCIDR(job.Getenv("Subnet")); err != nil {
+		return job.Errorf("Invalid macvlan subnet %s: %s", job.Getenv("Subnet"), err)
+	}
+	if ip.To4() == nil {
+		return job.Errorf("The macvlan subnet %s must be an IPv4 subnet", network)
+	}
+
+	if ipRange != "" {
+		_, subnet, err := net.ParseCIDR(ipRange)
+		if err != nil {
+			return job.Errorf("Invalid macvlan IP range %s: %s", ipRange, err)
+		}
+		if err := ipallocator.RegisterSubnet(network, subnet); err != nil {
+			return job.Errorf("Invalid macvlan IP range %s: %s", ipRange, err)
+		}
+	}
+
+	if gw := job.Getenv("Gateway"); gw != "" {
+		if gateway = net.ParseIP(gw); gateway == nil || !network.Contains(gateway) {
+			return job.Errorf("The macvlan gateway %s must be an address of the subnet %s", gw, network)
+		}
+		// Block the gateway in the IP allocator, it's fine if it's out
+		// of the IP range
+		ipallocator.RequestIP(network, gateway)
+	}
+
+	for name, f := range map[string]engine.Handler{
+		"allocate_macvlan_interface": Allocate,
+		"release_macvlan_interface":  Release,
+	} {
+		if err := job.Eng.Register(name, f); err != nil {
+			return job.Error(err)
+		}
+	}
+	return engine.StatusOK
+}
+
+// Allocate an address of the subnet to a container
+func Allocate(job *engine.Job) engine.Status {
+	var (
+		id          = job.Args[0]
+		requestedIP = net.ParseIP(job.Getenv("RequestedIP"))
+		mac         net.HardwareAddr
+	)
+
+	ip, err := ipallocator.RequestIP(network, requestedIP)
+	if err != nil {
+		switch err {
+		case ipallocator.ErrIPAlreadyAllocated:
+			return job.Errorf("IP address %s is already in use", requestedIP)
+		case ipallocator.ErrIPOutOfRange:
+			return job.Errorf("IP address %s is out of the range of the macvlan addresses", requestedIP)
+		}
+		return job.Error(err)
+	}
+
+	// If no explicit mac address was given, generate one from the IP.
+	if mac, err = net.ParseMAC(job.Getenv("RequestedMac")); err != nil {
+		mac = networkdriver.GenerateMacAddr(ip)
+	}
+
+	currentInterfaces.Lock()
+	currentInterfaces.c[id] = ip
+	currentInterfaces.Unlock()
+
+	out := engine.Env{}
+	out.Set("IP", ip.String())
+	out.Set("Mask", network.Mask.String())
+	size, _ := network.Mask.Size()
+	out.SetInt("IPPrefixLen", size)
+	if gateway != nil {
+		out.Set("Gateway", gateway.String())
+	}
+	out.Set("MacAddress", mac.String())
+	out.WriteTo(job.Stdout)
+
+	return engine.StatusOK
+}
+
+// Release the address of a container
+func Release(job *engine.Job) engine.Status {
+	id := job.Args[0]
+
+	currentInterfaces.Lock()
+	ip, ok := currentInterfaces.c[id]
+	delete(currentInterfaces.c, id)
+	currentInterfaces.Unlock()
+
+	if !ok {
+		return job.Errorf("No network information to release for %s", id)
+	}
+	if err := ipallocator.ReleaseIP(network, ip); err != nil {
+		log.Infof("Unable to release IP %s", err)
+	}
+	return engine.StatusOK
+}
@@ -0,0 +1,101 @@
+package macvlan
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/engine"
+)
+
+func newDriver(t *testing.T, env map[string]string) (*engine.Engine, engine.Status) {
+	eng := engine.New()
+	eng.Logging = false
+
+	job := eng.Job("init_macvlan_driver")
+	for k, v := range env {
+		job.Setenv(k, v)
+	}
+	return eng, InitDriver(job)
+}
+
+func allocate(t *testing.T, eng *engine.Engine, id, requestedIP string) (engine.Env, engine.Status) {
+	job := eng.Job("allocate_macvlan_interface", id)
+	job.Setenv("RequestedIP", requestedIP)
+	out := &bytes.Buffer{}
+	job.Stdout.Add(out)
+	res := Allocate(job)
+
+	env := engine.Env{}
+	if res == engine.StatusOK {
+		if err := env.Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return env, res
+}
+
+func TestInitDriverConfig(t *testing.T) {
+	for _, env := range []map[string]string{
+		{"Parent": "doesnotexist0", "Subnet": "10.2.0.0/24"},
+		{"Parent": "lo"},
+		{"Parent": "lo", "Subnet": "2001:db8::/64"},
+		{"Parent": "lo", "Subnet": "10.2.0.0/24", "Gateway": "10.3.0.1"},
+		{"Parent": "lo", "Subnet": "10.2.0.0/24", "IPRange": "10.3.0.0/25"},
+	} {
+		if _, res := newDriver(t, env); res == engine.StatusOK {
+			t.Fatalf("Expected the macvlan driver to reject %v", env)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	eng, res := newDriver(t, map[string]string{
+		"Parent":  "lo",
+		"Subnet":  "10.1.0.0/24",
+		"Gateway": "10.1.0.1",
+		"IPRange": "10.1.0.128/25",
+	})
+	if res != engine.StatusOK {
+		t.Fatal("Failed to init the macvlan driver")
+	}
+
+	env, res := allocate(t, eng, "c1", "")
+	if res != engine.StatusOK {
+		t.Fatal("Failed to allocate an address")
+	}
+	ip := net.ParseIP(env.Get("IP"))
+	if ip == nil || ip.To4()[3] < 128 {
+		t.Fatalf("Expected an address in the IP range, got %s", env.Get("IP"))
+	}
+	if env.Get("Gateway") != "10.1.0.1" || env.GetInt("IPPrefixLen") != 24 {
+		t.Fatalf("Expected the gateway and prefix of the subnet, got %s/%d", env.Get("Gateway"), env.GetInt("IPPrefixLen"))
+	}
+	if !strings.HasPrefix(env.Get("MacAddress"), "02:42:0a:01:00:") {
+		t.Fatalf("Expected a MAC address generated from the IP, got %s", env.Get("MacAddress"))
+	}
+
+	if _, res := allocate(t, eng, "c2", "10.1.0.200"); res != engine.StatusOK {
+		t.Fatal("Failed to allocate a static address")
+	}
+	if _, res := allocate(t, eng, "c3", "10.1.0.200"); res == engine.StatusOK {
+		t.Fatal("Allocated an address in use")
+	}
+	if _, res := allocate(t, eng, "c3", "10.1.0.10"); res == engine.StatusOK {
+		t.Fatal("Allocated an address out of the IP range")
+	}
+
+	if res := Release(eng.Job("release_macvlan_interface", "c2")); res != engine.StatusOK {
+		t.Fatal("Failed to release the address")
+	}
+	if _, res := allocate(t, eng, "c3", "10.1.0.200"); res != engine.StatusOK {
+		t.Fatal("Failed to allocate a released address")
+	}
+	if res := Release(eng.Job("release_macvlan_interface", "c2")); res == engine.StatusOK {
+		t.Fatal("Released the address of a container twice")
+	}
+
+	Release(eng.Job("release_macvlan_interface", "c1"))
+	Release(eng.Job("release_macvlan_interface", "c3"))
+}
@@ -7,8 +7,8 @@ import (
 	"sync"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/docker/docker/daemon/networkdriver/firewall"
 	"github.com/docker/docker/daemon/networkdriver/portallocator"
-	"github.com/docker/docker/pkg/iptables"
 )
 
 type mapping struct {
@@ -19,8 +19,13 @@ type mapping struct {
 }
 
 var (
-	chain *iptables.Chain
-	lock  sync.Mutex
+	fw   *firewall.Firewall
+	fw6  *firewall.Firewall
+	lock sync.Mutex
+
+	// with the userland proxy disabled, the traffic to the host ports is
+	// only forwarded by the firewall, hairpin NAT included
+	enableUserlandProxy = true
 
 	// udp:ip:port
 	currentMappings = make(map[string]*mapping)
@@ -32,13 +37,43 @@ var (
 	ErrUnknownBackendAddressType = errors.New("unknown container address type not supported")
 	ErrPortMappedForIP           = errors.New("port is already mapped to ip")
 	ErrPortNotMapped             = errors.New("port is not mapped")
+	ErrPortNotForwarded          = errors.New("port cannot be forwarded without the userland proxy: it needs the NAT of the firewall for the address family of the host ip and the container")
 )
 
-func SetIptablesChain(c *iptables.Chain) {
-	chain = c
+func SetFirewall(f *firewall.Firewall) {
+	fw = f
+}
+
+// SetFirewall6 sets the firewall of the IPv6 mappings. Without it, they are
+// only served by the userland proxy.
+func SetFirewall6(f *firewall.Firewall) {
+	fw6 = f
+}
+
+// SetUserlandProxy sets whether the mapped ports are served by a userland
+// proxy, or only bound by a dummy one.
+func SetUserlandProxy(enabled bool) {
+	enableUserlandProxy = enabled
 }
 
-func Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err error) {
+func newProxy(proto string, hostIP net.IP, hostPort int, containerIP net.IP, containerPort int, ipv4Only bool) UserlandProxy {
+	if !enableUserlandProxy {
+		return NewDummyProxy(proto, hostIP, hostPort, ipv4Only)
+	}
+	return NewProxy(proto, hostIP, hostPort, containerIP, containerPort, ipv4Only)
+}
+
+func Map(container net.Addr, hostIP net.IP, hostPort int) (net.Addr, error) {
+	return mapPort(container, hostIP, hostPort, false)
+}
+
+// MapIPv4Only is Map for the unspecified IPv4 address, leaving the port
+// free on the IPv6 addresses for another mapping.
+func MapIPv4Only(container net.Addr, hostIP net.IP, hostPort int) (net.Addr, error) {
+	return mapPort(container, hostIP, hostPort, true)
+}
+
+func mapPort(container net.Addr, hostIP net.IP, hostPort int, ipv4Only bool) (host net.Addr, err error) {
 	lock.Lock()
 	defer lock.Unlock()
 
@@ -62,7 +97,7 @@ func Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err er
 			container: container,
 		}
 
-		proxy = NewProxy(proto, hostIP, allocatedHostPort, container.(*net.TCPAddr).IP, container.(*net.TCPAddr).Port)
+		proxy = newProxy(proto, hostIP, allocatedHostPort, container.(*net.TCPAddr).IP, container.(*net.TCPAddr).Port, ipv4Only)
 	case *net.UDPAddr:
 		proto = "udp"
 		if allocatedHostPort, err = portallocator.RequestPort(hostIP, proto, hostPort); err != nil {
@@ -75,7 +110,7 @@ func Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err er
 			container: container,
 		}
 
-		proxy = NewProxy(proto, hostIP, allocatedHostPort, container.(*net.UDPAddr).IP, container.(*net.UDPAddr).Port)
+		proxy = newProxy(proto, hostIP, allocatedHostPort, container.(*net.UDPAddr).IP, container.(*net.UDPAddr).Port, ipv4Only)
 	default:
 		return nil, ErrUnknownBackendAddressType
 	}
@@ -93,14 +128,17 @@ func Map(container net.Addr, hostIP net.IP, hostPort int) (host net.Addr, err er
 	}
 
 	containerIP, containerPort := getIPAndPort(m.container)
-	if err := forward(iptables.Append, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort); err != nil {
+	if !enableUserlandProxy && fw != nil && forwardFirewall(hostIP, containerIP) == nil {
+		return nil, ErrPortNotForwarded
+	}
+	if err := forward(firewall.Append, m.proto, hostIP, allocatedHostPort, containerIP, containerPort); err != nil {
 		return nil, err
 	}
 
 	cleanup := func() error {
-		// need to undo the iptables rules before we return
+		// need to undo the firewall rules before we return
 		proxy.Stop()
-		forward(iptables.Delete, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort)
+		forward(firewall.Delete, m.proto, hostIP, allocatedHostPort, containerIP, containerPort)
 		if err := portallocator.ReleasePort(hostIP, m.proto, allocatedHostPort); err != nil {
 			return err
 		}
@@ -135,8 +173,8 @@ func Unmap(host net.Addr) error {
 
 	containerIP, containerPort := getIPAndPort(data.container)
 	hostIP, hostPort := getIPAndPort(data.host)
-	if err := forward(iptables.Delete, data.proto, hostIP, hostPort, containerIP.String(), containerPort); err != nil {
-		log.Errorf("Error on iptables delete: %s", err)
+	if err := forward(firewall.Delete, data.proto, hostIP, hostPort, containerIP, containerPort); err != nil {
+		log.Errorf("Error on firewall delete: %s", err)
 	}
 
 	switch a := host.(type) {
@@ -168,9 +206,23 @@ func getIPAndPort(a net.Addr) (net.IP, int) {
 	return nil, 0
 }
 
-func forward(action iptables.Action, proto string, sourceIP net.IP, sourcePort int, containerIP string, containerPort int) error {
-	if chain == nil {
+// forwardFirewall returns the firewall forwarding the traffic from hostIP
+// to containerIP, if any: there is none across address families, the
+// userland proxy alone serves them.
+func forwardFirewall(hostIP, containerIP net.IP) *firewall.Firewall {
+	switch {
+	case (hostIP.To4() == nil) != (containerIP.To4() == nil):
+		return nil
+	case containerIP.To4() == nil:
+		return fw6
+	}
+	return fw
+}
+
+func forward(action firewall.Action, proto string, sourceIP net.IP, sourcePort int, containerIP net.IP, containerPort int) error {
+	f := forwardFirewall(sourceIP, containerIP)
+	if f == nil {
 		return nil
 	}
-	return chain.Forward(action, sourceIP, sourcePort, proto, containerIP, containerPort)
+	return f.Forward(action, proto, sourceIP, sourcePort, containerIP, containerPort)
 }
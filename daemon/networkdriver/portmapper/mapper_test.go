@@ -4,8 +4,8 @@ import (
 	"net"
 	"testing"
 
+	"github.com/docker/docker/daemon/networkdriver/firewall"
 	"github.com/docker/docker/daemon/networkdriver/portallocator"
-	"github.com/docker/docker/pkg/iptables"
 )
 
 func init() {
@@ -14,25 +14,27 @@ func init() {
 }
 
 func reset() {
-	chain = nil
+	fw = nil
+	fw6 = nil
+	enableUserlandProxy = true
 	currentMappings = make(map[string]*mapping)
 }
 
-func TestSetIptablesChain(t *testing.T) {
+func TestSetFirewall(t *testing.T) {
 	defer reset()
 
-	c := &iptables.Chain{
-		Name:   "TEST",
-		Bridge: "192.168.1.1",
+	f, err := firewall.New("iptables", "docker0", false)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	if chain != nil {
-		t.Fatal("chain should be nil at init")
+	if fw != nil {
+		t.Fatal("firewall should be nil at init")
 	}
 
-	SetIptablesChain(c)
-	if chain == nil {
-		t.Fatal("chain should not be nil after set")
+	SetFirewall(f)
+	if fw == nil {
+		t.Fatal("firewall should not be nil after set")
 	}
 }
 
@@ -81,6 +83,67 @@ func TestMapPorts(t *testing.T) {
 	}
 }
 
+func TestMapPortsWithoutUserlandProxy(t *testing.T) {
+	defer reset()
+	SetUserlandProxy(false)
+
+	hostIP := net.ParseIP("127.0.0.1")
+	srcAddr := &net.TCPAddr{Port: 1080, IP: net.ParseIP("172.16.0.1")}
+
+	host, err := Map(srcAddr, hostIP, 0)
+	if err != nil {
+		t.Fatalf("Failed to allocate port: %s", err)
+	}
+
+	// the dummy proxy keeps the host port bound
+	if l, err := net.Listen("tcp", host.String()); err == nil {
+		l.Close()
+		t.Fatalf("Port %s should be bound by the dummy proxy", host)
+	}
+
+	if err := Unmap(host); err != nil {
+		t.Fatalf("Failed to release port: %s", err)
+	}
+
+	l, err := net.Listen("tcp", host.String())
+	if err != nil {
+		t.Fatalf("Port %s should have been released: %s", host, err)
+	}
+	l.Close()
+}
+
+func TestForwardFirewall(t *testing.T) {
+	defer reset()
+
+	f, _ := firewall.New("iptables", "docker0", false)
+	f6, _ := firewall.New("iptables", "docker0", false)
+	SetFirewall(f)
+
+	ip4 := net.ParseIP("172.17.0.2")
+	ip6 := net.ParseIP("2001:db8::2")
+
+	if forwardFirewall(net.ParseIP("0.0.0.0"), ip4) != f {
+		t.Fatal("IPv4 mappings should be forwarded by the firewall")
+	}
+	if forwardFirewall(net.ParseIP("::"), ip6) != nil {
+		t.Fatal("IPv6 mappings should not be forwarded without an IPv6 firewall")
+	}
+	SetFirewall6(f6)
+	if forwardFirewall(net.ParseIP("::"), ip6) != f6 {
+		t.Fatal("IPv6 mappings should be forwarded by the IPv6 firewall")
+	}
+	if forwardFirewall(net.ParseIP("::"), ip4) != nil || forwardFirewall(net.ParseIP("0.0.0.0"), ip6) != nil {
+		t.Fatal("Mappings across address families should not be forwarded")
+	}
+
+	// only the userland proxy serves mappings across address families
+	SetUserlandProxy(false)
+	srcAddr := &net.TCPAddr{Port: 1080, IP: ip4}
+	if _, err := Map(srcAddr, net.ParseIP("::1"), 0); err != ErrPortNotForwarded {
+		t.Fatalf("Expected ErrPortNotForwarded, got %v", err)
+	}
+}
+
 func TestGetUDPKey(t *testing.T) {
 	addr := &net.UDPAddr{IP: net.ParseIP("192.168.1.5"), Port: 53}
 
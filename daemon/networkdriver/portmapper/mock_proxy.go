@@ -2,7 +2,7 @@ package portmapper
 
 import "net"
 
-func NewMockProxyCommand(proto string, hostIP net.IP, hostPort int, containerIP net.IP, containerPort int) UserlandProxy {
+func NewMockProxyCommand(proto string, hostIP net.IP, hostPort int, containerIP net.IP, containerPort int, ipv4Only bool) UserlandProxy {
 	return &mockProxyCommand{}
 }
 
@@ -3,6 +3,7 @@ package portmapper
 import (
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net"
@@ -37,9 +38,13 @@ type proxyCommand struct {
 // execProxy is the reexec function that is registered to start the userland proxies
 func execProxy() {
 	f := os.NewFile(3, "signal-parent")
-	host, container := parseHostContainerAddrs()
+	host, container, ipv4Only := parseHostContainerAddrs()
 
-	p, err := proxy.NewProxy(host, container)
+	newProxy := proxy.NewProxy
+	if ipv4Only {
+		newProxy = proxy.NewIPv4OnlyProxy
+	}
+	p, err := newProxy(host, container)
 	if err != nil {
 		fmt.Fprintf(f, "1\n%s", err)
 		f.Close()
@@ -55,13 +60,14 @@ func execProxy() {
 
 // parseHostContainerAddrs parses the flags passed on reexec to create the TCP or UDP
 // net.Addrs to map the host and container ports
-func parseHostContainerAddrs() (host net.Addr, container net.Addr) {
+func parseHostContainerAddrs() (host net.Addr, container net.Addr, ipv4Only bool) {
 	var (
 		proto         = flag.String("proto", "tcp", "proxy protocol")
 		hostIP        = flag.String("host-ip", "", "host ip")
 		hostPort      = flag.Int("host-port", -1, "host port")
 		containerIP   = flag.String("container-ip", "", "container ip")
 		containerPort = flag.Int("container-port", -1, "container port")
+		hostIPv4Only  = flag.Bool("ipv4-only", false, "leave the host port free on IPv6")
 	)
 
 	flag.Parse()
@@ -77,7 +83,7 @@ func parseHostContainerAddrs() (host net.Addr, container net.Addr) {
 		log.Fatalf("unsupported protocol %s", *proto)
 	}
 
-	return host, container
+	return host, container, *hostIPv4Only
 }
 
 func handleStopSignals(p proxy.Proxy) {
@@ -91,7 +97,7 @@ func handleStopSignals(p proxy.Proxy) {
 	}
 }
 
-func NewProxyCommand(proto string, hostIP net.IP, hostPort int, containerIP net.IP, containerPort int) UserlandProxy {
+func NewProxyCommand(proto string, hostIP net.IP, hostPort int, containerIP net.IP, containerPort int, ipv4Only bool) UserlandProxy {
 	args := []string{
 		userlandProxyCommandName,
 		"-proto", proto,
@@ -100,6 +106,9 @@ func NewProxyCommand(proto string, hostIP net.IP, hostPort int, containerIP net.
 		"-container-ip", containerIP.String(),
 		"-container-port", strconv.Itoa(containerPort),
 	}
+	if ipv4Only {
+		args = append(args, "-ipv4-only")
+	}
 
 	return &proxyCommand{
 		cmd: &exec.Cmd{
@@ -159,3 +168,49 @@ func (p *proxyCommand) Stop() error {
 	}
 	return nil
 }
+
+// dummyProxy keeps the host port of a mapping bound when the userland
+// proxy is disabled, so that no other process takes it while iptables
+// forwards its traffic to the container.
+type dummyProxy struct {
+	listener io.Closer
+	addr     net.Addr
+	ipv4Only bool
+}
+
+func NewDummyProxy(proto string, hostIP net.IP, hostPort int, ipv4Only bool) UserlandProxy {
+	switch proto {
+	case "tcp":
+		return &dummyProxy{addr: &net.TCPAddr{IP: hostIP, Port: hostPort}, ipv4Only: ipv4Only}
+	case "udp":
+		return &dummyProxy{addr: &net.UDPAddr{IP: hostIP, Port: hostPort}, ipv4Only: ipv4Only}
+	}
+	return &dummyProxy{}
+}
+
+func (p *dummyProxy) Start() error {
+	switch addr := p.addr.(type) {
+	case *net.TCPAddr:
+		l, err := net.ListenTCP(proxy.ListenNetwork("tcp", addr.IP, p.ipv4Only), addr)
+		if err != nil {
+			return err
+		}
+		p.listener = l
+	case *net.UDPAddr:
+		l, err := net.ListenUDP(proxy.ListenNetwork("udp", addr.IP, p.ipv4Only), addr)
+		if err != nil {
+			return err
+		}
+		p.listener = l
+	default:
+		return fmt.Errorf("Unknown addr type: %T", p.addr)
+	}
+	return nil
+}
+
+func (p *dummyProxy) Stop() error {
+	if p.listener != nil {
+		return p.listener.Close()
+	}
+	return nil
+}
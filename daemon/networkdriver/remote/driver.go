@@ -0,0 +1,144 @@
+// Package remote calls the network drivers running out of the daemon, the
+// plugins implementing the NetworkDriver subsystem of the plugin API.
+//
+// A container whose network mode names such a plugin gets its endpoint,
+// its addresses and its interface from the plugin, which also publishes
+// its ports.
+package remote
+
+import (
+	"errors"
+
+	"github.com/docker/docker/pkg/plugins"
+)
+
+type pluginClient interface {
+	// Call calls the specified method with the specified arguments for the plugin.
+	Call(string, interface{}, interface{}) error
+}
+
+// Driver is a network driver plugin, each method is a
+// NetworkDriver.<Method> call of the plugin API.
+type Driver struct {
+	name   string
+	client pluginClient
+}
+
+// Endpoint is the network configuration of a container on the network of
+// a driver.
+type Endpoint struct {
+	IPAddress           string
+	IPPrefixLen         int
+	MacAddress          string
+	Gateway             string `json:",omitempty"`
+	GlobalIPv6Address   string `json:",omitempty"`
+	GlobalIPv6PrefixLen int    `json:",omitempty"`
+	IPv6Gateway         string `json:",omitempty"`
+}
+
+type driverRequest struct {
+	ContainerID   string
+	RequestedIP   string `json:",omitempty"`
+	RequestedIPv6 string `json:",omitempty"`
+	RequestedMac  string `json:",omitempty"`
+	Proto         string `json:",omitempty"`
+	HostIP        string `json:",omitempty"`
+	HostPort      int    `json:",omitempty"`
+	ContainerPort int    `json:",omitempty"`
+}
+
+type driverResponse struct {
+	Err string `json:",omitempty"`
+	Endpoint
+	InterfaceName string `json:",omitempty"`
+	HostIP        string `json:",omitempty"`
+	HostPort      int    `json:",omitempty"`
+}
+
+// Lookup returns the network driver plugin name, if there is one on the
+// host.
+func Lookup(name string) (*Driver, error) {
+	pl, err := plugins.Get(name, "NetworkDriver")
+	if err != nil {
+		return nil, err
+	}
+	return &Driver{name, pl.Client}, nil
+}
+
+func (d *Driver) String() string {
+	return d.name
+}
+
+func (d *Driver) call(method string, args *driverRequest) (*driverResponse, error) {
+	var ret driverResponse
+	if err := d.client.Call("NetworkDriver."+method, args, &ret); err != nil {
+		return nil, err
+	}
+	if ret.Err != "" {
+		return nil, errors.New(ret.Err)
+	}
+	return &ret, nil
+}
+
+// CreateEndpoint creates the endpoint of the container id on the network
+// of the driver. The requested addresses may be empty, for the driver to
+// pick them.
+func (d *Driver) CreateEndpoint(id, requestedIP, requestedIPv6, requestedMac string) (*Endpoint, error) {
+	ret, err := d.call("CreateEndpoint", &driverRequest{
+		ContainerID:   id,
+		RequestedIP:   requestedIP,
+		RequestedIPv6: requestedIPv6,
+		RequestedMac:  requestedMac,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ret.IPAddress == "" {
+		return nil, errors.New("The network driver returned no IP address")
+	}
+	return &ret.Endpoint, nil
+}
+
+// DeleteEndpoint deletes the endpoint of the container id, along with the
+// ports published for it.
+func (d *Driver) DeleteEndpoint(id string) error {
+	_, err := d.call("DeleteEndpoint", &driverRequest{ContainerID: id})
+	return err
+}
+
+// Join returns the name of the host interface to move inside the network
+// namespace of the container id, as its eth0.
+func (d *Driver) Join(id string) (string, error) {
+	ret, err := d.call("Join", &driverRequest{ContainerID: id})
+	if err != nil {
+		return "", err
+	}
+	if ret.InterfaceName == "" {
+		return "", errors.New("The network driver returned no interface name")
+	}
+	return ret.InterfaceName, nil
+}
+
+// Leave is called once the container id stopped, its interface is no
+// longer in use.
+func (d *Driver) Leave(id string) error {
+	_, err := d.call("Leave", &driverRequest{ContainerID: id})
+	return err
+}
+
+// PublishPort publishes the port containerPort of the container id on the
+// host, and returns the host address and port it is published on. hostIP
+// and hostPort may be empty, for the driver to pick them.
+func (d *Driver) PublishPort(id, proto, hostIP string, hostPort, containerPort int) (string, int, error) {
+	ret, err := d.call("PublishPort", &driverRequest{
+		ContainerID:   id,
+		Proto:         proto,
+		HostIP:        hostIP,
+		HostPort:      hostPort,
+		ContainerPort: containerPort,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return ret.HostIP, ret.HostPort, nil
+}
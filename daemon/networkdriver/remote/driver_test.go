@@ -0,0 +1,119 @@
+package remote_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/daemon/networkdriver/remote"
+	"github.com/docker/docker/daemon/networkdriver/remote/remotetest"
+	"github.com/docker/docker/pkg/plugins"
+)
+
+// newDriver serves a fake driver as the plugin name. Plugins are kept once
+// activated, each test uses its own name.
+func newDriver(t *testing.T, name string) (*remote.Driver, *remotetest.FakeDriver, func()) {
+	dir, err := ioutil.TempDir("", "docker-network-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugins.SocketsPath = dir
+
+	fake, err := remotetest.NewFakeDriver("10.3.0.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := fake.Listen(dir, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := remote.Lookup(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, fake, func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	d, fake, cleanup := newDriver(t, "fake-endpoint")
+	defer cleanup()
+
+	ep, err := d.CreateEndpoint("c1", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep.IPAddress != "10.3.0.2" || ep.IPPrefixLen != 24 || ep.Gateway != "10.3.0.1" {
+		t.Fatalf("Expected the first address of the subnet, got %+v", ep)
+	}
+	if ep.MacAddress != "02:42:0a:03:00:02" {
+		t.Fatalf("Expected a MAC address generated from the IP, got %s", ep.MacAddress)
+	}
+
+	ep, err = d.CreateEndpoint("c2", "10.3.0.100", "", "02:42:00:00:00:01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep.IPAddress != "10.3.0.100" || ep.MacAddress != "02:42:00:00:00:01" {
+		t.Fatalf("Expected the requested addresses, got %+v", ep)
+	}
+	if _, err := d.CreateEndpoint("c3", "10.3.0.100", "", ""); err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Fatalf("Expected the error of the driver for an address in use, got %v", err)
+	}
+
+	if err := d.DeleteEndpoint("c2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.Endpoint("c2"); ok {
+		t.Fatal("Expected the endpoint to be deleted")
+	}
+	if err := d.DeleteEndpoint("c2"); err == nil {
+		t.Fatal("Deleted the endpoint of a container twice")
+	}
+	d.DeleteEndpoint("c1")
+}
+
+func TestJoinAndPublishPort(t *testing.T) {
+	d, fake, cleanup := newDriver(t, "fake-join")
+	defer cleanup()
+
+	if _, err := d.Join("c1"); err == nil {
+		t.Fatal("Joined a container without endpoint")
+	}
+	if _, err := d.CreateEndpoint("c1", "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	defer d.DeleteEndpoint("c1")
+
+	fake.NewInterface = func(id string) (string, error) {
+		return "veth" + id, nil
+	}
+	name, err := d.Join("c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "vethc1" {
+		t.Fatalf("Expected the interface of the driver, got %s", name)
+	}
+
+	hostIP, hostPort, err := d.PublishPort("c1", "tcp", "", 0, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostIP != "0.0.0.0" || hostPort == 0 {
+		t.Fatalf("Expected the driver to pick the host port, got %s:%d", hostIP, hostPort)
+	}
+	if _, hostPort, err = d.PublishPort("c1", "udp", "127.0.0.1", 5353, 53); err != nil || hostPort != 5353 {
+		t.Fatalf("Expected the requested host port, got %d, %v", hostPort, err)
+	}
+	if ports := fake.Ports("c1"); len(ports) != 2 || ports[1] != "udp/127.0.0.1:5353->53" {
+		t.Fatalf("Expected the ports to be published, got %v", ports)
+	}
+
+	if err := d.Leave("c1"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,254 @@
+// Package remotetest provides a fake network driver plugin, to test the
+// remote network drivers and the daemon without a real network plugin.
+package remotetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+
+	"github.com/docker/docker/daemon/networkdriver/remote"
+)
+
+// firstHostPort is the first host port handed out by the fake driver when
+// none is requested
+const firstHostPort = 49153
+
+// FakeDriver is a network driver plugin handing out the addresses of a
+// subnet. It publishes ports by recording them only.
+type FakeDriver struct {
+	// NewInterface creates the host interface of the container id when
+	// it joins the network, and returns its name. When nil, Join returns
+	// the name of an interface which doesn't exist.
+	NewInterface func(id string) (string, error)
+
+	network *net.IPNet
+	gateway net.IP
+	mux     *http.ServeMux
+
+	mu        sync.Mutex
+	endpoints map[string]*endpoint
+	nextPort  int
+}
+
+type endpoint struct {
+	remote.Endpoint
+	iface string
+	ports []string
+}
+
+type request struct {
+	ContainerID   string
+	RequestedIP   string
+	RequestedIPv6 string
+	RequestedMac  string
+	Proto         string
+	HostIP        string
+	HostPort      int
+	ContainerPort int
+}
+
+// NewFakeDriver returns a driver handing out the addresses of subnet, whose
+// first address is the gateway.
+func NewFakeDriver(subnet string) (*FakeDriver, error) {
+	_, network, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return nil, err
+	}
+	if network.IP.To4() == nil {
+		return nil, fmt.Errorf("%s is not an IPv4 subnet", subnet)
+	}
+	d := &FakeDriver{
+		network:   network,
+		gateway:   nextIP(network.IP),
+		mux:       http.NewServeMux(),
+		endpoints: make(map[string]*endpoint),
+		nextPort:  firstHostPort,
+	}
+
+	d.mux.HandleFunc("/Plugin.Activate", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, map[string]interface{}{"Implements": []string{"NetworkDriver"}}, nil)
+	})
+	d.handle("CreateEndpoint", d.createEndpoint)
+	d.handle("DeleteEndpoint", d.deleteEndpoint)
+	d.handle("Join", d.join)
+	d.handle("Leave", d.leave)
+	d.handle("PublishPort", d.publishPort)
+	return d, nil
+}
+
+// Listen serves the driver as the plugin name, on a unix socket in dir,
+// usually plugins.SocketsPath.
+func (d *FakeDriver) Listen(dir, name string) (net.Listener, error) {
+	l, err := net.Listen("unix", filepath.Join(dir, name+".sock"))
+	if err != nil {
+		return nil, err
+	}
+	go http.Serve(l, d)
+	return l, nil
+}
+
+func (d *FakeDriver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mux.ServeHTTP(w, r)
+}
+
+// Endpoint returns the endpoint of the container id, if it has one.
+func (d *FakeDriver) Endpoint(id string) (*remote.Endpoint, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ep, ok := d.endpoints[id]
+	if !ok {
+		return nil, false
+	}
+	e := ep.Endpoint
+	return &e, true
+}
+
+// Ports returns the ports published for the container id, as
+// proto/hostIP:hostPort->containerPort.
+func (d *FakeDriver) Ports(id string) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ep, ok := d.endpoints[id]; ok {
+		return append([]string(nil), ep.ports...)
+	}
+	return nil
+}
+
+func respond(w http.ResponseWriter, ret interface{}, err error) {
+	w.Header().Set("Content-Type", "application/vnd.docker.plugins.v1+json")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		ret = map[string]interface{}{"Err": err.Error()}
+	}
+	json.NewEncoder(w).Encode(ret)
+}
+
+func (d *FakeDriver) handle(method string, h func(req *request) (interface{}, error)) {
+	d.mux.HandleFunc("/NetworkDriver."+method, func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respond(w, nil, err)
+			return
+		}
+		d.mu.Lock()
+		ret, err := h(&req)
+		d.mu.Unlock()
+		respond(w, ret, err)
+	})
+}
+
+func (d *FakeDriver) createEndpoint(req *request) (interface{}, error) {
+	if _, ok := d.endpoints[req.ContainerID]; ok {
+		return nil, fmt.Errorf("%s already has an endpoint", req.ContainerID)
+	}
+	if req.RequestedIPv6 != "" {
+		return nil, fmt.Errorf("IPv6 is not supported")
+	}
+
+	var ip net.IP
+	if req.RequestedIP != "" {
+		if ip = net.ParseIP(req.RequestedIP); ip == nil || !d.network.Contains(ip) {
+			return nil, fmt.Errorf("%s is not an address of %s", req.RequestedIP, d.network)
+		}
+		if d.inUse(ip) {
+			return nil, fmt.Errorf("%s is already in use", ip)
+		}
+	} else {
+		for ip = nextIP(d.gateway); d.inUse(ip); ip = nextIP(ip) {
+		}
+		if !d.network.Contains(ip) {
+			return nil, fmt.Errorf("No address left in %s", d.network)
+		}
+	}
+
+	mac := req.RequestedMac
+	if mac == "" {
+		ip4 := ip.To4()
+		mac = net.HardwareAddr{0x02, 0x42, ip4[0], ip4[1], ip4[2], ip4[3]}.String()
+	}
+	size, _ := d.network.Mask.Size()
+	ep := &endpoint{Endpoint: remote.Endpoint{
+		IPAddress:   ip.String(),
+		IPPrefixLen: size,
+		MacAddress:  mac,
+		Gateway:     d.gateway.String(),
+	}}
+	d.endpoints[req.ContainerID] = ep
+	return ep.Endpoint, nil
+}
+
+func (d *FakeDriver) inUse(ip net.IP) bool {
+	if ip.Equal(d.gateway) {
+		return true
+	}
+	for _, ep := range d.endpoints {
+		if ep.IPAddress == ip.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *FakeDriver) deleteEndpoint(req *request) (interface{}, error) {
+	if _, ok := d.endpoints[req.ContainerID]; !ok {
+		return nil, fmt.Errorf("%s has no endpoint", req.ContainerID)
+	}
+	delete(d.endpoints, req.ContainerID)
+	return nil, nil
+}
+
+func (d *FakeDriver) join(req *request) (interface{}, error) {
+	ep, ok := d.endpoints[req.ContainerID]
+	if !ok {
+		return nil, fmt.Errorf("%s has no endpoint", req.ContainerID)
+	}
+	ep.iface = fmt.Sprintf("fake%.7s", req.ContainerID)
+	if d.NewInterface != nil {
+		name, err := d.NewInterface(req.ContainerID)
+		if err != nil {
+			return nil, err
+		}
+		ep.iface = name
+	}
+	return map[string]string{"InterfaceName": ep.iface}, nil
+}
+
+func (d *FakeDriver) leave(req *request) (interface{}, error) {
+	ep, ok := d.endpoints[req.ContainerID]
+	if !ok {
+		return nil, fmt.Errorf("%s has no endpoint", req.ContainerID)
+	}
+	ep.iface = ""
+	return nil, nil
+}
+
+func (d *FakeDriver) publishPort(req *request) (interface{}, error) {
+	ep, ok := d.endpoints[req.ContainerID]
+	if !ok {
+		return nil, fmt.Errorf("%s has no endpoint", req.ContainerID)
+	}
+	if req.HostIP == "" {
+		req.HostIP = "0.0.0.0"
+	}
+	if req.HostPort == 0 {
+		req.HostPort = d.nextPort
+		d.nextPort++
+	}
+	ep.ports = append(ep.ports, fmt.Sprintf("%s/%s:%d->%d", req.Proto, req.HostIP, req.HostPort, req.ContainerPort))
+	return map[string]interface{}{"HostIP": req.HostIP, "HostPort": req.HostPort}, nil
+}
+
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip.To4()))
+	copy(next, ip.To4())
+	for i := len(next) - 1; i >= 0; i-- {
+		if next[i]++; next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
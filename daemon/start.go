@@ -3,9 +3,11 @@ package daemon
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/docker/docker/engine"
+	"github.com/docker/docker/pkg/mount"
 	"github.com/docker/docker/runconfig"
 )
 
@@ -50,19 +52,37 @@ func (daemon *Daemon) ContainerStart(job *engine.Job) engine.Status {
 func (daemon *Daemon) setHostConfig(container *Container, hostConfig *runconfig.HostConfig) error {
 	container.Lock()
 	defer container.Unlock()
+	if hostConfig.Privileged && daemon.uidMaps != nil {
+		return fmt.Errorf("Privileged mode is incompatible with user namespaces")
+	}
+	if hostConfig.Init != nil && *hostConfig.Init && !strings.HasPrefix(daemon.ExecutionDriver().Name(), "native") {
+		return fmt.Errorf("The container init is only supported by the native execdriver")
+	}
 	if err := parseSecurityOpt(container, hostConfig); err != nil {
 		return err
 	}
+	if err := verifyTmpfs(hostConfig); err != nil {
+		return err
+	}
 
 	// FIXME: this should be handled by the volume subsystem
 	// Validate the HostConfig binds. Make sure that:
 	// the source exists
+	// the mount propagation is supported by the execdriver
 	for _, bind := range hostConfig.Binds {
 		splitBind := strings.Split(bind, ":")
 		source := splitBind[0]
 
+		_, _, _, propagation, err := parseBindMountSpec(bind)
+		if err != nil {
+			return err
+		}
+		if propagation != "" && !strings.HasPrefix(daemon.ExecutionDriver().Name(), "native") {
+			return fmt.Errorf("Mount propagation is only supported by the native execdriver")
+		}
+
 		// ensure the source exists on the host
-		_, err := os.Stat(source)
+		_, err = os.Stat(source)
 		if err != nil && os.IsNotExist(err) {
 			err = os.MkdirAll(source, 0755)
 			if err != nil {
@@ -80,3 +100,31 @@ func (daemon *Daemon) setHostConfig(container *Container, hostConfig *runconfig.
 
 	return nil
 }
+
+// verifyTmpfs checks that the tmpfs mounts have valid options, and do not
+// take the place of the root filesystem or of a bind mount.
+func verifyTmpfs(hostConfig *runconfig.HostConfig) error {
+	paths := make(map[string]bool, len(hostConfig.Tmpfs))
+	for path, options := range hostConfig.Tmpfs {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("Invalid tmpfs mount %s: path must be absolute", path)
+		}
+		if filepath.Clean(path) == "/" {
+			return fmt.Errorf("Invalid tmpfs mount %s: path can't be '/'", path)
+		}
+		if _, _, err := mount.ParseTmpfsOptions(options); err != nil {
+			return err
+		}
+		paths[filepath.Clean(path)] = true
+	}
+	for _, bind := range hostConfig.Binds {
+		_, mountToPath, _, _, err := parseBindMountSpec(bind)
+		if err != nil {
+			return err
+		}
+		if paths[filepath.Clean(mountToPath)] {
+			return fmt.Errorf("Duplicate mount point %s: used by both a volume and a tmpfs", mountToPath)
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package daemon
 import (
 	"encoding/json"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/daemon/execdriver"
 	"github.com/docker/docker/engine"
@@ -11,6 +12,10 @@ import (
 )
 
 func (daemon *Daemon) ContainerStats(job *engine.Job) engine.Status {
+	container, err := daemon.Get(job.Args[0])
+	if err != nil {
+		return job.Error(err)
+	}
 	updates, err := daemon.SubscribeToContainerStats(job.Args[0])
 	if err != nil {
 		return job.Error(err)
@@ -22,6 +27,18 @@ func (daemon *Daemon) ContainerStats(job *engine.Job) engine.Status {
 		ss.MemoryStats.Limit = uint64(update.MemoryLimit)
 		ss.Read = update.Read
 		ss.CpuStats.SystemUsage = update.SystemUsage
+		container.Lock()
+		shaping, err := container.networkShaping()
+		container.Unlock()
+		if err != nil {
+			log.Errorf("%v: Failed to get the network shaping: %s", container.ID, err)
+		}
+		if shaping != nil {
+			ss.Network.IngressRate = shaping.IngressRate
+			ss.Network.EgressRate = shaping.EgressRate
+			ss.Network.IngressDropped = shaping.IngressDropped
+			ss.Network.EgressDropped = shaping.EgressDropped
+		}
 		if err := enc.Encode(ss); err != nil {
 			// TODO: handle the specific broken pipe
 			daemon.UnsubscribeToContainerStats(job.Args[0], updates)
@@ -80,6 +97,10 @@ func convertToAPITypes(ls *libcontainer.ContainerStats) *types.Stats {
 			Stats:    mem.Stats,
 			Failcnt:  mem.Failcnt,
 		}
+		s.PidsStats = types.PidsStats{
+			Current: cs.PidsStats.Current,
+			Limit:   cs.PidsStats.Limit,
+		}
 	}
 	return s
 }
@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"github.com/docker/docker/engine"
+)
+
+// ContainerUpdate changes the network rates of a container, and shapes the
+// traffic of the container to them at once if it is running.
+func (daemon *Daemon) ContainerUpdate(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("usage: %s NAME", job.Name)
+	}
+	container, err := daemon.Get(job.Args[0])
+	if err != nil {
+		return job.Error(err)
+	}
+
+	container.Lock()
+	defer container.Unlock()
+
+	hostConfig := container.hostConfig
+	ingressRate, egressRate := hostConfig.NetworkIngressRate, hostConfig.NetworkEgressRate
+	if job.EnvExists("NetworkIngressRate") {
+		ingressRate = job.GetenvInt64("NetworkIngressRate")
+	}
+	if job.EnvExists("NetworkEgressRate") {
+		egressRate = job.GetenvInt64("NetworkEgressRate")
+	}
+	if ingressRate < 0 || egressRate < 0 {
+		return job.Errorf("Network rates can't be negative")
+	}
+	if mode := hostConfig.NetworkMode; (ingressRate != 0 || egressRate != 0) && mode != "" && mode != "bridge" {
+		return job.Errorf("Network rates require the bridge network mode, %s uses %s", container.Name, mode)
+	}
+
+	oldIngressRate, oldEgressRate := hostConfig.NetworkIngressRate, hostConfig.NetworkEgressRate
+	hostConfig.NetworkIngressRate, hostConfig.NetworkEgressRate = ingressRate, egressRate
+	if container.Running {
+		if err := container.setNetworkRate(); err != nil {
+			hostConfig.NetworkIngressRate, hostConfig.NetworkEgressRate = oldIngressRate, oldEgressRate
+			return job.Errorf("Cannot update container %s: %s", container.Name, err)
+		}
+	}
+	if err := container.WriteHostConfig(); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
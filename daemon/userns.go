@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/libcontainer/user"
+)
+
+// parseRemappedRoot splits the --userns-remap value into a user and a group
+// name. Numeric ids are resolved to names, as /etc/subuid and /etc/subgid are
+// keyed by name. Without a group, the user name is used for the group too.
+func parseRemappedRoot(usergrp string) (string, string, error) {
+	var (
+		userID, groupID     int
+		username, groupname string
+	)
+
+	idparts := strings.Split(usergrp, ":")
+	if len(idparts) > 2 {
+		return "", "", fmt.Errorf("Invalid user/group specification in --userns-remap: %q", usergrp)
+	}
+
+	if uid, err := strconv.ParseInt(idparts[0], 10, 32); err == nil {
+		// must be a uid; take it as valid
+		userID = int(uid)
+		luser, err := user.LookupUid(userID)
+		if err != nil {
+			return "", "", fmt.Errorf("Uid %d has no entry in /etc/passwd: %v", userID, err)
+		}
+		username = luser.Name
+	} else {
+		if _, err := user.LookupUser(idparts[0]); err != nil {
+			return "", "", fmt.Errorf("User %q has no entry in /etc/passwd: %v", idparts[0], err)
+		}
+		username = idparts[0]
+	}
+
+	groupname = username
+	if len(idparts) == 2 {
+		if gid, err := strconv.ParseInt(idparts[1], 10, 32); err == nil {
+			groupID = int(gid)
+			lgrp, err := user.LookupGid(groupID)
+			if err != nil {
+				return "", "", fmt.Errorf("Gid %d has no entry in /etc/group: %v", groupID, err)
+			}
+			groupname = lgrp.Name
+		} else {
+			if _, err := user.LookupGroup(idparts[1]); err != nil {
+				return "", "", fmt.Errorf("Group %q has no entry in /etc/group: %v", idparts[1], err)
+			}
+			groupname = idparts[1]
+		}
+	}
+	return username, groupname, nil
+}
+
+// setupRemappedRoot returns the uid and gid maps for the user namespaces of
+// containers, or nil maps if --userns-remap is not set.
+func setupRemappedRoot(config *Config) ([]idtools.IDMap, []idtools.IDMap, error) {
+	if config.RemappedRoot == "" {
+		return nil, nil, nil
+	}
+	if config.ExecDriver != "native" {
+		return nil, nil, fmt.Errorf("User namespaces are only supported by the native execdriver")
+	}
+
+	username, groupname, err := parseRemappedRoot(config.RemappedRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+	if username == "root" {
+		// root to root "remapping" is the same as not remapping at all
+		log.Warnf("User namespaces: root cannot be remapped with itself; user namespaces are OFF")
+		return nil, nil, nil
+	}
+	log.Infof("User namespaces: ID ranges will be mapped to subuid/subgid ranges of: %s:%s", username, groupname)
+
+	uidMaps, gidMaps, err := idtools.CreateIDMappings(username, groupname)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Can't create ID mappings: %v", err)
+	}
+	return uidMaps, gidMaps, nil
+}
+
+// setupDaemonRoot creates the daemon root directory. With a remapped root,
+// the data of the daemon lives in a "<uid>.<gid>" subdirectory owned by the
+// remapped root, so that images and containers of different mappings do not
+// mix, and config.Root is updated to point there.
+func setupDaemonRoot(config *Config, rootUID, rootGID int) error {
+	// the daemon root must be traversable by the remapped root
+	perms := os.FileMode(0700)
+	if rootUID != 0 || rootGID != 0 {
+		perms = 0701
+	}
+
+	if err := os.MkdirAll(config.Root, perms); err != nil && !os.IsExist(err) {
+		return err
+	}
+	if err := os.Chmod(config.Root, perms); err != nil {
+		return err
+	}
+
+	if rootUID == 0 && rootGID == 0 {
+		return nil
+	}
+
+	config.Root = filepath.Join(config.Root, fmt.Sprintf("%d.%d", rootUID, rootGID))
+	log.Debugf("Creating user namespaced daemon root: %s", config.Root)
+	if err := idtools.MkdirAllAs(config.Root, 0700, rootUID, rootGID); err != nil {
+		return fmt.Errorf("Cannot create daemon root %q: %v", config.Root, err)
+	}
+	return nil
+}
+
+// GetUIDGIDMaps returns the user namespace mappings of the daemon, which are
+// nil unless the daemon runs with --userns-remap.
+func (daemon *Daemon) GetUIDGIDMaps() ([]idtools.IDMap, []idtools.IDMap) {
+	return daemon.uidMaps, daemon.gidMaps
+}
+
+// GetRemappedUIDGID returns the host uid and gid that root in a container
+// maps to, which is 0/0 without --userns-remap.
+func (daemon *Daemon) GetRemappedUIDGID() (int, int, error) {
+	return idtools.GetRootUIDGID(daemon.uidMaps, daemon.gidMaps)
+}
@@ -13,6 +13,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/docker/daemon/execdriver"
 	"github.com/docker/docker/pkg/chrootarchive"
+	"github.com/docker/docker/pkg/mount"
 	"github.com/docker/docker/pkg/symlink"
 	"github.com/docker/docker/volumes"
 )
@@ -47,10 +48,15 @@ func (container *Container) prepareVolumes() error {
 	return container.createVolumes()
 }
 
-// sortedVolumeMounts returns the list of container volume mount points sorted in lexicographic order
+// sortedVolumeMounts returns the list of container volume and tmpfs mount points sorted in lexicographic order
 func (container *Container) sortedVolumeMounts() []string {
 	var mountPaths []string
 	for path := range container.Volumes {
+		if _, exists := container.hostConfig.Tmpfs[path]; !exists {
+			mountPaths = append(mountPaths, path)
+		}
+	}
+	for path := range container.hostConfig.Tmpfs {
 		mountPaths = append(mountPaths, path)
 	}
 
@@ -154,7 +160,7 @@ func (container *Container) parseVolumeMountConfig() (map[string]*Mount, error)
 	var mounts = make(map[string]*Mount)
 	// Get all the bind mounts
 	for _, spec := range container.hostConfig.Binds {
-		path, mountToPath, writable, err := parseBindMountSpec(spec)
+		path, mountToPath, writable, _, err := parseBindMountSpec(spec)
 		if err != nil {
 			return nil, err
 		}
@@ -188,6 +194,11 @@ func (container *Container) parseVolumeMountConfig() (map[string]*Mount, error)
 			continue
 		}
 
+		// A tmpfs is mounted there instead
+		if _, exists := container.hostConfig.Tmpfs[path]; exists {
+			continue
+		}
+
 		if stat, err := os.Stat(filepath.Join(container.basefs, path)); err == nil {
 			if !stat.IsDir() {
 				return nil, fmt.Errorf("file exists at %s, can't create volume there")
@@ -210,11 +221,15 @@ func (container *Container) parseVolumeMountConfig() (map[string]*Mount, error)
 	return mounts, nil
 }
 
-func parseBindMountSpec(spec string) (string, string, bool, error) {
+// parseBindMountSpec parses a bind mount of the form
+// host-dir:container-dir[:mode], where mode is rw or ro, a mount propagation
+// mode, or both separated by a comma. An unknown mode makes the mount read
+// only, as it always did.
+func parseBindMountSpec(spec string) (string, string, bool, string, error) {
 	var (
-		path, mountToPath string
-		writable          bool
-		arr               = strings.Split(spec, ":")
+		path, mountToPath, propagation string
+		writable                       bool
+		arr                            = strings.Split(spec, ":")
 	)
 
 	switch len(arr) {
@@ -225,18 +240,33 @@ func parseBindMountSpec(spec string) (string, string, bool, error) {
 	case 3:
 		path = arr[0]
 		mountToPath = arr[1]
-		writable = validMountMode(arr[2]) && arr[2] == "rw"
+		writable = true
+		unknown := false
+		for _, mode := range strings.Split(arr[2], ",") {
+			switch {
+			case validMountMode(mode):
+				writable = mode == "rw"
+			case validPropagationMode(mode):
+				if propagation != "" {
+					return "", "", false, "", fmt.Errorf("Invalid volume specification: %s", spec)
+				}
+				propagation = mode
+			default:
+				unknown = true
+			}
+		}
+		writable = writable && !unknown
 	default:
-		return "", "", false, fmt.Errorf("Invalid volume specification: %s", spec)
+		return "", "", false, "", fmt.Errorf("Invalid volume specification: %s", spec)
 	}
 
 	if !filepath.IsAbs(path) {
-		return "", "", false, fmt.Errorf("cannot bind mount volume: %s volume paths must be absolute.", path)
+		return "", "", false, "", fmt.Errorf("cannot bind mount volume: %s volume paths must be absolute.", path)
 	}
 
 	path = filepath.Clean(path)
 	mountToPath = filepath.Clean(mountToPath)
-	return path, mountToPath, writable, nil
+	return path, mountToPath, writable, propagation, nil
 }
 
 func parseVolumesFromSpec(spec string) (string, string, error) {
@@ -315,19 +345,94 @@ func validMountMode(mode string) bool {
 	return validModes[mode]
 }
 
+func validPropagationMode(mode string) bool {
+	validModes := map[string]bool{
+		"private":  true,
+		"rprivate": true,
+		"slave":    true,
+		"rslave":   true,
+		"shared":   true,
+		"rshared":  true,
+	}
+
+	return validModes[mode]
+}
+
+// bindPropagation returns the propagation modes of the bind mounts by their
+// path in the container, after checking that the mount of their source can
+// propagate mounts: shared modes need a shared mount, and slave modes a
+// shared or slave one.
+func (container *Container) bindPropagation() (map[string]string, error) {
+	propagation := make(map[string]string)
+	for _, spec := range container.hostConfig.Binds {
+		path, mountToPath, _, mode, err := parseBindMountSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		if mode == "" {
+			continue
+		}
+		if strings.HasSuffix(mode, "shared") || strings.HasSuffix(mode, "slave") {
+			if err := checkSourcePropagation(path, mode); err != nil {
+				return nil, err
+			}
+		}
+		propagation[mountToPath] = mode
+	}
+	return propagation, nil
+}
+
+func checkSourcePropagation(path, mode string) error {
+	source, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	sourceMount, err := mount.GetSourceMount(source)
+	if err != nil {
+		return err
+	}
+	if sourceMount.IsShared() {
+		return nil
+	}
+	if strings.HasSuffix(mode, "slave") && sourceMount.IsSlave() {
+		return nil
+	}
+	if strings.HasSuffix(mode, "shared") {
+		return fmt.Errorf("Cannot mount %s as %s: %s is not a shared mount, run `mount --make-shared %s` first", path, mode, sourceMount.Mountpoint, sourceMount.Mountpoint)
+	}
+	return fmt.Errorf("Cannot mount %s as %s: %s is neither a shared nor a slave mount, run `mount --make-shared %s` first", path, mode, sourceMount.Mountpoint, sourceMount.Mountpoint)
+}
+
 func (container *Container) setupMounts() error {
 	mounts := []execdriver.Mount{}
 
+	propagation, err := container.bindPropagation()
+	if err != nil {
+		return err
+	}
+
 	// Mount user specified volumes
 	// Note, these are not private because you may want propagation of (un)mounts from host
 	// volumes. For instance if you use -v /usr:/usr and the host later mounts /usr/share you
 	// want this new mount in the container
 	// These mounts must be ordered based on the length of the path that it is being mounted to (lexicographic)
+	// Tmpfs mounts are ordered along with them, and take the place of the
+	// volumes of the image at the same path
 	for _, path := range container.sortedVolumeMounts() {
+		if options, exists := container.hostConfig.Tmpfs[path]; exists {
+			mounts = append(mounts, execdriver.Mount{
+				Type:        "tmpfs",
+				Destination: path,
+				Data:        options,
+				Writable:    true,
+			})
+			continue
+		}
 		mounts = append(mounts, execdriver.Mount{
 			Source:      container.Volumes[path],
 			Destination: path,
 			Writable:    container.VolumesRW[path],
+			Propagation: propagation[path],
 		})
 	}
 
@@ -0,0 +1,34 @@
+package daemon
+
+import "testing"
+
+func TestParseBindMountSpec(t *testing.T) {
+	for _, c := range []struct {
+		spec, path, mountToPath string
+		writable                bool
+		propagation             string
+	}{
+		{"/host:/container", "/host", "/container", true, ""},
+		{"/host:/container:ro", "/host", "/container", false, ""},
+		{"/host/:/container/:rw", "/host", "/container", true, ""},
+		{"/host:/container:rshared", "/host", "/container", true, "rshared"},
+		{"/host:/container:ro,slave", "/host", "/container", false, "slave"},
+		{"/host:/container:rprivate,rw", "/host", "/container", true, "rprivate"},
+		{"/host:/container:bogus", "/host", "/container", false, ""},
+		{"/host:/container:bogus,rw", "/host", "/container", false, ""},
+	} {
+		path, mountToPath, writable, propagation, err := parseBindMountSpec(c.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", c.spec, err)
+		}
+		if path != c.path || mountToPath != c.mountToPath || writable != c.writable || propagation != c.propagation {
+			t.Fatalf("%s: unexpected %s %s %v %q", c.spec, path, mountToPath, writable, propagation)
+		}
+	}
+
+	for _, spec := range []string{"/host", "host:/container", "/host:/container:rw:ro", "/host:/container:shared,slave"} {
+		if _, _, _, _, err := parseBindMountSpec(spec); err == nil {
+			t.Fatalf("Expected an error for %s", spec)
+		}
+	}
+}
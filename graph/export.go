@@ -15,14 +15,22 @@ import (
 )
 
 // CmdImageExport exports all images with the given tag. All versions
-// containing the same tag are exported. The resulting output is an
-// uncompressed tar ball.
+// containing the same tag are exported. The resulting output is a tar
+// ball, uncompressed unless the compress env names a compression, in the
+// format of docker, or of an OCI image layout if the format env is oci.
 // name is the set of tags to export.
 // out is the writer where the images are written to.
 func (s *TagStore) CmdImageExport(job *engine.Job) engine.Status {
 	if len(job.Args) < 1 {
 		return job.Errorf("Usage: %s IMAGE [IMAGE...]\n", job.Name)
 	}
+	compression := archive.Uncompressed
+	if c := job.Getenv("compress"); c != "" {
+		var err error
+		if compression, err = archive.ParseCompression(c); err != nil {
+			return job.Error(err)
+		}
+	}
 	// get image json
 	tempdir, err := ioutil.TempDir("", "docker-export-")
 	if err != nil {
@@ -30,6 +38,21 @@ func (s *TagStore) CmdImageExport(job *engine.Job) engine.Status {
 	}
 	defer os.RemoveAll(tempdir)
 
+	var oci *ociExporter
+	switch format := job.Getenv("format"); format {
+	case "", "docker":
+	case "oci":
+		oci = newOCIExporter(s.graph, tempdir)
+	default:
+		return job.Errorf("Unsupported image format %s: must be docker or oci", format)
+	}
+	exportImage := func(id string) error {
+		if oci != nil {
+			return oci.exportImage(id)
+		}
+		return s.exportImage(job.Eng, id, tempdir)
+	}
+
 	rootRepoMap := map[string]Repository{}
 	addKey := func(name string, tag string, id string) {
 		log.Debugf("add key [%s:%s]", name, tag)
@@ -47,7 +70,7 @@ func (s *TagStore) CmdImageExport(job *engine.Job) engine.Status {
 			// this is a base repo name, like 'busybox'
 			for tag, id := range rootRepo {
 				addKey(name, tag, id)
-				if err := s.exportImage(job.Eng, id, tempdir); err != nil {
+				if err := exportImage(id); err != nil {
 					return job.Error(err)
 				}
 			}
@@ -66,21 +89,25 @@ func (s *TagStore) CmdImageExport(job *engine.Job) engine.Status {
 				if len(repoTag) > 0 {
 					addKey(repoName, repoTag, img.ID)
 				}
-				if err := s.exportImage(job.Eng, img.ID, tempdir); err != nil {
+				if err := exportImage(img.ID); err != nil {
 					return job.Error(err)
 				}
 
 			} else {
 				// this must be an ID that didn't get looked up just right?
-				if err := s.exportImage(job.Eng, name, tempdir); err != nil {
+				if err := exportImage(name); err != nil {
 					return job.Error(err)
 				}
 			}
 		}
 		log.Debugf("End Serializing %s", name)
 	}
-	// write repositories, if there is something to write
-	if len(rootRepoMap) > 0 {
+	if oci != nil {
+		if err := oci.writeIndex(rootRepoMap); err != nil {
+			return job.Error(err)
+		}
+	} else if len(rootRepoMap) > 0 {
+		// write repositories, if there is something to write
 		rootRepoJson, _ := json.Marshal(rootRepoMap)
 		if err := ioutil.WriteFile(path.Join(tempdir, "repositories"), rootRepoJson, os.FileMode(0644)); err != nil {
 			return job.Error(err)
@@ -89,7 +116,7 @@ func (s *TagStore) CmdImageExport(job *engine.Job) engine.Status {
 		log.Debugf("There were no repositories to write")
 	}
 
-	fs, err := archive.Tar(tempdir, archive.Uncompressed)
+	fs, err := archive.Tar(tempdir, compression)
 	if err != nil {
 		return job.Error(err)
 	}
@@ -0,0 +1,188 @@
+package graph
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/image"
+)
+
+// ociExporter exports images to an OCI image layout.
+type ociExporter struct {
+	graph *Graph
+	dir   string
+	// the descriptors of the blobs already exported, by image id
+	layers    map[string]ociDescriptor
+	manifests map[string]ociDescriptor
+	// the ids of the exported images, in order
+	ids []string
+}
+
+func newOCIExporter(graph *Graph, dir string) *ociExporter {
+	return &ociExporter{
+		graph:     graph,
+		dir:       dir,
+		layers:    make(map[string]ociDescriptor),
+		manifests: make(map[string]ociDescriptor),
+	}
+}
+
+// exportImage writes the layers, the config and the manifest of the image
+// name.
+func (e *ociExporter) exportImage(name string) error {
+	img, err := e.graph.Get(name)
+	if err != nil {
+		return err
+	}
+	if _, exists := e.manifests[img.ID]; exists {
+		return nil
+	}
+
+	// the image and its parents, base image first
+	var chain []*image.Image
+	for i := img; ; {
+		chain = append([]*image.Image{i}, chain...)
+		if i.Parent == "" {
+			break
+		}
+		if i, err = e.graph.Get(i.Parent); err != nil {
+			return err
+		}
+	}
+
+	config := ociImage{
+		Created:      &img.Created,
+		Author:       img.Author,
+		Architecture: img.Architecture,
+		OS:           img.OS,
+		RootFS:       ociRootFS{Type: "layers"},
+	}
+	if config.Architecture == "" {
+		config.Architecture = runtime.GOARCH
+	}
+	if config.OS == "" {
+		config.OS = "linux"
+	}
+	if c := img.Config; c != nil {
+		config.Config = ociImageConfig{
+			User:       c.User,
+			Env:        c.Env,
+			Entrypoint: c.Entrypoint,
+			Cmd:        c.Cmd,
+			Volumes:    c.Volumes,
+			WorkingDir: c.WorkingDir,
+			StopSignal: c.StopSignal,
+		}
+		if len(c.ExposedPorts) > 0 {
+			config.Config.ExposedPorts = make(map[string]struct{})
+			for port := range c.ExposedPorts {
+				config.Config.ExposedPorts[string(port)] = struct{}{}
+			}
+		}
+	}
+
+	manifest := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociMediaTypeManifest,
+	}
+	for _, i := range chain {
+		layer, err := e.exportLayer(i)
+		if err != nil {
+			return err
+		}
+		manifest.Layers = append(manifest.Layers, layer)
+		// the layers are uncompressed, their digests are their diff ids
+		config.RootFS.DiffIDs = append(config.RootFS.DiffIDs, layer.Digest)
+
+		created := i.Created
+		config.History = append(config.History, ociHistory{
+			Created:   &created,
+			CreatedBy: strings.Join(i.ContainerConfig.Cmd, " "),
+			Author:    i.Author,
+			Comment:   i.Comment,
+		})
+	}
+
+	if manifest.Config, err = writeOCIJSON(e.dir, ociMediaTypeConfig, config); err != nil {
+		return err
+	}
+	desc, err := writeOCIJSON(e.dir, ociMediaTypeManifest, manifest)
+	if err != nil {
+		return err
+	}
+	e.manifests[img.ID] = desc
+	e.ids = append(e.ids, img.ID)
+	return nil
+}
+
+func (e *ociExporter) exportLayer(img *image.Image) (ociDescriptor, error) {
+	if desc, exists := e.layers[img.ID]; exists {
+		return desc, nil
+	}
+	arch, err := img.TarLayer()
+	if err != nil {
+		return ociDescriptor{}, err
+	}
+	defer arch.Close()
+	desc, err := writeOCIBlob(e.dir, ociMediaTypeLayer, arch)
+	if err != nil {
+		return ociDescriptor{}, err
+	}
+	desc.Annotations = map[string]string{ociImageIDAnnotation: img.ID}
+	e.layers[img.ID] = desc
+	return desc, nil
+}
+
+// writeIndex writes the index of the exported images, naming them with
+// the tags of repos, and the oci-layout file.
+func (e *ociExporter) writeIndex(repos map[string]Repository) error {
+	index := ociIndex{
+		SchemaVersion: 2,
+		MediaType:     ociMediaTypeIndex,
+		Manifests:     []ociDescriptor{},
+	}
+
+	var refs []string
+	for name, repo := range repos {
+		for tag := range repo {
+			refs = append(refs, name+":"+tag)
+		}
+	}
+	sort.Strings(refs)
+
+	tagged := make(map[string]bool)
+	for _, ref := range refs {
+		i := strings.LastIndex(ref, ":")
+		id := repos[ref[:i]][ref[i+1:]]
+		desc, exists := e.manifests[id]
+		if !exists {
+			continue
+		}
+		desc.Annotations = map[string]string{ociRefNameAnnotation: ref}
+		index.Manifests = append(index.Manifests, desc)
+		tagged[id] = true
+	}
+	// the images exported by id are listed unnamed
+	for _, id := range e.ids {
+		if !tagged[id] {
+			index.Manifests = append(index.Manifests, e.manifests[id])
+		}
+	}
+
+	b, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path.Join(e.dir, "index.json"), b, 0644); err != nil {
+		return err
+	}
+	b, err = json.Marshal(ociLayout{ImageLayoutVersion: ociLayoutVersion})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path.Join(e.dir, "oci-layout"), b, 0644)
+}
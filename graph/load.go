@@ -17,7 +17,8 @@ import (
 )
 
 // Loads a set of images into the repository. This is the complementary of ImageExport.
-// The input stream is an uncompressed tar ball containing images and metadata.
+// The input stream is a tar ball containing images and metadata, which may
+// be compressed, in the format of docker or of an OCI image layout.
 func (s *TagStore) CmdLoad(job *engine.Job) engine.Status {
 	tmpImageDir, err := ioutil.TempDir("", "docker-import-")
 	if err != nil {
@@ -46,6 +47,13 @@ func (s *TagStore) CmdLoad(job *engine.Job) engine.Status {
 		return job.Error(err)
 	}
 
+	if isOCILayout(repoDir) {
+		if err := s.loadOCI(repoDir); err != nil {
+			return job.Error(err)
+		}
+		return engine.StatusOK
+	}
+
 	dirs, err := ioutil.ReadDir(repoDir)
 	if err != nil {
 		return job.Error(err)
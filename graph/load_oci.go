@@ -0,0 +1,201 @@
+// +build linux
+
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/nat"
+	"github.com/docker/docker/pkg/parsers"
+	"github.com/docker/docker/runconfig"
+	"github.com/docker/docker/utils"
+)
+
+// isOCILayout returns whether dir holds an OCI image layout.
+func isOCILayout(dir string) bool {
+	_, err := os.Stat(path.Join(dir, "oci-layout"))
+	return err == nil
+}
+
+// loadOCI loads the images of the OCI image layout at dir, and tags them
+// with the names of their manifests in its index.
+func (s *TagStore) loadOCI(dir string) error {
+	var layout ociLayout
+	b, err := ioutil.ReadFile(path.Join(dir, "oci-layout"))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &layout); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(layout.ImageLayoutVersion, "1.") {
+		return fmt.Errorf("Unsupported OCI image layout version %s", layout.ImageLayoutVersion)
+	}
+
+	var index ociIndex
+	if b, err = ioutil.ReadFile(path.Join(dir, "index.json")); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &index); err != nil {
+		return err
+	}
+	// the media type of the index is optional
+	if index.MediaType != "" && index.MediaType != ociMediaTypeIndex {
+		return fmt.Errorf("Unsupported index of media type %s", index.MediaType)
+	}
+
+	for _, desc := range index.Manifests {
+		if desc.MediaType != ociMediaTypeManifest {
+			return fmt.Errorf("Unsupported manifest %s of media type %s", desc.Digest, desc.MediaType)
+		}
+		id, err := s.loadOCIImage(dir, desc)
+		if err != nil {
+			return err
+		}
+
+		ref := desc.Annotations[ociRefNameAnnotation]
+		if ref == "" {
+			continue
+		}
+		if !strings.ContainsAny(ref, ":/") {
+			// a tag, without the repository to set it in
+			log.Debugf("Not tagging image %s with %s, it has no repository", id, ref)
+			continue
+		}
+		repoName, tag := parsers.ParseRepositoryTag(ref)
+		if err := s.Set(repoName, tag, id, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadOCIImage registers the layers of the manifest desc in the graph, an
+// image for each, and returns the id of the top one. The layers keep the
+// ids the manifest records for them. Otherwise the ids derive from the
+// diff ids of the layers, and the config of the image for the top one, so
+// that loading an image twice doesn't register it twice.
+func (s *TagStore) loadOCIImage(dir string, desc ociDescriptor) (string, error) {
+	var manifest ociManifest
+	if err := readOCIJSON(dir, desc, &manifest); err != nil {
+		return "", err
+	}
+	var config ociImage
+	if err := readOCIJSON(dir, manifest.Config, &config); err != nil {
+		return "", err
+	}
+	if len(manifest.Layers) == 0 || len(manifest.Layers) != len(config.RootFS.DiffIDs) {
+		return "", fmt.Errorf("Manifest %s has %d layers for %d diff ids", desc.Digest, len(manifest.Layers), len(config.RootFS.DiffIDs))
+	}
+
+	// the history of the layers, without the one of empty layers
+	var history []ociHistory
+	for _, h := range config.History {
+		if !h.EmptyLayer {
+			history = append(history, h)
+		}
+	}
+
+	parent := ""
+	for i, layer := range manifest.Layers {
+		top := i == len(manifest.Layers)-1
+		id := layer.Annotations[ociImageIDAnnotation]
+		if err := utils.ValidateID(id); err != nil {
+			key := parent + " " + config.RootFS.DiffIDs[i]
+			if top {
+				key += " " + manifest.Config.Digest
+			}
+			sum := sha256.Sum256([]byte(key))
+			id = hex.EncodeToString(sum[:])
+		}
+
+		if !s.graph.Exists(id) {
+			img := &image.Image{
+				ID:           id,
+				Parent:       parent,
+				Architecture: config.Architecture,
+				OS:           config.OS,
+			}
+			if i < len(history) {
+				h := history[i]
+				if h.Created != nil {
+					img.Created = *h.Created
+				}
+				img.Author = h.Author
+				img.Comment = h.Comment
+				if h.CreatedBy != "" {
+					img.ContainerConfig.Cmd = []string{h.CreatedBy}
+				}
+			}
+			if top {
+				if config.Created != nil {
+					img.Created = *config.Created
+				}
+				img.Author = config.Author
+				img.Config = ociRunConfig(config.Config)
+			}
+			if err := s.registerOCILayer(dir, img, layer); err != nil {
+				return "", err
+			}
+		}
+		parent = id
+	}
+	return parent, nil
+}
+
+// registerOCILayer registers img with the blob of layer, which may be
+// compressed, checking its digest.
+func (s *TagStore) registerOCILayer(dir string, img *image.Image, layer ociDescriptor) error {
+	blobPath, err := ociBlobPath(dir, layer.Digest)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(blobPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if err := s.graph.Register(img, io.TeeReader(f, h)); err != nil {
+		return err
+	}
+	// the end of the blob may not be read by the extraction
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if digest := "sha256:" + hex.EncodeToString(h.Sum(nil)); digest != layer.Digest {
+		s.graph.Delete(img.ID)
+		return fmt.Errorf("Layer %s has digest %s", layer.Digest, digest)
+	}
+	return nil
+}
+
+func ociRunConfig(c ociImageConfig) *runconfig.Config {
+	config := &runconfig.Config{
+		User:       c.User,
+		Env:        c.Env,
+		Entrypoint: c.Entrypoint,
+		Cmd:        c.Cmd,
+		Volumes:    c.Volumes,
+		WorkingDir: c.WorkingDir,
+		StopSignal: c.StopSignal,
+	}
+	if len(c.ExposedPorts) > 0 {
+		config.ExposedPorts = make(map[nat.Port]struct{})
+		for port := range c.ExposedPorts {
+			config.ExposedPorts[nat.Port(port)] = struct{}{}
+		}
+	}
+	return config
+}
@@ -0,0 +1,164 @@
+package graph
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// The OCI image layout holds the images in content addressed blobs: an
+// index.json lists the manifests of the images, a manifest the config and
+// the layers of its image.
+// See https://github.com/opencontainers/image-spec/blob/master/image-layout.md
+const (
+	ociLayoutVersion = "1.0.0"
+
+	ociMediaTypeIndex    = "application/vnd.oci.image.index.v1+json"
+	ociMediaTypeManifest = "application/vnd.oci.image.manifest.v1+json"
+	ociMediaTypeConfig   = "application/vnd.oci.image.config.v1+json"
+	ociMediaTypeLayer    = "application/vnd.oci.image.layer.v1.tar"
+
+	// ociRefNameAnnotation names the image of a manifest of the index,
+	// as repository:tag
+	ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+
+	// ociImageIDAnnotation records the id of the image of a layer of a
+	// manifest, so that loading the layout gives the images their ids back
+	ociImageIDAnnotation = "com.docker.image.id"
+)
+
+type ociLayout struct {
+	ImageLayoutVersion string `json:"imageLayoutVersion"`
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType,omitempty"`
+	Manifests     []ociDescriptor `json:"manifests"`
+}
+
+type ociManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType,omitempty"`
+	Config        ociDescriptor   `json:"config"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+type ociImageConfig struct {
+	User         string              `json:"User,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	Env          []string            `json:"Env,omitempty"`
+	Entrypoint   []string            `json:"Entrypoint,omitempty"`
+	Cmd          []string            `json:"Cmd,omitempty"`
+	Volumes      map[string]struct{} `json:"Volumes,omitempty"`
+	WorkingDir   string              `json:"WorkingDir,omitempty"`
+	StopSignal   string              `json:"StopSignal,omitempty"`
+}
+
+type ociRootFS struct {
+	Type    string   `json:"type"`
+	DiffIDs []string `json:"diff_ids"`
+}
+
+type ociHistory struct {
+	Created    *time.Time `json:"created,omitempty"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	Author     string     `json:"author,omitempty"`
+	Comment    string     `json:"comment,omitempty"`
+	EmptyLayer bool       `json:"empty_layer,omitempty"`
+}
+
+type ociImage struct {
+	Created      *time.Time     `json:"created,omitempty"`
+	Author       string         `json:"author,omitempty"`
+	Architecture string         `json:"architecture"`
+	OS           string         `json:"os"`
+	Config       ociImageConfig `json:"config"`
+	RootFS       ociRootFS      `json:"rootfs"`
+	History      []ociHistory   `json:"history,omitempty"`
+}
+
+// ociBlobPath returns the path of the blob digest in the layout at dir.
+func ociBlobPath(dir, digest string) (string, error) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || parts[0] != "sha256" || len(parts[1]) != 64 {
+		return "", fmt.Errorf("Unsupported blob digest %q", digest)
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return "", fmt.Errorf("Invalid blob digest %q", digest)
+	}
+	return path.Join(dir, "blobs", parts[0], parts[1]), nil
+}
+
+// writeOCIBlob writes the content of r as a blob of the layout at dir,
+// and returns its descriptor.
+func writeOCIBlob(dir, mediaType string, r io.Reader) (ociDescriptor, error) {
+	blobs := path.Join(dir, "blobs", "sha256")
+	if err := os.MkdirAll(blobs, 0755); err != nil {
+		return ociDescriptor{}, err
+	}
+	f, err := ioutil.TempFile(blobs, "tmp-")
+	if err != nil {
+		return ociDescriptor{}, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		return ociDescriptor{}, err
+	}
+	desc := ociDescriptor{
+		MediaType: mediaType,
+		Digest:    "sha256:" + hex.EncodeToString(h.Sum(nil)),
+		Size:      size,
+	}
+	blobPath, err := ociBlobPath(dir, desc.Digest)
+	if err != nil {
+		return ociDescriptor{}, err
+	}
+	return desc, os.Rename(f.Name(), blobPath)
+}
+
+// writeOCIJSON writes v as a JSON blob of the layout at dir.
+func writeOCIJSON(dir, mediaType string, v interface{}) (ociDescriptor, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ociDescriptor{}, err
+	}
+	return writeOCIBlob(dir, mediaType, bytes.NewReader(b))
+}
+
+// readOCIJSON decodes the blob of desc in the layout at dir into v, after
+// checking its digest.
+func readOCIJSON(dir string, desc ociDescriptor, v interface{}) error {
+	blobPath, err := ociBlobPath(dir, desc.Digest)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(blobPath)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(b)
+	if digest := "sha256:" + hex.EncodeToString(sum[:]); digest != desc.Digest {
+		return fmt.Errorf("Blob %s has digest %s", desc.Digest, digest)
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,110 @@
+// +build linux
+
+package graph
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/runconfig"
+	"github.com/docker/docker/utils"
+)
+
+const testChildImageID = "7d3e1f1bc5a2c2e8d1aa3e6a57f0d44f5fd2b0fa05a78b02b6f4a1b3e5c8d9a0"
+
+func TestOCIExportLoad(t *testing.T) {
+	tmp, err := utils.TestDirectory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	store := mkTestTagStore(path.Join(tmp, "src"), t)
+	defer store.graph.driver.Cleanup()
+
+	// a child image with a config
+	layer, err := fakeTar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &runconfig.Config{
+		Cmd:        []string{"/bin/sh"},
+		Env:        []string{"PATH=/bin"},
+		WorkingDir: "/tmp",
+	}
+	child := &image.Image{ID: testChildImageID, Parent: testOfficialImageID, Config: config}
+	if err := store.graph.Register(child, layer); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(testOfficialImageName, "child", testChildImageID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := path.Join(tmp, "layout")
+	e := newOCIExporter(store.graph, dir)
+	for _, id := range []string{testOfficialImageID, testChildImageID, testPrivateImageID} {
+		if err := e.exportImage(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.writeIndex(store.Repositories); err != nil {
+		t.Fatal(err)
+	}
+	if !isOCILayout(dir) {
+		t.Fatal("Expected an OCI image layout")
+	}
+	loaded := mkTestTagStore(path.Join(tmp, "dst"), t)
+	defer loaded.graph.driver.Cleanup()
+	if err := loaded.loadOCI(dir); err != nil {
+		t.Fatal(err)
+	}
+	images, err := loaded.graph.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// loading the images again doesn't register them again
+	if err := loaded.loadOCI(dir); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := loaded.graph.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded) != len(images) {
+		t.Fatalf("Expected %d images after loading twice, got %d", len(images), len(reloaded))
+	}
+
+	for _, ref := range []struct{ name, tag string }{
+		{testOfficialImageName, "latest"},
+		{testOfficialImageName, "child"},
+		{testPrivateImageName, "latest"},
+	} {
+		if _, exists := loaded.Repositories[ref.name][ref.tag]; !exists {
+			t.Fatalf("Expected %s:%s to be loaded", ref.name, ref.tag)
+		}
+	}
+	img, err := loaded.LookupImage(testOfficialImageName + ":child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.ID != testChildImageID || img.Parent != testOfficialImageID {
+		t.Fatalf("Expected the image %s with parent %s, got %s with parent %s", testChildImageID, testOfficialImageID, img.ID, img.Parent)
+	}
+	if !reflect.DeepEqual(img.Config, config) {
+		t.Fatalf("Expected config %v, got %v", config, img.Config)
+	}
+	if !loaded.graph.Exists(img.Parent) {
+		t.Fatalf("Expected the parent of %s to be loaded", img.ID)
+	}
+
+	if err := ioutil.WriteFile(path.Join(dir, "index.json"), []byte(`{"schemaVersion": 2, "mediaType": "application/json", "manifests": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.loadOCI(dir); err == nil {
+		t.Fatal("Expected an error loading an index of another media type")
+	}
+}
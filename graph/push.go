@@ -14,6 +14,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/docker/engine"
 	"github.com/docker/docker/image"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/common"
 	"github.com/docker/docker/registry"
 	"github.com/docker/docker/utils"
@@ -135,7 +136,7 @@ func lookupImageOnEndpoint(wg *sync.WaitGroup, r *registry.Session, out io.Write
 }
 
 func (s *TagStore) pushImageToEndpoint(endpoint string, out io.Writer, remoteName string, imageIDs []string,
-	tags map[string][]string, repo *registry.RepositoryData, sf *utils.StreamFormatter, r *registry.Session) error {
+	tags map[string][]string, repo *registry.RepositoryData, sf *utils.StreamFormatter, r *registry.Session, compression archive.Compression) error {
 	workerCount := len(imageIDs)
 	// start a maximum of 5 workers to check if images exist on the specified endpoint.
 	if workerCount > 5 {
@@ -176,7 +177,7 @@ func (s *TagStore) pushImageToEndpoint(endpoint string, out io.Writer, remoteNam
 	// is very important that is why we are still iterating over the ordered list of imageIDs.
 	for _, id := range imageIDs {
 		if _, push := shouldPush[id]; push {
-			if _, err := s.pushImage(r, out, id, endpoint, repo.Tokens, sf); err != nil {
+			if _, err := s.pushImage(r, out, id, endpoint, repo.Tokens, sf, compression); err != nil {
 				// FIXME: Continue on error?
 				return err
 			}
@@ -194,7 +195,7 @@ func (s *TagStore) pushImageToEndpoint(endpoint string, out io.Writer, remoteNam
 // pushRepository pushes layers that do not already exist on the registry.
 func (s *TagStore) pushRepository(r *registry.Session, out io.Writer,
 	repoInfo *registry.RepositoryInfo, localRepo map[string]string,
-	tag string, sf *utils.StreamFormatter) error {
+	tag string, sf *utils.StreamFormatter, compression archive.Compression) error {
 	log.Debugf("Local repo: %s", localRepo)
 	out = utils.NewWriteFlusher(out)
 	imgList, tags, err := s.getImageList(localRepo, tag)
@@ -221,7 +222,7 @@ func (s *TagStore) pushRepository(r *registry.Session, out io.Writer,
 	out.Write(sf.FormatStatus("", "Pushing repository %s (%d tags)", repoInfo.CanonicalName, nTag))
 	// push the repository to each of the endpoints only if it does not exist.
 	for _, endpoint := range repoData.Endpoints {
-		if err := s.pushImageToEndpoint(endpoint, out, repoInfo.RemoteName, imgList, tags, repoData, sf, r); err != nil {
+		if err := s.pushImageToEndpoint(endpoint, out, repoInfo.RemoteName, imgList, tags, repoData, sf, r, compression); err != nil {
 			return err
 		}
 	}
@@ -229,7 +230,7 @@ func (s *TagStore) pushRepository(r *registry.Session, out io.Writer,
 	return err
 }
 
-func (s *TagStore) pushImage(r *registry.Session, out io.Writer, imgID, ep string, token []string, sf *utils.StreamFormatter) (checksum string, err error) {
+func (s *TagStore) pushImage(r *registry.Session, out io.Writer, imgID, ep string, token []string, sf *utils.StreamFormatter, compression archive.Compression) (checksum string, err error) {
 	out = utils.NewWriteFlusher(out)
 	jsonRaw, err := ioutil.ReadFile(path.Join(s.graph.Root, imgID, "json"))
 	if err != nil {
@@ -259,7 +260,7 @@ func (s *TagStore) pushImage(r *registry.Session, out io.Writer, imgID, ep strin
 	// Send the layer
 	log.Debugf("rendered layer for %s of [%d] size", imgData.ID, layerData.Size)
 
-	checksum, checksumPayload, err := r.PushImageLayerRegistry(imgData.ID, utils.ProgressReader(layerData, int(layerData.Size), out, sf, false, common.TruncateID(imgData.ID), "Pushing"), ep, token, jsonRaw)
+	checksum, checksumPayload, err := r.PushImageLayerRegistry(imgData.ID, utils.ProgressReader(layerData, int(layerData.Size), out, sf, false, common.TruncateID(imgData.ID), "Pushing"), ep, token, jsonRaw, compression)
 	if err != nil {
 		return "", err
 	}
@@ -442,6 +443,16 @@ func (s *TagStore) CmdPush(job *engine.Job) engine.Status {
 	job.GetenvJson("authConfig", authConfig)
 	job.GetenvJson("metaHeaders", &metaHeaders)
 
+	// the layers pushed to v1 registries are compressed with gzip by
+	// default; v2 registries take them uncompressed
+	compress := job.Getenv("compress")
+	compression := archive.Gzip
+	if compress != "" {
+		if compression, err = archive.ParseCompression(compress); err != nil {
+			return job.Error(err)
+		}
+	}
+
 	if _, err := s.poolAdd("push", repoInfo.LocalName); err != nil {
 		return job.Error(err)
 	}
@@ -458,6 +469,9 @@ func (s *TagStore) CmdPush(job *engine.Job) engine.Status {
 	}
 
 	if endpoint.Version == registry.APIVersion2 {
+		if compression != archive.Gzip {
+			return job.Errorf("Error pushing to registry: --compress=%s is only supported by v1 registries", compress)
+		}
 		err := s.pushV2Repository(r, job.Eng, job.Stdout, repoInfo, tag, sf)
 		if err == nil {
 			return engine.StatusOK
@@ -473,12 +487,16 @@ func (s *TagStore) CmdPush(job *engine.Job) engine.Status {
 		reposLen = len(s.Repositories[repoInfo.LocalName])
 	}
 	job.Stdout.Write(sf.FormatStatus("", "The push refers to a repository [%s] (len: %d)", repoInfo.CanonicalName, reposLen))
+	if compression == archive.Zstd {
+		// the registry stores the layers as they are pushed
+		job.Stdout.Write(sf.FormatStatus("", "Warning: the layers are compressed with zstd, only Docker daemons with zstd support can pull them"))
+	}
 	// If it fails, try to get the repository
 	localRepo, exists := s.Repositories[repoInfo.LocalName]
 	if !exists {
 		return job.Errorf("Repository does not exist: %s", repoInfo.LocalName)
 	}
-	if err := s.pushRepository(r, job.Stdout, repoInfo, localRepo, tag, sf); err != nil {
+	if err := s.pushRepository(r, job.Stdout, repoInfo, localRepo, tag, sf, compression); err != nil {
 		return job.Error(err)
 	}
 	return engine.StatusOK
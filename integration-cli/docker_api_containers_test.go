@@ -486,3 +486,23 @@ func TestBuildApiDockerfileSymlink(t *testing.T) {
 
 	logDone("container REST API - check build w/bad Dockerfile symlink path")
 }
+
+func TestContainerApiCreateInvalidStopSignal(t *testing.T) {
+	defer deleteAllContainers()
+
+	config := map[string]interface{}{
+		"Image":      "busybox",
+		"Cmd":        []string{"true"},
+		"StopSignal": "SIGNOPE",
+	}
+
+	out, err := sockRequest("POST", "/containers/create", config)
+	if err == nil || strings.Contains(err.Error(), "201 Created") {
+		t.Fatalf("Create was supposed to fail: %s", out)
+	}
+	if !strings.Contains(string(out), "Invalid signal: SIGNOPE") {
+		t.Fatalf("Didn't complain about the invalid stop signal: %s", out)
+	}
+
+	logDone("container REST API - create with an invalid stop signal")
+}
@@ -2032,6 +2032,44 @@ func TestBuildUser(t *testing.T) {
 	logDone("build - user")
 }
 
+func TestBuildStopSignal(t *testing.T) {
+	name := "testbuildstopsignal"
+	defer deleteImages(name)
+	defer deleteAllContainers()
+	_, err := buildImage(name,
+		`FROM busybox
+		 STOPSIGNAL SIGKILL`,
+		true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := inspectField(name, "Config.StopSignal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "SIGKILL" {
+		t.Fatalf("StopSignal %s, expected SIGKILL", res)
+	}
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--name", "stopsignal", name, "true"))
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	res, err = inspectField("stopsignal", "Config.StopSignal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "SIGKILL" {
+		t.Fatalf("StopSignal %s of the container, expected SIGKILL", res)
+	}
+
+	_, err = buildImage(name, "FROM busybox\nSTOPSIGNAL SIGNOPE", true)
+	if err == nil {
+		t.Fatal("expected an invalid STOPSIGNAL to fail the build")
+	}
+	logDone("build - stop signal")
+}
+
 func TestBuildRelativeWorkdir(t *testing.T) {
 	name := "testbuildrelativeworkdir"
 	expected := "/test2/test3"
@@ -279,3 +279,38 @@ func TestCommitChange(t *testing.T) {
 
 	logDone("commit - commit --change")
 }
+
+func TestCommitWithoutStaticAddresses(t *testing.T) {
+	defer deleteAllContainers()
+
+	out, _, err := dockerCmd(t, "run", "-d", "busybox", "top")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip, err := inspectField(strings.TrimSpace(out), "NetworkSettings.IPAddress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	static := ip[:strings.LastIndex(ip, ".")] + ".251"
+
+	if out, _, err := dockerCmd(t, "run", "--name", "static", "--ip", static, "busybox", "true"); err != nil {
+		t.Fatal(out, err)
+	}
+	if out, _, err := dockerCmd(t, "commit", "static", "staticcommit"); err != nil {
+		t.Fatal(out, err)
+	}
+	defer deleteImages("staticcommit", "staticbuild")
+
+	for _, field := range []string{"Config.IPAddress", "ContainerConfig.IPAddress"} {
+		if address, err := inspectField("staticcommit", field); err != nil || address != "" {
+			t.Fatalf("Expected no static address in the %s of the image, got %q, %v", field, address, err)
+		}
+	}
+
+	// every step creates a container from the config of the image
+	if _, err := buildImage("staticbuild", "FROM staticcommit\nRUN true\nRUN true\n", false); err != nil {
+		t.Fatal(err)
+	}
+
+	logDone("commit - static addresses are left out of the image")
+}
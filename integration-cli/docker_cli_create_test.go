@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"os/exec"
+	"strings"
 	"testing"
 	"time"
 
@@ -249,3 +250,23 @@ func TestCreateVolumesCreated(t *testing.T) {
 
 	logDone("create - volumes are created")
 }
+
+func TestCreateInvalidStorageOpt(t *testing.T) {
+	defer deleteAllContainers()
+
+	name := "test_create_storage_opt"
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "create", "--name", name, "--storage-opt", "unknown=1", "busybox"))
+	if err == nil {
+		t.Fatalf("expected an error for an unknown storage option, got %s", out)
+	}
+	if !strings.Contains(out, "Unknown storage option") {
+		t.Fatalf("expected the unknown storage option to be reported, got %s", out)
+	}
+
+	// the container is not left behind
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "inspect", name)); err == nil {
+		t.Fatalf("expected the container not to be created, got %s", out)
+	}
+
+	logDone("create - invalid storage options are rejected")
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -101,6 +102,228 @@ func TestDaemonStartIptablesFalse(t *testing.T) {
 	logDone("daemon - started daemon with iptables=false")
 }
 
+func TestDaemonUserlandProxyRequiresIptables(t *testing.T) {
+	d := NewDaemon(t)
+	if err := d.Start("--iptables=false", "--userland-proxy=false"); err == nil {
+		d.Stop()
+		t.Fatal("expected the daemon to refuse --userland-proxy=false with --iptables=false")
+	}
+
+	logDone("daemon - userland proxy cannot be disabled without iptables")
+}
+
+// Without the userland proxy, a container reaches its own published port
+// through hairpin NAT, and the host through route_localnet.
+func TestDaemonUserlandProxyDisabledHairpin(t *testing.T) {
+	testRequires(t, NativeExecDriver, SameHostDaemon)
+	defer deleteAllContainers()
+
+	d := NewDaemon(t)
+	if err := d.StartWithBusybox("--userland-proxy=false"); err != nil {
+		t.Fatalf("Could not start daemon with busybox: %v", err)
+	}
+	defer d.Stop()
+
+	if out, err := d.Cmd("run", "-d", "--name", "hairpin", "-p", "9876:80", "busybox",
+		"sh", "-c", "while true; do echo hello | nc -l -p 80; done"); err != nil {
+		t.Fatalf("Could not run the server: %s, %v", out, err)
+	}
+
+	out, err := d.Cmd("inspect", "--format", "{{.NetworkSettings.HairpinMode}}", "hairpin")
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	if strings.TrimSpace(out) != "true" {
+		t.Fatalf("expected hairpin mode on the container, got %q", out)
+	}
+
+	out, err = d.Cmd("inspect", "--format", "{{.NetworkSettings.Gateway}}", "hairpin")
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	gateway := strings.TrimSpace(out)
+
+	// the container connects to its own published port on the host
+	out, err = d.Cmd("exec", "hairpin", "nc", gateway, "9876")
+	if err != nil {
+		t.Fatalf("Could not connect to the published port from the container: %s, %v", out, err)
+	}
+	if strings.TrimSpace(out) != "hello" {
+		t.Fatalf("expected hello from the published port, got %q", out)
+	}
+
+	// the host connects to the published port on the loopback address
+	conn, err := net.Dial("tcp", "127.0.0.1:9876")
+	if err != nil {
+		t.Fatalf("Could not connect to the published port on the loopback address: %v", err)
+	}
+	b, err := ioutil.ReadAll(conn)
+	conn.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(b)) != "hello" {
+		t.Fatalf("expected hello from the published port, got %q", b)
+	}
+
+	ps, _, err := runCommandWithOutput(exec.Command("ps", "-eo", "args"))
+	if err != nil {
+		t.Fatal(ps, err)
+	}
+	if strings.Contains(ps, "docker-proxy") {
+		t.Fatalf("no userland proxy should be running: %s", ps)
+	}
+
+	logDone("daemon - containers reach their published ports without the userland proxy")
+}
+
+// With IPv6, the ports published on the default ip are published on the
+// IPv6 addresses too, and ports can be published on an IPv6 ip.
+func TestDaemonIPv6PublishedPorts(t *testing.T) {
+	testRequires(t, SameHostDaemon)
+	defer deleteAllContainers()
+
+	d := NewDaemon(t)
+	if err := d.StartWithBusybox("--ipv6", "--fixed-cidr-v6=2001:db8:1::/64"); err != nil {
+		t.Fatalf("Could not start daemon with busybox: %v", err)
+	}
+	defer d.Stop()
+
+	if out, err := d.Cmd("run", "-d", "--name", "ipv6", "-p", "9877:80", "-p", "[::1]:9878:81", "busybox",
+		"sh", "-c", "while true; do echo hello | nc -l -p 80; done"); err != nil {
+		t.Fatalf("Could not run the server: %s, %v", out, err)
+	}
+
+	out, err := d.Cmd("port", "ipv6", "80")
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	if !strings.Contains(out, "0.0.0.0:9877") || !strings.Contains(out, "[::]:9877") {
+		t.Fatalf("expected port 80 published on both address families, got %q", out)
+	}
+
+	out, err = d.Cmd("port", "ipv6", "81")
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	if strings.TrimSpace(out) != "[::1]:9878" {
+		t.Fatalf("expected port 81 published on [::1]:9878, got %q", out)
+	}
+
+	conn, err := net.Dial("tcp", "[::1]:9877")
+	if err != nil {
+		t.Fatalf("Could not connect to the published port on IPv6: %v", err)
+	}
+	b, err := ioutil.ReadAll(conn)
+	conn.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(b)) != "hello" {
+		t.Fatalf("expected hello from the published port, got %q", b)
+	}
+
+	logDone("daemon - ports are published on IPv6")
+}
+
+func TestDaemonCheckpointsNetworkAllocations(t *testing.T) {
+	testRequires(t, SameHostDaemon)
+
+	d := NewDaemon(t)
+	if err := d.StartWithBusybox(); err != nil {
+		t.Fatalf("Could not start daemon with busybox: %v", err)
+	}
+	defer d.Stop()
+
+	out, err := d.Cmd("run", "-d", "-p", "9879:80", "busybox", "top")
+	if err != nil {
+		t.Fatalf("Could not run top: %s, %v", out, err)
+	}
+	id := strings.TrimSpace(out)
+
+	allocations := func() map[string]json.RawMessage {
+		data, err := ioutil.ReadFile(filepath.Join(d.folder, "graph", "network", "allocations.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved := make(map[string]json.RawMessage)
+		if err := json.Unmarshal(data, &saved); err != nil {
+			t.Fatal(err)
+		}
+		return saved
+	}
+
+	a, ok := allocations()[id]
+	if !ok || !strings.Contains(string(a), "9879") {
+		t.Fatalf("expected the allocation of %s to be checkpointed, got %s", id, a)
+	}
+
+	// the allocations of the containers which aren't running after a
+	// restart are freed
+	if out, err := d.Cmd("stop", id); err != nil {
+		t.Fatalf("Could not stop top: %s, %v", out, err)
+	}
+	if err := d.Restart(); err != nil {
+		t.Fatalf("Could not restart daemon: %v", err)
+	}
+	if _, ok := allocations()[id]; ok {
+		t.Fatalf("expected the allocation of the stopped %s to be freed", id)
+	}
+	if out, err := d.Cmd("run", "-d", "-p", "9879:80", "busybox", "top"); err != nil {
+		t.Fatalf("Could not publish the freed port: %s, %v", out, err)
+	}
+
+	logDone("daemon - network allocations are checkpointed")
+}
+
+func TestDaemonMacvlanNetworkMode(t *testing.T) {
+	testRequires(t, NativeExecDriver, SameHostDaemon)
+
+	// a dummy interface stands for the NIC of the VLAN
+	if out, _, err := runCommandWithOutput(exec.Command("ip", "link", "add", "dockertest0", "type", "dummy")); err != nil {
+		t.Skipf("Could not create a dummy interface: %s, %v", out, err)
+	}
+	defer exec.Command("ip", "link", "del", "dockertest0").Run()
+	if out, _, err := runCommandWithOutput(exec.Command("ip", "link", "set", "dockertest0", "up")); err != nil {
+		t.Fatal(out, err)
+	}
+
+	d := NewDaemon(t)
+	if err := d.StartWithBusybox("--macvlan-parent=dockertest0", "--macvlan-subnet=10.99.0.0/24",
+		"--macvlan-gateway=10.99.0.1", "--macvlan-ip-range=10.99.0.128/25"); err != nil {
+		t.Fatalf("Could not start daemon with busybox: %v", err)
+	}
+	defer d.Stop()
+
+	out, err := d.Cmd("run", "--net=macvlan", "--ip=10.99.0.200", "busybox", "ip", "-o", "-4", "addr", "show", "eth0")
+	if err != nil {
+		t.Fatalf("Could not run a container on the macvlan network: %s, %v", out, err)
+	}
+	if !strings.Contains(out, "10.99.0.200/24") {
+		t.Fatalf("expected the static address on eth0, got %q", out)
+	}
+
+	out, err = d.Cmd("run", "-d", "--net=macvlan", "busybox", "top")
+	if err != nil {
+		t.Fatalf("Could not run a container on the macvlan network: %s, %v", out, err)
+	}
+	id := strings.TrimSpace(out)
+	out, err = d.Cmd("inspect", "--format", "{{.NetworkSettings.IPAddress}} {{.NetworkSettings.Gateway}}", id)
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	if !strings.HasPrefix(out, "10.99.0.") || !strings.Contains(out, " 10.99.0.1") {
+		t.Fatalf("expected an address of the macvlan IP range, got %q", out)
+	}
+
+	out, err = d.Cmd("run", "--net=macvlan", "-p", "80:80", "busybox", "true")
+	if err == nil || !strings.Contains(out, "--net=macvlan can't be used with -p") {
+		t.Fatalf("expected publishing ports on the macvlan network to fail, got %q", out)
+	}
+
+	logDone("daemon - containers are attached to the macvlan network")
+}
+
 // Issue #8444: If docker0 bridge is modified (intentionally or unintentionally) and
 // no longer has an IP associated, we should gracefully handle that case and associate
 // an IP with it rather than fail daemon start
@@ -534,3 +757,50 @@ func TestDaemonUlimitDefaults(t *testing.T) {
 
 	logDone("daemon - default ulimits are applied")
 }
+
+func TestDaemonUserNamespaceRemap(t *testing.T) {
+	testRequires(t, NativeExecDriver, SameHostDaemon)
+
+	subuid, err := ioutil.ReadFile("/etc/subuid")
+	if err != nil || !strings.Contains(string(subuid), "dockremap:") {
+		t.Skip("Test requires a subordinate id range for the dockremap user")
+	}
+
+	d := NewDaemon(t)
+	if err := d.StartWithBusybox("--userns-remap", "dockremap"); err != nil {
+		t.Fatal(err)
+	}
+	defer d.Stop()
+
+	out, err := d.Cmd("run", "busybox", "id", "-u")
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	if strings.TrimSpace(out) != "0" {
+		t.Fatalf("expected to be root inside the container, got uid %q", out)
+	}
+
+	out, err = d.Cmd("run", "-d", "--name", "remapped", "busybox", "top")
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	out, err = d.Cmd("inspect", "--format", "{{.State.Pid}}", "remapped")
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	status, err := ioutil.ReadFile(filepath.Join("/proc", strings.TrimSpace(out), "status"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		if strings.HasPrefix(line, "Uid:") && strings.Fields(line)[1] == "0" {
+			t.Fatalf("container process runs as root on the host: %s", line)
+		}
+	}
+
+	if out, err := d.Cmd("run", "--privileged", "busybox", "true"); err == nil {
+		t.Fatalf("expected --privileged to be rejected with user namespaces: %s", out)
+	}
+
+	logDone("daemon - containers run in a remapped user namespace")
+}
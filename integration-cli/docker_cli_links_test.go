@@ -337,3 +337,59 @@ func TestLinksUpdateOnRestart(t *testing.T) {
 	}
 	logDone("link - ensure containers hosts files are updated on restart")
 }
+
+func TestLinksRulesUpdateOnRestart(t *testing.T) {
+	testRequires(t, SameHostDaemon)
+	defer deleteAllContainers()
+
+	since := time.Now().Unix()
+	dockerCmd(t, "run", "-d", "--name", "child", "--expose", "80", "busybox", "top")
+	out, _, _ := dockerCmd(t, "run", "-d", "--name", "parent", "--link", "child:http", "busybox", "top")
+	parentID := strings.TrimSpace(out)
+
+	rules := func(parentIP, childIP string) [][]string {
+		return [][]string{
+			{"DOCKER", "-i", "docker0", "-o", "docker0", "-p", "tcp", "-s", childIP, "--sport", "80", "-d", parentIP, "-j", "ACCEPT"},
+			{"DOCKER", "-i", "docker0", "-o", "docker0", "-p", "tcp", "-s", parentIP, "--dport", "80", "-d", childIP, "-j", "ACCEPT"},
+		}
+	}
+	parentIP := findContainerIP(t, "parent")
+	oldChildIP := findContainerIP(t, "child")
+
+	// take the IP of the child while it is stopped
+	dockerCmd(t, "stop", "child")
+	dockerCmd(t, "run", "-d", "busybox", "top")
+	dockerCmd(t, "start", "child")
+
+	childIP := findContainerIP(t, "child")
+	if childIP == oldChildIP {
+		t.Fatalf("Expected the child to get a new IP, kept %s", childIP)
+	}
+	for _, rule := range rules(parentIP, childIP) {
+		if !iptables.Exists(rule...) {
+			t.Fatalf("Iptables rule of the new child IP not found: %v", rule)
+		}
+	}
+	for _, rule := range rules(parentIP, oldChildIP) {
+		if iptables.Exists(rule...) {
+			t.Fatalf("Iptables rule of the previous child IP should be removed: %v", rule)
+		}
+	}
+
+	eventsCmd := exec.Command(dockerBinary, "events", fmt.Sprintf("--since=%d", since), fmt.Sprintf("--until=%d", time.Now().Unix()))
+	out, _, err := runCommandWithOutput(eventsCmd)
+	if err != nil {
+		t.Fatal(err, out)
+	}
+	found := false
+	for _, event := range strings.Split(out, "\n") {
+		if strings.Contains(event, parentID) && strings.HasSuffix(strings.TrimSpace(event), "link_update") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("Expected a link_update event of the parent, got:\n%s", out)
+	}
+
+	logDone("link - ensure iptables rules of links are updated on restart")
+}
@@ -156,3 +156,19 @@ func TestPushEmptyLayer(t *testing.T) {
 	}
 	logDone("push - empty layer config to private registry")
 }
+
+func TestPushCompressV2Registry(t *testing.T) {
+	defer setupRegistry(t)()
+
+	repoName := fmt.Sprintf("%v/dockercli/busybox", privateRegistryURL)
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "tag", "busybox", repoName)); err != nil {
+		t.Fatalf("image tagging failed: %s, %v", out, err)
+	}
+	defer deleteImages(repoName)
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "push", "--compress=zstd", repoName))
+	if err == nil || !strings.Contains(out, "only supported by v1 registries") {
+		t.Fatalf("Expected the push of zstd layers to a v2 registry to fail, got %s, %v", out, err)
+	}
+	logDone("push - compression refused by v2 registries")
+}
@@ -19,8 +19,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/docker/docker/daemon/networkdriver/remote/remotetest"
 	"github.com/docker/docker/nat"
 	"github.com/docker/docker/pkg/networkfs/resolvconf"
+	"github.com/docker/docker/pkg/plugins"
 )
 
 // "test123" should be printed by docker run
@@ -1488,6 +1490,60 @@ func TestRunDnsOptions(t *testing.T) {
 	logDone("run - dns options")
 }
 
+func TestRunDnsResolverOptions(t *testing.T) {
+	defer deleteAllContainers()
+
+	cmd := exec.Command(dockerBinary, "run", "--dns=127.0.0.1", "--dns-opt=ndots:2", "--dns-opt=timeout:1", "--dns-opt=rotate", "busybox", "cat", "/etc/resolv.conf")
+
+	out, _, err := runCommandWithOutput(cmd)
+	if err != nil {
+		t.Fatal(err, out)
+	}
+
+	if actual := resolvconf.GetOptions([]byte(out)); strings.Join(actual, " ") != "ndots:2 timeout:1 rotate" {
+		t.Fatalf("expected 'ndots:2 timeout:1 rotate', but says: %q", actual)
+	}
+
+	cmd = exec.Command(dockerBinary, "run", "--dns-opt=ndots 2", "busybox", "true")
+	if out, _, err := runCommandWithOutput(cmd); err == nil || !strings.Contains(out, "not a valid DNS option") {
+		t.Fatalf("expected an invalid DNS option error, got %v: %s", err, out)
+	}
+
+	logDone("run - dns resolver options")
+}
+
+// the containers sharing the network of another one don't regenerate its
+// resolv.conf with their own dns settings when they start again
+func TestRunNetContainerKeepsResolvConf(t *testing.T) {
+	testRequires(t, SameHostDaemon)
+	defer deleteAllContainers()
+
+	dockerCmd(t, "run", "-d", "--name", "dnsowner", "--dns=127.0.0.1", "--dns-search=mydomain", "--dns-opt=ndots:2", "busybox", "top")
+	resolvConfPath, err := inspectField("dnsowner", "ResolvConfPath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ioutil.ReadFile(resolvConfPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dockerCmd(t, "run", "--name", "dnsjoiner", "--net=container:dnsowner", "busybox", "true")
+	for i := 0; i < 2; i++ {
+		dockerCmd(t, "start", "-a", "dnsjoiner")
+	}
+
+	actual, err := ioutil.ReadFile(resolvConfPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Fatalf("expected the resolv.conf of the shared network to stay %q, got %q", expected, actual)
+	}
+
+	logDone("run - containers sharing a network keep its resolv.conf")
+}
+
 func TestRunDnsOptionsBasedOnHostResolvConf(t *testing.T) {
 	defer deleteAllContainers()
 	testRequires(t, SameHostDaemon)
@@ -1520,6 +1576,10 @@ func TestRunDnsOptionsBasedOnHostResolvConf(t *testing.T) {
 		}
 	}
 
+	// the nameservers of the host are filtered as without --dns-search
+	filteredResolvConf, _ := resolvconf.FilterResolvDns(origResolvConf, false)
+	hostNamservers = resolvconf.GetNameservers(filteredResolvConf)
+
 	cmd = exec.Command(dockerBinary, "run", "--dns-search=mydomain", "busybox", "cat", "/etc/resolv.conf")
 
 	if out, _, err = runCommandWithOutput(cmd); err != nil {
@@ -2407,6 +2467,147 @@ func TestRunWithInvalidMacAddress(t *testing.T) {
 	logDone("run - can't use an invalid mac address")
 }
 
+func TestRunSetIPAddress(t *testing.T) {
+	defer deleteAllContainers()
+
+	out, _, err := dockerCmd(t, "run", "-d", "busybox", "top")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip, err := inspectField(strings.TrimSpace(out), "NetworkSettings.IPAddress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pick a static address in the bridge network, away from the addresses
+	// handed out to the other containers
+	static := ip[:strings.LastIndex(ip, ".")] + ".250"
+
+	out, _, err = dockerCmd(t, "run", "-d", "--name", "static", "--ip", static, "busybox", "top")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inspectedIP, err := inspectField("static", "NetworkSettings.IPAddress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inspectedIP != static {
+		t.Fatalf("docker inspect outputs wrong IP address: %q, should be: %q", inspectedIP, static)
+	}
+
+	// the address stays reserved while the container is stopped
+	if _, _, err := dockerCmd(t, "stop", "static"); err != nil {
+		t.Fatal(err)
+	}
+	out, _, err = runCommandWithOutput(exec.Command(dockerBinary, "create", "--ip", static, "busybox"))
+	if err == nil || !strings.Contains(out, "already reserved") {
+		t.Fatalf("create with a reserved --ip should error out, got %s", out)
+	}
+
+	// and is released when it is removed
+	if _, _, err := dockerCmd(t, "rm", "static"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := dockerCmd(t, "create", "--ip", static, "busybox"); err != nil {
+		t.Fatal(err)
+	}
+
+	logDone("run - setting IP address with --ip")
+}
+
+func TestRunWithInvalidIPAddress(t *testing.T) {
+	defer deleteAllContainers()
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--ip", "2001:db8::33", "busybox"))
+	if err == nil || !strings.Contains(out, "is not a valid IPv4 address") {
+		t.Fatalf("run with an invalid --ip should error out, got %s", out)
+	}
+	out, _, err = runCommandWithOutput(exec.Command(dockerBinary, "run", "--ip", "10.255.255.10", "busybox"))
+	if err == nil || !strings.Contains(out, "out of the range") {
+		t.Fatalf("run with an --ip out of the bridge network should error out, got %s", out)
+	}
+	out, _, err = runCommandWithOutput(exec.Command(dockerBinary, "run", "--net", "host", "--ip", "172.17.0.10", "busybox"))
+	if err == nil || !strings.Contains(out, "require the bridge network mode") {
+		t.Fatalf("run with --ip and --net=host should error out, got %s", out)
+	}
+
+	logDone("run - can't use an invalid IP address")
+}
+
+func TestRunNetworkDriverPlugin(t *testing.T) {
+	defer deleteAllContainers()
+	testRequires(t, NativeExecDriver, SameHostDaemon)
+
+	if out, _, err := runCommandWithOutput(exec.Command("ip", "link", "add", "dockertest0", "type", "dummy")); err != nil {
+		t.Skipf("Could not create a dummy interface: %s, %v", out, err)
+	}
+	exec.Command("ip", "link", "del", "dockertest0").Run()
+
+	fake, err := remotetest.NewFakeDriver("10.98.0.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the fake driver gives the containers dummy interfaces, which aren't
+	// attached to anything
+	var ifaces []string
+	fake.NewInterface = func(id string) (string, error) {
+		name := "dt" + id[:8]
+		if out, _, err := runCommandWithOutput(exec.Command("ip", "link", "add", name, "type", "dummy")); err != nil {
+			return "", fmt.Errorf("%s: %v", out, err)
+		}
+		ifaces = append(ifaces, name)
+		return name, nil
+	}
+	defer func() {
+		for _, name := range ifaces {
+			exec.Command("ip", "link", "del", name).Run()
+		}
+	}()
+
+	if err := os.MkdirAll(plugins.SocketsPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	l, err := fake.Listen(plugins.SocketsPath, "dockertest-net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filepath.Join(plugins.SocketsPath, "dockertest-net.sock"))
+	defer l.Close()
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--net=dockertest-net", "--ip=10.98.0.50",
+		"busybox", "ip", "-o", "-4", "addr", "show", "eth0"))
+	if err != nil {
+		t.Fatalf("Could not run a container with the network driver plugin: %s, %v", out, err)
+	}
+	if !strings.Contains(out, "10.98.0.50/24") {
+		t.Fatalf("expected the address of the plugin on eth0, got %q", out)
+	}
+
+	out, _, err = runCommandWithOutput(exec.Command(dockerBinary, "run", "-d", "--net=dockertest-net", "-p", "8080:80", "busybox", "top"))
+	if err != nil {
+		t.Fatalf("Could not run a container with the network driver plugin: %s, %v", out, err)
+	}
+	id := strings.TrimSpace(out)
+	out, _, err = runCommandWithOutput(exec.Command(dockerBinary, "port", id, "80"))
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	if strings.TrimSpace(out) != "0.0.0.0:8080" {
+		t.Fatalf("expected the port published by the plugin, got %q", out)
+	}
+	if ports := fake.Ports(id); len(ports) != 1 || ports[0] != "tcp/0.0.0.0:8080->80" {
+		t.Fatalf("expected the plugin to publish the port, got %v", ports)
+	}
+
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "stop", id)); err != nil {
+		t.Fatal(out, err)
+	}
+	if _, ok := fake.Endpoint(id); ok {
+		t.Fatal("expected the endpoint to be deleted once the container stopped")
+	}
+
+	logDone("run - attach a container to the network of a network driver plugin")
+}
+
 func TestRunDeallocatePortOnMissingIptablesRule(t *testing.T) {
 	defer deleteAllContainers()
 	testRequires(t, SameHostDaemon)
@@ -3248,3 +3449,63 @@ func TestRunVolumesFromRestartAfterRemoved(t *testing.T) {
 
 	logDone("run - can restart a volumes-from container after producer is removed")
 }
+
+func TestRunStopSignal(t *testing.T) {
+	defer deleteAllContainers()
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "-d", "--name", "stopsignal", "--stop-signal", "SIGUSR1", "busybox", "sh", "-c", "trap 'exit 42' USR1; while true; do sleep 1; done"))
+	if err != nil {
+		t.Fatal(out, err)
+	}
+
+	signal, err := inspectField("stopsignal", "Config.StopSignal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signal != "SIGUSR1" {
+		t.Fatalf("expected stop signal SIGUSR1, got %q", signal)
+	}
+
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "stop", "-t", "30", "stopsignal")); err != nil {
+		t.Fatal(out, err)
+	}
+
+	exitCode, err := inspectField("stopsignal", "State.ExitCode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exitCode != "42" {
+		t.Fatalf("expected the container to exit with 42 on SIGUSR1, got %s", exitCode)
+	}
+
+	out, _, err = runCommandWithOutput(exec.Command(dockerBinary, "run", "--stop-signal", "SIGNOPE", "busybox", "true"))
+	if err == nil || !strings.Contains(out, "Invalid signal: SIGNOPE") {
+		t.Fatalf("expected an invalid stop signal to be rejected, got %v: %s", err, out)
+	}
+
+	logDone("run - stop signal")
+}
+
+func TestRunNetworkRateRestartPolicy(t *testing.T) {
+	defer deleteAllContainers()
+	testRequires(t, SameHostDaemon)
+
+	runCmd := exec.Command(dockerBinary, "run", "-d", "--name", "shaped", "--restart=always", "--network-ingress-rate=1mbit", "busybox", "sh", "-c", "sleep 2")
+	if out, _, err := runCommandWithOutput(runCmd); err != nil {
+		t.Fatal(out, err)
+	}
+
+	// the veth pair is created again by the restart
+	if err := waitInspect("shaped", "{{.RestartCount}} {{.State.Running}}", "1 true", 10); err != nil {
+		t.Fatal(err)
+	}
+	out, _, err := runCommandWithOutput(exec.Command("tc", "qdisc", "show"))
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	if !strings.Contains(out, "rate 1Mbit") {
+		t.Fatalf("Expected the traffic to be shaped after the restart, got %s", out)
+	}
+
+	logDone("run - network rate applied again on restart")
+}
@@ -107,3 +107,290 @@ func TestRunWithUlimits(t *testing.T) {
 
 	logDone("run - ulimits are set")
 }
+
+func TestRunWithPidsLimit(t *testing.T) {
+	testRequires(t, NativeExecDriver, PidsLimit)
+	defer deleteAllContainers()
+	out, _, _ := runCommandWithOutput(exec.Command(dockerBinary, "run", "--pids-limit=4", "busybox", "/bin/sh", "-c", "for i in 1 2 3 4 5 6 7 8; do sleep 1 & done; wait"))
+	if !strings.Contains(out, "can't fork") {
+		t.Fatalf("expected fork failure, got: %s", out)
+	}
+
+	logDone("run - pids limit is set")
+}
+
+func TestRunSeccompDefaultProfile(t *testing.T) {
+	testRequires(t, NativeExecDriver, SameHostDaemon, Seccomp)
+	defer deleteAllContainers()
+
+	// the status of a process shows 2 when it runs with a filter
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--name", "seccomp", "busybox", "grep", "Seccomp:", "/proc/self/status"))
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	if !strings.Contains(out, "2") {
+		t.Fatalf("expected a seccomp filter, got %s", out)
+	}
+
+	profile, err := inspectField("seccomp", "SeccompProfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile != "default" {
+		t.Fatalf("expected the default seccomp profile, got %q", profile)
+	}
+
+	// CAP_SYS_ADMIN relaxes the default profile to allow mounting tmpfs
+	out, _, err = runCommandWithOutput(exec.Command(dockerBinary, "run", "--name", "sysadmin", "--cap-add", "SYS_ADMIN", "busybox", "mount", "-t", "tmpfs", "none", "/mnt"))
+	if err != nil {
+		t.Fatalf("expected mount to succeed with CAP_SYS_ADMIN, got %v: %s", err, out)
+	}
+
+	profile, err = inspectField("sysadmin", "SeccompProfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile == "default" || !strings.Contains(profile, "syscalls") || strings.Contains(profile, `"mount"`) {
+		t.Fatalf("expected the default seccomp profile without mount, got %q", profile)
+	}
+
+	out, _, err = runCommandWithOutput(exec.Command(dockerBinary, "run", "--name", "unconfined", "--security-opt", "seccomp=unconfined", "busybox", "grep", "Seccomp:", "/proc/self/status"))
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	if !strings.Contains(out, "0") {
+		t.Fatalf("expected no seccomp filter, got %s", out)
+	}
+
+	profile, err = inspectField("unconfined", "SeccompProfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile != "unconfined" {
+		t.Fatalf("expected the unconfined seccomp profile, got %q", profile)
+	}
+
+	logDone("run - seccomp default profile")
+}
+
+func TestRunSeccompProfileFromFile(t *testing.T) {
+	testRequires(t, NativeExecDriver, SameHostDaemon, Seccomp)
+	defer deleteAllContainers()
+
+	tmpDir, err := ioutil.TempDir("", "seccomp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	profile := `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"name": "chmod", "action": "SCMP_ACT_ERRNO"}, {"name": "fchmodat", "action": "SCMP_ACT_ERRNO"}]}`
+	profilePath := filepath.Join(tmpDir, "profile.json")
+	if err := ioutil.WriteFile(profilePath, []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--name", "seccomp", "--security-opt", "seccomp="+profilePath, "busybox", "chmod", "400", "/etc/hostname"))
+	if err == nil || !strings.Contains(out, "Operation not permitted") {
+		t.Fatalf("expected chmod to fail with EPERM, got %v: %s", err, out)
+	}
+
+	inspected, err := inspectField("seccomp", "SeccompProfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inspected != profile {
+		t.Fatalf("expected the custom seccomp profile, got %q", inspected)
+	}
+
+	logDone("run - seccomp profile from file")
+}
+
+func TestRunInit(t *testing.T) {
+	testRequires(t, NativeExecDriver)
+	defer deleteAllContainers()
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--init", "busybox", "ps", "-o", "pid,args"))
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	found := false
+	for _, line := range strings.Split(out, "\n") {
+		if fields := strings.Fields(line); len(fields) > 1 && fields[0] == "1" {
+			found = fields[1] == "/dev/init"
+		}
+	}
+	if !found {
+		t.Fatalf("expected the init to run as PID 1, got:\n%s", out)
+	}
+
+	// top ignores SIGTERM as PID 1, but not as a child of the init
+	out, _, err = runCommandWithOutput(exec.Command(dockerBinary, "run", "-d", "--name", "init", "--init", "busybox", "top"))
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "stop", "-t", "30", "init")); err != nil {
+		t.Fatal(out, err)
+	}
+	exitCode, err := inspectField("init", "State.ExitCode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exitCode != "143" {
+		t.Fatalf("expected top to exit on the forwarded SIGTERM with 143, got %s", exitCode)
+	}
+
+	logDone("run - init forwards signals")
+}
+
+func TestRunInitReapsZombies(t *testing.T) {
+	testRequires(t, NativeExecDriver)
+	defer deleteAllContainers()
+
+	// the orphaned sleep is reparented to the init once its shell exits
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--init", "busybox", "sh", "-c", "sh -c 'sleep 1 &'; sleep 2; cat /proc/[0-9]*/status"))
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	if strings.Contains(out, "zombie") {
+		t.Fatalf("expected zombies to be reaped, got:\n%s", out)
+	}
+
+	logDone("run - init reaps zombies")
+}
+
+func TestRunTmpfsMounts(t *testing.T) {
+	defer deleteAllContainers()
+
+	// the tmpfs is writable on a read-only rootfs, and exec is allowed back
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--name", "tmpfs", "--read-only", "--tmpfs", "/run:size=64k,mode=1777,exec", "busybox", "sh", "-c", "touch /run/file && grep ' /run ' /proc/mounts"))
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	for _, option := range []string{"tmpfs", "nosuid", "nodev", "size=64k", "mode=1777"} {
+		if !strings.Contains(out, option) {
+			t.Fatalf("expected the tmpfs mount to have %s, got %s", option, out)
+		}
+	}
+	if strings.Contains(out, "noexec") {
+		t.Fatalf("expected the tmpfs mount to allow exec, got %s", out)
+	}
+
+	tmpfs, err := inspectFieldJSON("tmpfs", "HostConfig.Tmpfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"/run":"size=64k,mode=1777,exec"}`; tmpfs != expected {
+		t.Fatalf("expected the tmpfs mounts %s in inspect, got %s", expected, tmpfs)
+	}
+
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--tmpfs", "/run:bind", "busybox", "true")); err == nil {
+		t.Fatalf("expected an error for an invalid tmpfs option, got %s", out)
+	}
+
+	logDone("run - tmpfs mounts")
+}
+
+func TestRunTmpfsMountsNoDefaults(t *testing.T) {
+	defer deleteAllContainers()
+
+	// all the default flags can be overridden at once
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--tmpfs", "/run:exec,suid,dev", "busybox", "grep", " /run ", "/proc/mounts"))
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	for _, option := range []string{"noexec", "nosuid", "nodev"} {
+		if strings.Contains(out, option) {
+			t.Fatalf("expected the tmpfs mount not to have %s, got %s", option, out)
+		}
+	}
+
+	logDone("run - tmpfs mounts without the default flags")
+}
+
+func TestRunTmpfsExcludedFromCommit(t *testing.T) {
+	defer deleteAllContainers()
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--name", "tmpfs", "--tmpfs", "/data", "busybox", "sh", "-c", "echo hello > /data/file"))
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	out, _, err = runCommandWithOutput(exec.Command(dockerBinary, "commit", "tmpfs", "tmpfscommit"))
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	defer deleteImages("tmpfscommit")
+
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "tmpfscommit", "ls", "/data/file")); err == nil {
+		t.Fatalf("expected the content of the tmpfs not to be committed, got %s", out)
+	}
+
+	logDone("run - tmpfs excluded from commit")
+}
+
+func TestRunBindMountPropagation(t *testing.T) {
+	testRequires(t, SameHostDaemon, NativeExecDriver)
+	defer deleteAllContainers()
+
+	tmpDir, err := ioutil.TempDir("", "docker-propagation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// the source must be a shared mount
+	if err := mount.MakeShared(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	defer mount.Unmount(tmpDir)
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "-d", "--name", "slave", "-v", tmpDir+":/data:ro,rslave", "busybox", "top"))
+	if err != nil {
+		t.Fatal(out, err)
+	}
+
+	// a mount made on the host after the container started shows up in it
+	subDir := filepath.Join(tmpDir, "sub")
+	if err := os.Mkdir(subDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := mount.Mount("tmpfs", subDir, "tmpfs", ""); err != nil {
+		t.Fatal(err)
+	}
+	defer mount.Unmount(subDir)
+	if err := ioutil.WriteFile(filepath.Join(subDir, "file"), []byte("propagated"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, _, err = runCommandWithOutput(exec.Command(dockerBinary, "exec", "slave", "cat", "/data/sub/file"))
+	if err != nil {
+		t.Fatal(out, err)
+	}
+	if strings.TrimSpace(out) != "propagated" {
+		t.Fatalf("expected the host mount to propagate to the container, got %s", out)
+	}
+
+	logDone("run - bind mount propagation")
+}
+
+func TestRunBindMountPropagationPrivateSource(t *testing.T) {
+	testRequires(t, SameHostDaemon, NativeExecDriver)
+	defer deleteAllContainers()
+
+	tmpDir, err := ioutil.TempDir("", "docker-propagation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := mount.MakePrivate(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	defer mount.Unmount(tmpDir)
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "-v", tmpDir+":/data:shared", "busybox", "true"))
+	if err == nil || !strings.Contains(out, "is not a shared mount") {
+		t.Fatalf("expected an error for a private source, got %s %v", out, err)
+	}
+
+	logDone("run - bind mount propagation needs a shared source")
+}
@@ -261,6 +261,108 @@ func TestSaveAndLoadRepoFlags(t *testing.T) {
 	logDone("save - save a repo using -o && load a repo using -i")
 }
 
+// save a repo compressed by docker, and load it
+func TestSaveCompressAndLoadRepo(t *testing.T) {
+	repoName := "foobar-save-load-test-compress"
+	defer deleteImages(repoName)
+
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "tag", "busybox", repoName)); err != nil {
+		t.Fatalf("failed to tag busybox: %s, %v", out, err)
+	}
+	before, _, err := runCommandWithOutput(exec.Command(dockerBinary, "inspect", repoName))
+	if err != nil {
+		t.Fatalf("the repo should exist before saving it: %s, %v", before, err)
+	}
+
+	compressions := map[string][]byte{
+		"gzip": {0x1F, 0x8B, 0x08},
+	}
+	if _, err := exec.LookPath("zstd"); err == nil {
+		compressions["zstd"] = []byte{0x28, 0xB5, 0x2F, 0xFD}
+	}
+	for compression, magic := range compressions {
+		repoTarball, _, err := runCommandWithOutput(exec.Command(dockerBinary, "save", "--compress="+compression, repoName))
+		if err != nil {
+			t.Fatalf("failed to save repo with %s: %v", compression, err)
+		}
+		if !strings.HasPrefix(repoTarball, string(magic)) {
+			t.Fatalf("expected the repo to be saved with %s", compression)
+		}
+
+		if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "rmi", repoName)); err != nil {
+			t.Fatalf("failed to remove the repo: %s, %v", out, err)
+		}
+
+		loadCmd := exec.Command(dockerBinary, "load")
+		loadCmd.Stdin = strings.NewReader(repoTarball)
+		if out, _, err := runCommandWithOutput(loadCmd); err != nil {
+			t.Fatalf("failed to load repo saved with %s: %s, %v", compression, out, err)
+		}
+
+		after, _, err := runCommandWithOutput(exec.Command(dockerBinary, "inspect", repoName))
+		if err != nil {
+			t.Fatalf("the repo should exist after loading it: %s, %v", after, err)
+		}
+		if before != after {
+			t.Fatalf("inspect is not the same after a save / load with %s", compression)
+		}
+	}
+
+	out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "save", "--compress=lz4", repoName))
+	if err == nil || !strings.Contains(out, "Unsupported compression") {
+		t.Fatalf("expected saving with an unsupported compression to fail, got %s, %v", out, err)
+	}
+
+	logDone("save - save a repo with --compress and load it")
+}
+
+// save repos as an OCI image layout, and load them
+func TestSaveOCIAndLoadRepo(t *testing.T) {
+	repoName := "foobar-save-load-test-oci"
+	defer deleteImages(repoName + ":first")
+	defer deleteImages(repoName + ":second")
+
+	for _, tag := range []string{"first", "second"} {
+		if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "tag", "busybox", repoName+":"+tag)); err != nil {
+			t.Fatalf("failed to tag busybox: %s, %v", out, err)
+		}
+	}
+
+	tmpDir, err := ioutil.TempDir("", "save-oci-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	layout := filepath.Join(tmpDir, "layout.tar")
+
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "save", "--format=oci", "-o", layout, repoName)); err != nil {
+		t.Fatalf("failed to save repo: %s, %v", out, err)
+	}
+	out, _, err := runCommandWithOutput(exec.Command("tar", "tf", layout))
+	if err != nil {
+		t.Fatalf("failed to list the layout: %s, %v", out, err)
+	}
+	for _, name := range []string{"oci-layout", "index.json", "blobs/sha256/"} {
+		if !strings.Contains(out, name) {
+			t.Fatalf("expected %s in the layout, got %s", name, out)
+		}
+	}
+
+	deleteImages(repoName + ":first")
+	deleteImages(repoName + ":second")
+
+	if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "load", "-i", layout)); err != nil {
+		t.Fatalf("failed to load the layout: %s, %v", out, err)
+	}
+	for _, tag := range []string{"first", "second"} {
+		if out, _, err := runCommandWithOutput(exec.Command(dockerBinary, "run", "--rm", repoName+":"+tag, "true")); err != nil {
+			t.Fatalf("failed to run the loaded image %s: %s, %v", tag, out, err)
+		}
+	}
+
+	logDone("save - save repos as an OCI image layout and load them")
+}
+
 func TestSaveMultipleNames(t *testing.T) {
 	repoName := "foobar-save-multi-name-test"
 
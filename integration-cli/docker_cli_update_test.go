@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestUpdateNetworkRate(t *testing.T) {
+	defer deleteAllContainers()
+	testRequires(t, SameHostDaemon)
+
+	runCmd := exec.Command(dockerBinary, "run", "-d", "--name", "shaped", "--network-ingress-rate=1mbit", "busybox", "top")
+	if out, _, err := runCommandWithOutput(runCmd); err != nil {
+		t.Fatal(out, err)
+	}
+
+	rate, err := inspectField("shaped", "NetworkShaping.IngressRate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate != "1000000" {
+		t.Fatalf("Expected the traffic to be shaped to 1mbit, got %s", rate)
+	}
+
+	updateCmd := exec.Command(dockerBinary, "update", "--network-ingress-rate=10mbit", "shaped")
+	if out, _, err := runCommandWithOutput(updateCmd); err != nil {
+		t.Fatal(out, err)
+	}
+	if rate, err = inspectField("shaped", "NetworkShaping.IngressRate"); err != nil {
+		t.Fatal(err)
+	}
+	if rate != "10000000" {
+		t.Fatalf("Expected the shaping to be updated to 10mbit, got %s", rate)
+	}
+	if rate, err = inspectField("shaped", "HostConfig.NetworkIngressRate"); err != nil {
+		t.Fatal(err)
+	}
+	if rate != "10000000" {
+		t.Fatalf("Expected the host config to be updated, got %s", rate)
+	}
+
+	updateCmd = exec.Command(dockerBinary, "update", "--network-ingress-rate=0", "shaped")
+	if out, _, err := runCommandWithOutput(updateCmd); err != nil {
+		t.Fatal(out, err)
+	}
+	if shaping, err := inspectFieldJSON("shaped", "NetworkShaping"); err != nil || shaping != "null" {
+		t.Fatalf("Expected the shaping to be removed, got %s, %v", shaping, err)
+	}
+
+	logDone("update - network rate of a running container")
+}
+
+func TestUpdateNetworkRateHostNetwork(t *testing.T) {
+	defer deleteAllContainers()
+
+	runCmd := exec.Command(dockerBinary, "run", "-d", "--net=host", "--name", "unshaped", "busybox", "top")
+	if out, _, err := runCommandWithOutput(runCmd); err != nil {
+		t.Fatal(out, err)
+	}
+
+	updateCmd := exec.Command(dockerBinary, "update", "--network-egress-rate=10mbit", "unshaped")
+	out, _, err := runCommandWithOutput(updateCmd)
+	if err == nil || !strings.Contains(out, "require the bridge network mode") {
+		t.Fatalf("Expected the update to fail with the host network, got %s, %v", out, err)
+	}
+
+	logDone("update - network rate requires the bridge")
+}
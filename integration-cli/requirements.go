@@ -67,6 +67,32 @@ var (
 		"Test requires the native (libcontainer) exec driver.",
 	}
 
+	PidsLimit = TestRequirement{
+		func() bool {
+			body, err := sockRequest("GET", "/info", nil)
+			if err != nil {
+				log.Fatalf("sockRequest failed for /info: %v", err)
+			}
+
+			var info struct {
+				PidsLimit bool
+			}
+			if err = json.Unmarshal(body, &info); err != nil {
+				log.Fatalf("unable to unmarshal body: %v", err)
+			}
+			return info.PidsLimit
+		},
+		"Test requires the pids cgroup controller on the tested daemon.",
+	}
+
+	Seccomp = TestRequirement{
+		func() bool {
+			cmd := exec.Command("grep", "^Seccomp:", "/proc/self/status")
+			return cmd.Run() == nil
+		},
+		"Test requires a kernel with seccomp support.",
+	}
+
 	NotOverlay = TestRequirement{
 		func() bool {
 			cmd := exec.Command("grep", "^overlay / overlay", "/proc/mounts")
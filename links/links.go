@@ -157,6 +157,21 @@ func (l *Link) Disable() {
 	l.IsEnabled = false
 }
 
+// Update sets the addresses of the containers of the link, which change
+// when they restart, and refreshes the rules of the link when it is enabled.
+func (l *Link) Update(parentIP, childIP string) error {
+	enabled := l.IsEnabled
+	if enabled {
+		l.Disable()
+	}
+	l.ParentIP = parentIP
+	l.ChildIP = childIP
+	if enabled {
+		return l.Enable()
+	}
+	return nil
+}
+
 func (l *Link) toggle(action string, ignoreErrors bool) error {
 	job := l.eng.Job("link", action)
 
@@ -2,9 +2,11 @@ package links
 
 import (
 	"fmt"
-	"github.com/docker/docker/nat"
 	"strings"
 	"testing"
+
+	"github.com/docker/docker/engine"
+	"github.com/docker/docker/nat"
 )
 
 func TestLinkNaming(t *testing.T) {
@@ -225,3 +227,45 @@ func TestLinkPortRangeEnv(t *testing.T) {
 		}
 	}
 }
+
+func TestLinkUpdate(t *testing.T) {
+	var jobs []string
+	eng := engine.New()
+	eng.Register("link", func(job *engine.Job) engine.Status {
+		jobs = append(jobs, fmt.Sprintf("%s %s %s", job.Args[0], job.Getenv("ParentIP"), job.Getenv("ChildIP")))
+		return engine.StatusOK
+	})
+
+	ports := make(nat.PortSet)
+	ports[nat.Port("6379/tcp")] = struct{}{}
+
+	link, err := NewLink("172.0.17.3", "172.0.17.2", "/db/docker", nil, ports, eng)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the rules of a disabled link are left alone
+	if err := link.Update("172.0.17.3", "172.0.17.4"); err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 0 || link.ChildIP != "172.0.17.4" {
+		t.Fatalf("Expected the child IP to be updated without rules, got %v and %s", jobs, link.ChildIP)
+	}
+
+	if err := link.Enable(); err != nil {
+		t.Fatal(err)
+	}
+	if err := link.Update("172.0.17.3", "172.0.17.5"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"-A 172.0.17.3 172.0.17.4", "-D 172.0.17.3 172.0.17.4", "-A 172.0.17.3 172.0.17.5"}
+	if fmt.Sprint(jobs) != fmt.Sprint(expected) {
+		t.Fatalf("Expected the jobs %v, got %v", expected, jobs)
+	}
+	if !link.IsEnabled {
+		t.Fatal("Expected the link to stay enabled")
+	}
+	if env := link.ToEnv(); env[0] != "DOCKER_PORT=tcp://172.0.17.5:6379" {
+		t.Fatalf("Expected the environment of the new child IP, got %s", env[0])
+	}
+}
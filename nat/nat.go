@@ -99,23 +99,32 @@ func ParsePortSpecs(ports []string) (map[Port]struct{}, map[Port][]PortBinding,
 			proto = rawPort[i+1:]
 			rawPort = rawPort[:i]
 		}
-		if !strings.Contains(rawPort, ":") {
-			rawPort = fmt.Sprintf("::%s", rawPort)
-		} else if len(strings.Split(rawPort, ":")) == 2 {
-			rawPort = fmt.Sprintf(":%s", rawPort)
-		}
+		var containerPort, rawIp, hostPort string
+		if strings.HasPrefix(rawPort, "[") {
+			// [ipv6]:hostPort:containerPort or [ipv6]::containerPort
+			end := strings.Index(rawPort, "]:")
+			if end == -1 {
+				return nil, nil, fmt.Errorf("Invalid ip address: %s", rawPort)
+			}
+			parts := strings.Split(rawPort[end+2:], ":")
+			if len(parts) != 2 {
+				return nil, nil, fmt.Errorf("Invalid port format %s: must be [ip]:hostPort:containerPort or [ip]::containerPort", rawPort)
+			}
+			rawIp, hostPort, containerPort = rawPort[1:end], parts[0], parts[1]
+		} else {
+			if !strings.Contains(rawPort, ":") {
+				rawPort = fmt.Sprintf("::%s", rawPort)
+			} else if len(strings.Split(rawPort, ":")) == 2 {
+				rawPort = fmt.Sprintf(":%s", rawPort)
+			}
 
-		parts, err := parsers.PartParser(PortSpecTemplate, rawPort)
-		if err != nil {
-			return nil, nil, err
+			parts, err := parsers.PartParser(PortSpecTemplate, rawPort)
+			if err != nil {
+				return nil, nil, err
+			}
+			containerPort, rawIp, hostPort = parts["containerPort"], parts["ip"], parts["hostPort"]
 		}
 
-		var (
-			containerPort = parts["containerPort"]
-			rawIp         = parts["ip"]
-			hostPort      = parts["hostPort"]
-		)
-
 		if rawIp != "" && net.ParseIP(rawIp) == nil {
 			return nil, nil, fmt.Errorf("Invalid ip address: %s", rawIp)
 		}
@@ -200,6 +200,35 @@ func TestParsePortSpecs(t *testing.T) {
 	}
 }
 
+func TestParsePortSpecsIPv6(t *testing.T) {
+	portMap, bindingMap, err := ParsePortSpecs([]string{"[::]:1234:1234/tcp", "[2001:db8::1]::2345/udp"})
+	if err != nil {
+		t.Fatalf("Error while processing ParsePortSpecs: %s", err)
+	}
+
+	if _, ok := portMap[Port("1234/tcp")]; !ok {
+		t.Fatal("1234/tcp was not parsed properly")
+	}
+
+	if _, ok := portMap[Port("2345/udp")]; !ok {
+		t.Fatal("2345/udp was not parsed properly")
+	}
+
+	if b := bindingMap[Port("1234/tcp")]; len(b) != 1 || b[0].HostIp != "::" || b[0].HostPort != "1234" {
+		t.Fatalf("Wrong bindings for 1234/tcp: %v", b)
+	}
+
+	if b := bindingMap[Port("2345/udp")]; len(b) != 1 || b[0].HostIp != "2001:db8::1" || b[0].HostPort != "" {
+		t.Fatalf("Wrong bindings for 2345/udp: %v", b)
+	}
+
+	for _, spec := range []string{"[::1]", "[::1]:1234", "[::1:1234:1234", "[not-an-ip]:1234:1234", "[::1]:1:2:3"} {
+		if _, _, err := ParsePortSpecs([]string{spec}); err == nil {
+			t.Fatalf("Received no error while trying to parse %s", spec)
+		}
+	}
+}
+
 func TestParsePortSpecsWithRange(t *testing.T) {
 	var (
 		portMap    map[Port]struct{}
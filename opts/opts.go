@@ -17,6 +17,7 @@ import (
 
 var (
 	alphaRegexp  = regexp.MustCompile(`[a-zA-Z]`)
+	dnsOptRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9\-]*(:[0-9]+)?$`)
 	domainRegexp = regexp.MustCompile(`^(:?(:?[a-zA-Z0-9]|(:?[a-zA-Z0-9][a-zA-Z0-9\-]*[a-zA-Z0-9]))(:?\.(:?[a-zA-Z0-9]|(:?[a-zA-Z0-9][a-zA-Z0-9\-]*[a-zA-Z0-9])))*)\.?\s*$`)
 )
 
@@ -36,6 +37,10 @@ func DnsSearchListVar(values *[]string, names []string, usage string) {
 	flag.Var(newListOptsRef(values, ValidateDnsSearch), names, usage)
 }
 
+func DnsOptListVar(values *[]string, names []string, usage string) {
+	flag.Var(newListOptsRef(values, ValidateDnsOpt), names, usage)
+}
+
 func IPVar(value *net.IP, names []string, defaultValue, usage string) {
 	flag.Var(NewIpOpt(value, defaultValue), names, usage)
 }
@@ -206,6 +211,15 @@ func ValidateDnsSearch(val string) (string, error) {
 	return validateDomain(val)
 }
 
+// Validates an option of the resolver, such as ndots:2 or rotate, for the
+// options line of resolv.conf.
+func ValidateDnsOpt(val string) (string, error) {
+	if !dnsOptRegexp.MatchString(val) {
+		return "", fmt.Errorf("%s is not a valid DNS option", val)
+	}
+	return val, nil
+}
+
 func validateDomain(val string) (string, error) {
 	if alphaRegexp.FindString(val) == "" {
 		return "", fmt.Errorf("%s is not a valid domain", val)
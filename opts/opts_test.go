@@ -120,6 +120,39 @@ func TestValidateDnsSearch(t *testing.T) {
 	}
 }
 
+func TestValidateDnsOpt(t *testing.T) {
+	valid := []string{
+		`ndots:2`,
+		`timeout:1`,
+		`rotate`,
+		`single-request-reopen`,
+		`edns0`,
+	}
+
+	invalid := []string{
+		``,
+		` `,
+		`ndots:`,
+		`ndots: 2`,
+		`ndots:two`,
+		`rotate timeout:1`,
+		`-rotate`,
+		`Rotate`,
+	}
+
+	for _, opt := range valid {
+		if ret, err := ValidateDnsOpt(opt); err != nil || ret != opt {
+			t.Fatalf("ValidateDnsOpt(`"+opt+"`) got %s %s", ret, err)
+		}
+	}
+
+	for _, opt := range invalid {
+		if ret, err := ValidateDnsOpt(opt); err == nil || ret != "" {
+			t.Fatalf("ValidateDnsOpt(`"+opt+"`) got %s %s", ret, err)
+		}
+	}
+}
+
 func TestValidateExtraHosts(t *testing.T) {
 	valid := []string{
 		`myhost:192.168.0.1`,
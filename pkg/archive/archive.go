@@ -20,6 +20,8 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/docker/pkg/fileutils"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/pools"
 	"github.com/docker/docker/pkg/promise"
 	"github.com/docker/docker/pkg/system"
@@ -35,12 +37,17 @@ type (
 		Compression     Compression
 		NoLchown        bool
 		Name            string
+		UIDMaps         []idtools.IDMap
+		GIDMaps         []idtools.IDMap
 	}
 
 	// Archiver allows the reuse of most utility functions of this package
-	// with a pluggable Untar function.
+	// with a pluggable Untar function. UntarPath shifts the ownership of the
+	// unpacked files according to UIDMaps and GIDMaps.
 	Archiver struct {
-		Untar func(io.Reader, string, *TarOptions) error
+		Untar   func(io.Reader, string, *TarOptions) error
+		UIDMaps []idtools.IDMap
+		GIDMaps []idtools.IDMap
 	}
 
 	// breakoutError is used to differentiate errors related to breaking out
@@ -51,7 +58,7 @@ type (
 
 var (
 	ErrNotImplemented = errors.New("Function not implemented")
-	defaultArchiver   = &Archiver{Untar}
+	defaultArchiver   = &Archiver{Untar: Untar}
 )
 
 const (
@@ -59,6 +66,7 @@ const (
 	Bzip2
 	Gzip
 	Xz
+	Zstd
 )
 
 func IsArchive(header []byte) bool {
@@ -76,6 +84,7 @@ func DetectCompression(source []byte) Compression {
 		Bzip2: {0x42, 0x5A, 0x68},
 		Gzip:  {0x1F, 0x8B, 0x08},
 		Xz:    {0xFD, 0x37, 0x7A, 0x58, 0x5A, 0x00},
+		Zstd:  {0x28, 0xB5, 0x2F, 0xFD},
 	} {
 		if len(source) < len(m) {
 			log.Debugf("Len too short")
@@ -94,6 +103,28 @@ func xzDecompress(archive io.Reader) (io.ReadCloser, error) {
 	return CmdStream(exec.Command(args[0], args[1:]...), archive)
 }
 
+// ErrZstdNotFound is returned when the zstd program, which compresses and
+// decompresses the zstd archives, is not installed.
+var ErrZstdNotFound = errors.New("zstd compression needs the zstd program, which is not installed")
+
+func zstdDecompress(archive io.Reader) (io.ReadCloser, error) {
+	args := []string{"zstd", "-d", "-c", "-q"}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil, ErrZstdNotFound
+	}
+
+	return CmdStream(exec.Command(args[0], args[1:]...), archive)
+}
+
+func zstdCompress(dest io.Writer) (io.WriteCloser, error) {
+	args := []string{"zstd", "-c", "-q"}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil, ErrZstdNotFound
+	}
+
+	return cmdWriter(exec.Command(args[0], args[1:]...), dest)
+}
+
 func DecompressStream(archive io.Reader) (io.ReadCloser, error) {
 	p := pools.BufioReader32KPool
 	buf := p.Get(archive)
@@ -125,6 +156,13 @@ func DecompressStream(archive io.Reader) (io.ReadCloser, error) {
 		}
 		readBufWrapper := p.NewReadCloserWrapper(buf, xzReader)
 		return readBufWrapper, nil
+	case Zstd:
+		zstdReader, err := zstdDecompress(buf)
+		if err != nil {
+			return nil, err
+		}
+		readBufWrapper := p.NewReadCloserWrapper(buf, zstdReader)
+		return readBufWrapper, nil
 	default:
 		return nil, fmt.Errorf("Unsupported compression format %s", (&compression).Extension())
 	}
@@ -141,6 +179,13 @@ func CompressStream(dest io.WriteCloser, compression Compression) (io.WriteClose
 		gzWriter := gzip.NewWriter(dest)
 		writeBufWrapper := p.NewWriteCloserWrapper(buf, gzWriter)
 		return writeBufWrapper, nil
+	case Zstd:
+		zstdWriter, err := zstdCompress(dest)
+		if err != nil {
+			return nil, err
+		}
+		writeBufWrapper := p.NewWriteCloserWrapper(buf, zstdWriter)
+		return writeBufWrapper, nil
 	case Bzip2, Xz:
 		// archive/bzip2 does not support writing, and there is no xz support at all
 		// However, this is not a problem as docker only currently generates gzipped tars
@@ -150,6 +195,19 @@ func CompressStream(dest io.WriteCloser, compression Compression) (io.WriteClose
 	}
 }
 
+// ParallelCompressStream is like CompressStream, but compresses the blocks
+// of large gzip streams, such as the layers pushed to a registry, on all
+// the cpus at once. The output is a gzip stream of several members.
+func ParallelCompressStream(dest io.WriteCloser, compression Compression) (io.WriteCloser, error) {
+	if compression != Gzip {
+		return CompressStream(dest, compression)
+	}
+	p := pools.BufioWriter32KPool
+	buf := p.Get(dest)
+	gzWriter := newParallelGzipWriter(dest, defaultGzipBlockSize)
+	return p.NewWriteCloserWrapper(buf, gzWriter), nil
+}
+
 func (compression *Compression) Extension() string {
 	switch *compression {
 	case Uncompressed:
@@ -160,16 +218,36 @@ func (compression *Compression) Extension() string {
 		return "tar.gz"
 	case Xz:
 		return "tar.xz"
+	case Zstd:
+		return "tar.zst"
 	}
 	return ""
 }
 
+// ParseCompression returns the compression named name, one of none, gzip
+// and zstd, the formats docker can write.
+func ParseCompression(name string) (Compression, error) {
+	switch strings.ToLower(name) {
+	case "none":
+		return Uncompressed, nil
+	case "gzip":
+		return Gzip, nil
+	case "zstd":
+		return Zstd, nil
+	}
+	return Uncompressed, fmt.Errorf("Unsupported compression %s: must be none, gzip or zstd", name)
+}
+
 type tarAppender struct {
 	TarWriter *tar.Writer
 	Buffer    *bufio.Writer
 
 	// for hardlink mapping
 	SeenFiles map[uint64]string
+
+	// for remapping ownership back to container ids
+	UIDMaps []idtools.IDMap
+	GIDMaps []idtools.IDMap
 }
 
 // canonicalTarName provides a platform-independent and consistent posix-style
@@ -230,6 +308,17 @@ func (ta *tarAppender) addTarFile(path, name string) error {
 		}
 	}
 
+	// translate the host ownership of files created by a remapped root
+	// back to the ids seen inside the container
+	if ta.UIDMaps != nil || ta.GIDMaps != nil {
+		if hdr.Uid, err = idtools.ToContainer(hdr.Uid, ta.UIDMaps); err != nil {
+			return err
+		}
+		if hdr.Gid, err = idtools.ToContainer(hdr.Gid, ta.GIDMaps); err != nil {
+			return err
+		}
+	}
+
 	capability, _ := system.Lgetxattr(path, "security.capability")
 	if capability != nil {
 		hdr.Xattrs = make(map[string]string)
@@ -369,6 +458,24 @@ func createTarFile(path, extractDir string, hdr *tar.Header, reader io.Reader, L
 	return nil
 }
 
+// remapIDs translates the container ownership recorded in hdr to the host
+// ids of a remapped root, if any maps are provided.
+func remapIDs(hdr *tar.Header, uidMaps, gidMaps []idtools.IDMap) error {
+	if uidMaps == nil && gidMaps == nil {
+		return nil
+	}
+	uid, err := idtools.ToHost(hdr.Uid, uidMaps)
+	if err != nil {
+		return err
+	}
+	gid, err := idtools.ToHost(hdr.Gid, gidMaps)
+	if err != nil {
+		return err
+	}
+	hdr.Uid, hdr.Gid = uid, gid
+	return nil
+}
+
 // Tar creates an archive from the directory at `path`, and returns it as a
 // stream of bytes.
 func Tar(path string, compression Compression) (io.ReadCloser, error) {
@@ -406,6 +513,8 @@ func TarWithOptions(srcPath string, options *TarOptions) (io.ReadCloser, error)
 			TarWriter: tar.NewWriter(compressWriter),
 			Buffer:    pools.BufioWriter32KPool.Get(nil),
 			SeenFiles: make(map[uint64]string),
+			UIDMaps:   options.UIDMaps,
+			GIDMaps:   options.GIDMaps,
 		}
 		// this buffer is needed for the duration of this piped stream
 		defer pools.BufioWriter32KPool.Put(ta.Buffer)
@@ -558,6 +667,10 @@ loop:
 				}
 			}
 		}
+		if err := remapIDs(hdr, options.UIDMaps, options.GIDMaps); err != nil {
+			return err
+		}
+
 		trBuf.Reset(tr)
 		if err := createTarFile(path, dest, hdr, trBuf, !options.NoLchown); err != nil {
 			return err
@@ -626,7 +739,11 @@ func (archiver *Archiver) UntarPath(src, dst string) error {
 		return err
 	}
 	defer archive.Close()
-	if err := archiver.Untar(archive, dst, nil); err != nil {
+	options := &TarOptions{
+		UIDMaps: archiver.UIDMaps,
+		GIDMaps: archiver.GIDMaps,
+	}
+	if err := archiver.Untar(archive, dst, options); err != nil {
 		return err
 	}
 	return nil
@@ -777,6 +894,29 @@ func CmdStream(cmd *exec.Cmd, input io.Reader) (io.ReadCloser, error) {
 	return pipeR, nil
 }
 
+// cmdWriter executes a command writing its stdout to output, and returns
+// its stdin. Closing it waits for the command to complete, returning an
+// error including anything written on stderr if it didn't succeed.
+func cmdWriter(cmd *exec.Cmd, output io.Writer) (io.WriteCloser, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd.Stdout = output
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return ioutils.NewWriteCloserWrapper(stdin, func() error {
+		stdin.Close()
+		if err := cmd.Wait(); err != nil {
+			return fmt.Errorf("%s: %s", err, stderr.String())
+		}
+		return nil
+	}), nil
+}
+
 // NewTempArchive reads the content of src into a temporary file, and returns the contents
 // of that file as an archive. The archive can only be read once - as soon as reading completes,
 // the file will be deleted.
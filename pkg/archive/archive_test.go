@@ -15,6 +15,9 @@ import (
 	"time"
 
 	"github.com/docker/docker/vendor/src/code.google.com/p/go/src/pkg/archive/tar"
+
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/ioutils"
 )
 
 func TestCmdStreamLargeStderr(t *testing.T) {
@@ -160,10 +163,14 @@ func TestTarUntar(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	for _, c := range []Compression{
+	compressions := []Compression{
 		Uncompressed,
 		Gzip,
-	} {
+	}
+	if _, err := exec.LookPath("zstd"); err == nil {
+		compressions = append(compressions, Zstd)
+	}
+	for _, c := range compressions {
 		changes, err := tarUntar(t, origin, &TarOptions{
 			Compression:     c,
 			ExcludePatterns: []string{"3"},
@@ -211,6 +218,66 @@ func TestTarWithOptions(t *testing.T) {
 	}
 }
 
+func TestTarUntarWithIDMaps(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("chown requires root")
+	}
+	origin, err := ioutil.TempDir("", "docker-test-untar-idmaps-origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(origin)
+	dest, err := ioutil.TempDir("", "docker-test-untar-idmaps-dest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	file := path.Join(origin, "1")
+	if err := ioutil.WriteFile(file, []byte("hello world"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(file, 100001, 100002); err != nil {
+		t.Fatal(err)
+	}
+
+	idMaps := []idtools.IDMap{{ContainerID: 0, HostID: 100000, Size: 65536}}
+	archive, err := TarWithOptions(origin, &TarOptions{
+		Compression:  Uncompressed,
+		IncludeFiles: []string{"1"},
+		UIDMaps:      idMaps,
+		GIDMaps:      idMaps,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := ioutil.ReadAll(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tar.NewReader(bytes.NewReader(buf))
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Uid != 1 || hdr.Gid != 2 {
+		t.Fatalf("Expected archived ownership 1:2, got %d:%d", hdr.Uid, hdr.Gid)
+	}
+
+	if err := Untar(bytes.NewReader(buf), dest, &TarOptions{UIDMaps: idMaps, GIDMaps: idMaps}); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path.Join(dest, "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := fi.Sys().(*syscall.Stat_t)
+	if st.Uid != 100001 || st.Gid != 100002 {
+		t.Fatalf("Expected unpacked ownership 100001:100002, got %d:%d", st.Uid, st.Gid)
+	}
+}
+
 // Some tar archives such as http://haproxy.1wt.eu/download/1.5/src/devel/haproxy-1.5-dev21.tar.gz
 // use PAX Global Extended Headers.
 // Failing prevents the archives from being uncompressed during ADD
@@ -623,3 +690,15 @@ func TestTempArchiveCloseMultipleTimes(t *testing.T) {
 		}
 	}
 }
+
+func TestZstdNotFound(t *testing.T) {
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", "")
+
+	if _, err := CompressStream(ioutils.NopWriteCloser(ioutil.Discard), Zstd); err != ErrZstdNotFound {
+		t.Fatalf("Expected ErrZstdNotFound, got %v", err)
+	}
+	if _, err := DecompressStream(bytes.NewReader([]byte{0x28, 0xB5, 0x2F, 0xFD, 0, 0, 0, 0, 0, 0})); err != ErrZstdNotFound {
+		t.Fatalf("Expected ErrZstdNotFound, got %v", err)
+	}
+}
@@ -15,7 +15,14 @@ import (
 	"github.com/docker/docker/pkg/system"
 )
 
-func UnpackLayer(dest string, layer ArchiveReader) (size int64, err error) {
+// UnpackLayer unpacks `layer` to a `dest`. The stream `layer` can be
+// compressed or uncompressed.
+// Returns the size in bytes of the contents of the layer.
+func UnpackLayer(dest string, layer ArchiveReader, options *TarOptions) (size int64, err error) {
+	if options == nil {
+		options = &TarOptions{}
+	}
+
 	tr := tar.NewReader(layer)
 	trBuf := pools.BufioReader32KPool.Get(tr)
 	defer pools.BufioReader32KPool.Put(trBuf)
@@ -69,6 +76,9 @@ func UnpackLayer(dest string, layer ArchiveReader) (size int64, err error) {
 					}
 					defer os.RemoveAll(aufsTempdir)
 				}
+				if err := remapIDs(hdr, options.UIDMaps, options.GIDMaps); err != nil {
+					return 0, err
+				}
 				if err := createTarFile(filepath.Join(aufsTempdir, basename), dest, hdr, tr, true); err != nil {
 					return 0, err
 				}
@@ -109,6 +119,10 @@ func UnpackLayer(dest string, layer ArchiveReader) (size int64, err error) {
 			srcData := io.Reader(trBuf)
 			srcHdr := hdr
 
+			if err := remapIDs(srcHdr, options.UIDMaps, options.GIDMaps); err != nil {
+				return 0, err
+			}
+
 			// Hard links into /.wh..wh.plnk don't work, as we don't extract that directory, so
 			// we manually retarget these into the temporary files we extracted them into
 			if hdr.Typeflag == tar.TypeLink && strings.HasPrefix(filepath.Clean(hdr.Linkname), ".wh..wh.plnk") {
@@ -152,6 +166,12 @@ func UnpackLayer(dest string, layer ArchiveReader) (size int64, err error) {
 // applies it to the directory `dest`. Returns the size in bytes of the
 // contents of the layer.
 func ApplyLayer(dest string, layer ArchiveReader) (int64, error) {
+	return ApplyLayerWithOptions(dest, layer, nil)
+}
+
+// ApplyLayerWithOptions is like ApplyLayer, but shifts the ownership of the
+// unpacked files according to the id maps in `options`.
+func ApplyLayerWithOptions(dest string, layer ArchiveReader, options *TarOptions) (int64, error) {
 	dest = filepath.Clean(dest)
 
 	// We need to be able to set any perms
@@ -165,5 +185,5 @@ func ApplyLayer(dest string, layer ArchiveReader) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return UnpackLayer(dest, layer)
+	return UnpackLayer(dest, layer, options)
 }
@@ -0,0 +1,114 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// defaultGzipBlockSize is the size of the blocks of input a parallel gzip
+// writer compresses concurrently.
+const defaultGzipBlockSize = 1 << 20
+
+// parallelGzipWriter compresses the blocks of its input concurrently, each
+// one as a gzip member of its output. Gzip readers read the members in
+// sequence as a single stream.
+type parallelGzipWriter struct {
+	blockSize int
+	buf       []byte
+	// whether a block was queued, an empty input is still a gzip member
+	queued bool
+	// the compressed blocks, in the order of the input, are written to
+	// the output by a single goroutine
+	queue chan chan []byte
+	done  chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+func newParallelGzipWriter(w io.Writer, blockSize int) *parallelGzipWriter {
+	z := &parallelGzipWriter{
+		blockSize: blockSize,
+		buf:       make([]byte, 0, blockSize),
+		// as many blocks as cpus are compressed at once
+		queue: make(chan chan []byte, runtime.NumCPU()),
+		done:  make(chan struct{}),
+	}
+	go z.writeBlocks(w)
+	return z
+}
+
+func (z *parallelGzipWriter) writeBlocks(w io.Writer) {
+	defer close(z.done)
+	for block := range z.queue {
+		compressed := <-block
+		if z.getErr() != nil {
+			// drain the queue, so that writes don't block
+			continue
+		}
+		if _, err := w.Write(compressed); err != nil {
+			z.setErr(err)
+		}
+	}
+}
+
+func (z *parallelGzipWriter) getErr() error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	return z.err
+}
+
+func (z *parallelGzipWriter) setErr(err error) {
+	z.mu.Lock()
+	z.err = err
+	z.mu.Unlock()
+}
+
+// compress queues the buffered input to be compressed.
+func (z *parallelGzipWriter) compress() {
+	block := make(chan []byte, 1)
+	go func(data []byte) {
+		var out bytes.Buffer
+		gz := gzip.NewWriter(&out)
+		// writes to a bytes.Buffer don't fail
+		gz.Write(data)
+		gz.Close()
+		block <- out.Bytes()
+	}(z.buf)
+	z.queue <- block
+	z.queued = true
+	z.buf = make([]byte, 0, z.blockSize)
+}
+
+func (z *parallelGzipWriter) Write(p []byte) (int, error) {
+	if err := z.getErr(); err != nil {
+		return 0, err
+	}
+	n := len(p)
+	for len(p) > 0 {
+		l := z.blockSize - len(z.buf)
+		if l > len(p) {
+			l = len(p)
+		}
+		z.buf = append(z.buf, p[:l]...)
+		p = p[l:]
+		if len(z.buf) == z.blockSize {
+			z.compress()
+		}
+	}
+	return n, nil
+}
+
+// Close compresses the rest of the input, and waits for all of it to be
+// written.
+func (z *parallelGzipWriter) Close() error {
+	if len(z.buf) > 0 || !z.queued {
+		z.compress()
+	}
+	close(z.queue)
+	<-z.done
+	return z.getErr()
+}
@@ -0,0 +1,67 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+)
+
+func TestParallelGzipWriter(t *testing.T) {
+	data := make([]byte, 5*1024+100)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	for _, size := range []int{0, 1, 1024, len(data)} {
+		var buf bytes.Buffer
+		w := newParallelGzipWriter(&buf, 1024)
+		// write in pieces not aligned on the blocks
+		for p := data[:size]; len(p) > 0; {
+			n := 300
+			if n > len(p) {
+				n = len(p)
+			}
+			if _, err := w.Write(p[:n]); err != nil {
+				t.Fatal(err)
+			}
+			p = p[n:]
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := gzip.NewReader(&buf)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		out, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		if !bytes.Equal(out, data[:size]) {
+			t.Fatalf("%d bytes: the uncompressed output differs from the input", size)
+		}
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	for name, expected := range map[string]Compression{
+		"none": Uncompressed,
+		"gzip": Gzip,
+		"GZIP": Gzip,
+		"zstd": Zstd,
+	} {
+		c, err := ParseCompression(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != expected {
+			t.Fatalf("Expected %s for %s, got %s", expected.Extension(), name, c.Extension())
+		}
+	}
+	for _, name := range []string{"", "xz", "bzip2"} {
+		if _, err := ParseCompression(name); err == nil {
+			t.Fatalf("Expected an error for %q", name)
+		}
+	}
+}
@@ -11,11 +11,22 @@ import (
 	"syscall"
 
 	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/reexec"
 )
 
 var chrootArchiver = &archive.Archiver{Untar: Untar}
 
+// NewArchiver returns an archiver that unpacks in a chroot and shifts the
+// ownership of unpacked archives according to the given id maps.
+func NewArchiver(uidMaps, gidMaps []idtools.IDMap) *archive.Archiver {
+	return &archive.Archiver{
+		Untar:   Untar,
+		UIDMaps: uidMaps,
+		GIDMaps: gidMaps,
+	}
+}
+
 func chroot(path string) error {
 	if err := syscall.Chroot(path); err != nil {
 		return err
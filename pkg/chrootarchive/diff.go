@@ -23,6 +23,11 @@ func applyLayer() {
 	runtime.LockOSThread()
 	flag.Parse()
 
+	var options *archive.TarOptions
+	if err := json.Unmarshal([]byte(os.Getenv("OPT")), &options); err != nil {
+		fatal(err)
+	}
+
 	if err := chroot(flag.Arg(0)); err != nil {
 		fatal(err)
 	}
@@ -36,7 +41,7 @@ func applyLayer() {
 	}
 
 	os.Setenv("TMPDIR", tmpDir)
-	size, err := archive.UnpackLayer("/", os.Stdin)
+	size, err := archive.UnpackLayer("/", os.Stdin, options)
 	os.RemoveAll(tmpDir)
 	if err != nil {
 		fatal(err)
@@ -53,7 +58,21 @@ func applyLayer() {
 }
 
 func ApplyLayer(dest string, layer archive.ArchiveReader) (size int64, err error) {
+	return ApplyLayerWithOptions(dest, layer, nil)
+}
+
+// ApplyLayerWithOptions is like ApplyLayer, but shifts the ownership of the
+// unpacked files according to the id maps in `options`.
+func ApplyLayerWithOptions(dest string, layer archive.ArchiveReader, options *archive.TarOptions) (size int64, err error) {
 	dest = filepath.Clean(dest)
+	if options == nil {
+		options = &archive.TarOptions{}
+	}
+	// pass the options through the env, as Untar does
+	data, err := json.Marshal(options)
+	if err != nil {
+		return 0, fmt.Errorf("ApplyLayer json encode: %v", err)
+	}
 	decompressed, err := archive.DecompressStream(layer)
 	if err != nil {
 		return 0, err
@@ -63,6 +82,7 @@ func ApplyLayer(dest string, layer archive.ArchiveReader) (size int64, err error
 
 	cmd := reexec.Command("docker-applyLayer", dest)
 	cmd.Stdin = decompressed
+	cmd.Env = append(cmd.Env, fmt.Sprintf("OPT=%s", data))
 
 	outBuf, errBuf := new(bytes.Buffer), new(bytes.Buffer)
 	cmd.Stdout, cmd.Stderr = outBuf, errBuf
@@ -49,6 +49,7 @@ var (
 	ErrTaskSetMessage         = errors.New("dm_task_set_message failed")
 	ErrTaskSetAddNode         = errors.New("dm_task_set_add_node failed")
 	ErrTaskSetRo              = errors.New("dm_task_set_ro failed")
+	ErrTaskDeferredRemove     = errors.New("dm_task_deferred_remove failed")
 	ErrTaskAddTarget          = errors.New("dm_task_add_target failed")
 	ErrTaskSetSector          = errors.New("dm_task_set_sector failed")
 	ErrTaskGetDeps            = errors.New("dm_task_get_deps failed")
@@ -68,9 +69,11 @@ var (
 	ErrLoopbackSetCapacity    = errors.New("Unable set loopback capacity")
 	ErrBusy                   = errors.New("Device is Busy")
 	ErrDeviceIdExists         = errors.New("Device Id Exists")
+	ErrEnxio                  = errors.New("No such device or address")
 
 	dmSawBusy  bool
 	dmSawExist bool
+	dmSawEnxio bool // No Such Device or Address
 )
 
 type (
@@ -83,16 +86,17 @@ type (
 		Device []uint64
 	}
 	Info struct {
-		Exists        int
-		Suspended     int
-		LiveTable     int
-		InactiveTable int
-		OpenCount     int32
-		EventNr       uint32
-		Major         uint32
-		Minor         uint32
-		ReadOnly      int
-		TargetCount   int32
+		Exists         int
+		Suspended      int
+		LiveTable      int
+		InactiveTable  int
+		OpenCount      int32
+		EventNr        uint32
+		Major          uint32
+		Minor          uint32
+		ReadOnly       int
+		TargetCount    int32
+		DeferredRemove int
 	}
 	TaskType    int
 	AddNodeType int
@@ -187,6 +191,15 @@ func (t *Task) SetAddNode(addNode AddNodeType) error {
 	return nil
 }
 
+// SetDeferredRemove makes a remove task remove the device only once it is
+// closed by its last user, instead of failing while it is busy.
+func (t *Task) SetDeferredRemove() error {
+	if res := DmTaskDeferredRemove(t.unmanaged); res != 1 {
+		return ErrTaskDeferredRemove
+	}
+	return nil
+}
+
 func (t *Task) SetRo() error {
 	if res := DmTaskSetRo(t.unmanaged); res != 1 {
 		return ErrTaskSetRo
@@ -218,6 +231,16 @@ func (t *Task) GetInfo() (*Info, error) {
 	return info, nil
 }
 
+// GetInfoWithDeferred is GetInfo also reporting whether the removal of the
+// device is deferred.
+func (t *Task) GetInfoWithDeferred() (*Info, error) {
+	info := &Info{}
+	if res := DmTaskGetInfoWithDeferred(t.unmanaged, info); res != 1 {
+		return nil, ErrTaskGetInfo
+	}
+	return info, nil
+}
+
 func (t *Task) GetDriverVersion() (string, error) {
 	res := DmTaskGetDriverVersion(t.unmanaged)
 	if res == "" {
@@ -372,6 +395,63 @@ func RemoveDevice(name string) error {
 	return nil
 }
 
+// RemoveDeviceDeferred schedules the removal of the device for when it is
+// no longer open, and returns at once.
+func RemoveDeviceDeferred(name string) error {
+	log.Debugf("[devmapper] RemoveDeviceDeferred START(%s)", name)
+	defer log.Debugf("[devmapper] RemoveDeviceDeferred END(%s)", name)
+	task, err := TaskCreateNamed(DeviceRemove, name)
+	if task == nil {
+		return err
+	}
+
+	if err := task.SetDeferredRemove(); err != nil {
+		return err
+	}
+
+	var cookie uint = 0
+	if err := task.SetCookie(&cookie, 0); err != nil {
+		return fmt.Errorf("Can not set cookie: %s", err)
+	}
+	defer UdevWait(cookie)
+
+	if err = task.Run(); err != nil {
+		return fmt.Errorf("Error running RemoveDeviceDeferred %s", err)
+	}
+
+	return nil
+}
+
+// CancelDeferredRemove cancels the deferred removal of a device, which is
+// needed before the device is used again.
+func CancelDeferredRemove(deviceName string) error {
+	task, err := TaskCreateNamed(DeviceTargetMsg, deviceName)
+	if task == nil {
+		return err
+	}
+
+	if err := task.SetSector(0); err != nil {
+		return fmt.Errorf("Can't set sector %s", err)
+	}
+
+	if err := task.SetMessage("@cancel_deferred_remove"); err != nil {
+		return fmt.Errorf("Can't set message %s", err)
+	}
+
+	dmSawBusy = false
+	dmSawEnxio = false
+	if err := task.Run(); err != nil {
+		// A device might be being deleted already
+		if dmSawBusy {
+			return ErrBusy
+		} else if dmSawEnxio {
+			return ErrEnxio
+		}
+		return fmt.Errorf("Error running CancelDeferredRemove %s", err)
+	}
+	return nil
+}
+
 func GetBlockDeviceSize(file *os.File) (uint64, error) {
 	size, err := ioctlBlkGetSize64(file.Fd())
 	if err != nil {
@@ -480,6 +560,19 @@ func GetInfo(name string) (*Info, error) {
 	return task.GetInfo()
 }
 
+// GetInfoWithDeferred returns the info of the device name, including
+// whether its removal is deferred.
+func GetInfoWithDeferred(name string) (*Info, error) {
+	task, err := TaskCreateNamed(DeviceInfo, name)
+	if task == nil {
+		return nil, err
+	}
+	if err := task.Run(); err != nil {
+		return nil, err
+	}
+	return task.GetInfoWithDeferred()
+}
+
 func GetDriverVersion() (string, error) {
 	task := TaskCreate(DeviceVersion)
 	if task == nil {
@@ -607,7 +700,11 @@ func DeleteDevice(poolName string, deviceId int) error {
 		return fmt.Errorf("Can't set message %s", err)
 	}
 
+	dmSawBusy = false
 	if err := task.Run(); err != nil {
+		if dmSawBusy {
+			return ErrBusy
+		}
 		return fmt.Errorf("Error running DeleteDevice %s", err)
 	}
 	return nil
@@ -0,0 +1,17 @@
+// +build linux,libdm_no_deferred_remove
+
+package devicemapper
+
+// LibraryDeferredRemovalSupport tells whether libdevmapper supports the
+// deferred removal of devices. It was built without it for versions older
+// than 1.02.89.
+const LibraryDeferredRemovalSupport = false
+
+func dmTaskDeferredRemoveFct(task *CDmTask) int {
+	// never called, the deferred removal can't be enabled
+	return -1
+}
+
+func dmTaskGetInfoWithDeferredFct(task *CDmTask, info *Info) int {
+	return -1
+}
@@ -0,0 +1,95 @@
+package idtools
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSubidFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "idtools")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	subuid := filepath.Join(tmpDir, "subuid")
+	content := "# comment\nother:100000:65536\ndockremap:165536:65536\n\ndockremap:300000:1000\n"
+	if err := ioutil.WriteFile(subuid, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rangeList, err := parseSubidFile(subuid, "dockremap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rangeList) != 2 {
+		t.Fatalf("Expected 2 ranges, got %d", len(rangeList))
+	}
+	if rangeList[0].Start != 165536 || rangeList[0].Length != 65536 {
+		t.Fatalf("Unexpected first range: %v", rangeList[0])
+	}
+
+	idMap := createIDMap(rangeList)
+	expected := []IDMap{
+		{ContainerID: 0, HostID: 165536, Size: 65536},
+		{ContainerID: 65536, HostID: 300000, Size: 1000},
+	}
+	if len(idMap) != len(expected) {
+		t.Fatalf("Expected %d mappings, got %d", len(expected), len(idMap))
+	}
+	for i := range expected {
+		if idMap[i] != expected[i] {
+			t.Fatalf("Expected mapping %v, got %v", expected[i], idMap[i])
+		}
+	}
+
+	if err := ioutil.WriteFile(subuid, []byte("dockremap:165536\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseSubidFile(subuid, "dockremap"); err == nil {
+		t.Fatal("Expected an error for a malformed subid file")
+	}
+}
+
+func TestToHostToContainer(t *testing.T) {
+	idMap := []IDMap{
+		{ContainerID: 0, HostID: 165536, Size: 65536},
+	}
+
+	hostID, err := ToHost(1000, idMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostID != 166536 {
+		t.Fatalf("Expected host ID 166536, got %d", hostID)
+	}
+
+	contID, err := ToContainer(hostID, idMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contID != 1000 {
+		t.Fatalf("Expected container ID 1000, got %d", contID)
+	}
+
+	if _, err := ToHost(65536, idMap); err == nil {
+		t.Fatal("Expected an error translating an unmapped container ID")
+	}
+	if _, err := ToContainer(0, idMap); err == nil {
+		t.Fatal("Expected an error translating an unmapped host ID")
+	}
+
+	if id, err := ToHost(42, nil); err != nil || id != 42 {
+		t.Fatalf("Expected identity mapping without maps, got %d (%v)", id, err)
+	}
+
+	uid, gid, err := GetRootUIDGID(idMap, idMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid != 165536 || gid != 165536 {
+		t.Fatalf("Expected remapped root 165536:165536, got %d:%d", uid, gid)
+	}
+}
@@ -1,6 +1,7 @@
 package mount
 
 import (
+	"fmt"
 	"strings"
 )
 
@@ -67,3 +68,38 @@ func parseOptions(options string) (int, string) {
 	}
 	return flag, strings.Join(data, ",")
 }
+
+// ParseTmpfsOptions parses the fstab type mount options of a tmpfs mount
+// into mount() flags and tmpfs specific data. The mount is noexec, nosuid
+// and nodev unless the options say otherwise.
+func ParseTmpfsOptions(options string) (int, string, error) {
+	defaults := "noexec,nosuid,nodev"
+	if options != "" {
+		defaults += "," + options
+	}
+	flags, data := parseOptions(defaults)
+	if flags&(BIND|REMOUNT|UNBINDABLE|PRIVATE|SHARED|SLAVE) != 0 {
+		return 0, "", fmt.Errorf("invalid tmpfs options %q: only mount flags and tmpfs options are allowed", options)
+	}
+	for _, o := range strings.Split(data, ",") {
+		if o == "" {
+			continue
+		}
+		key := strings.SplitN(o, "=", 2)[0]
+		if _, ok := tmpfsOptions[key]; !ok {
+			return 0, "", fmt.Errorf("invalid tmpfs option %q", o)
+		}
+	}
+	return flags, data, nil
+}
+
+// tmpfsOptions are the filesystem specific options accepted by tmpfs
+var tmpfsOptions = map[string]struct{}{
+	"size":      {},
+	"nr_blocks": {},
+	"nr_inodes": {},
+	"mode":      {},
+	"uid":       {},
+	"gid":       {},
+	"mpol":      {},
+}
@@ -1,6 +1,8 @@
 package mount
 
 import (
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -25,6 +27,30 @@ func Mounted(mountpoint string) (bool, error) {
 	return false, nil
 }
 
+// GetSourceMount looks at /proc/self/mountinfo to find the mount that
+// the specified path is on
+func GetSourceMount(path string) (*MountInfo, error) {
+	entries, err := parseMountTable()
+	if err != nil {
+		return nil, err
+	}
+
+	// The deepest mountpoint wins, and the last one of stacked mounts
+	var mount *MountInfo
+	for _, e := range entries {
+		if e.Mountpoint != "/" && path != e.Mountpoint && !strings.HasPrefix(path, e.Mountpoint+"/") {
+			continue
+		}
+		if mount == nil || len(e.Mountpoint) >= len(mount.Mountpoint) {
+			mount = e
+		}
+	}
+	if mount == nil {
+		return nil, fmt.Errorf("Could not find the mount of %s", path)
+	}
+	return mount, nil
+}
+
 // Mount the specified options at the target path only if
 // the target is not mounted
 // Options must be specified as fstab style
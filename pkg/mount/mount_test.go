@@ -135,3 +135,39 @@ func TestGetMounts(t *testing.T) {
 		t.Fatal("/ should be mounted at least")
 	}
 }
+
+func TestParseTmpfsOptions(t *testing.T) {
+	flags, data, err := ParseTmpfsOptions("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := NOEXEC | NOSUID | NODEV; flags != expected || data != "" {
+		t.Fatalf("Expected %d and no data, got %d and %q", expected, flags, data)
+	}
+
+	flags, data, err = ParseTmpfsOptions("exec,ro,size=64m,mode=1777")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := NOSUID | NODEV | RDONLY; flags != expected {
+		t.Fatalf("Expected %d got %d", expected, flags)
+	}
+	if data != "size=64m,mode=1777" {
+		t.Fatalf("Expected size=64m,mode=1777 got %s", data)
+	}
+
+	// the defaults can all be overridden
+	flags, data, err = ParseTmpfsOptions("exec,suid,dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags != 0 || data != "" {
+		t.Fatalf("Expected no flags and no data, got %d and %q", flags, data)
+	}
+
+	for _, options := range []string{"bind", "rbind", "remount", "shared", "rprivate", "foo=bar", "size=1m,nodata"} {
+		if _, _, err := ParseTmpfsOptions(options); err == nil {
+			t.Fatalf("Expected an error for tmpfs options %q", options)
+		}
+	}
+}
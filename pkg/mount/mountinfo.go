@@ -1,7 +1,30 @@
 package mount
 
+import "strings"
+
 type MountInfo struct {
 	Id, Parent, Major, Minor         int
 	Root, Mountpoint, Opts, Optional string
 	Fstype, Source, VfsOpts          string
 }
+
+// IsShared reports whether mount events propagate to and from the peers
+// of the mount
+func (m *MountInfo) IsShared() bool {
+	return m.hasOptionalField("shared:")
+}
+
+// IsSlave reports whether mount events propagate to the mount from its
+// master
+func (m *MountInfo) IsSlave() bool {
+	return m.hasOptionalField("master:")
+}
+
+func (m *MountInfo) hasOptionalField(prefix string) bool {
+	for _, field := range strings.Fields(m.Optional) {
+		if strings.HasPrefix(field, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -475,3 +475,34 @@ func TestParseFedoraMountinfoFields(t *testing.T) {
 		t.Fatalf("expected %#v, got %#v", mi, infos[0])
 	}
 }
+
+func TestMountInfoPropagation(t *testing.T) {
+	for _, c := range []struct {
+		optional      string
+		shared, slave bool
+	}{
+		{"", false, false},
+		{"shared:5", true, false},
+		{"master:2", false, true},
+		{"shared:7 master:2", true, true},
+		{"unbindable", false, false},
+	} {
+		mi := MountInfo{Optional: c.optional}
+		if mi.IsShared() != c.shared || mi.IsSlave() != c.slave {
+			t.Fatalf("%q: expected shared %v and slave %v", c.optional, c.shared, c.slave)
+		}
+	}
+}
+
+func TestGetSourceMount(t *testing.T) {
+	mi, err := GetSourceMount("/proc/self")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mi.Mountpoint != "/proc" {
+		t.Fatalf("Expected /proc/self to be on /proc, got %s", mi.Mountpoint)
+	}
+	if mi, err = GetSourceMount("/"); err != nil || mi.Mountpoint != "/" {
+		t.Fatalf("Expected / to be on /, got %v %v", mi, err)
+	}
+}
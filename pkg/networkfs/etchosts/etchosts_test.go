@@ -132,3 +132,39 @@ func TestUpdate(t *testing.T) {
 		t.Fatalf("Expected to find '%s' got '%s'", expected, content)
 	}
 }
+
+func TestUpdateLinkAlias(t *testing.T) {
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	extraContent := []Record{
+		{Hosts: "db 4e1a2b3c4d5e", IP: "172.17.0.2"},
+		{Hosts: "db2 7f6e5d4c3b2a", IP: "172.17.0.3"},
+		{Hosts: "cache db", IP: "172.17.0.4"},
+	}
+	if err := Build(file.Name(), "172.17.0.5", "web", "", extraContent); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Update(file.Name(), "172.17.0.6", "db"); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"172.17.0.6\tdb 4e1a2b3c4d5e\n",
+		"172.17.0.3\tdb2 7f6e5d4c3b2a\n",
+		"172.17.0.4\tcache db\n",
+	} {
+		if !bytes.Contains(content, []byte(expected)) {
+			t.Fatalf("Expected to find '%s' got '%s'", expected, content)
+		}
+	}
+}
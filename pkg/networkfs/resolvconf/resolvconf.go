@@ -28,6 +28,7 @@ var (
 	nsIPv6Regexp    = regexp.MustCompile(`(?m)^nameserver\s+` + ipv6Address + `\s*\n*`)
 	nsRegexp        = regexp.MustCompile(`^\s*nameserver\s*((` + ipv4Address + `)|(` + ipv6Address + `))\s*$`)
 	searchRegexp    = regexp.MustCompile(`^\s*search\s*(([^\s]+\s*)*)$`)
+	optionsRegexp   = regexp.MustCompile(`^\s*options\s*(([^\s]+\s*)*)$`)
 )
 
 var lastModified struct {
@@ -162,20 +163,46 @@ func GetSearchDomains(resolvConf []byte) []string {
 	return domains
 }
 
-func Build(path string, dns, dnsSearch []string) error {
+// GetOptions returns the resolver options (if any) listed in /etc/resolv.conf
+// If more than one options line is encountered, only the contents of the
+// last one is returned.
+func GetOptions(resolvConf []byte) []string {
+	options := []string{}
+	for _, line := range getLines(resolvConf, []byte("#")) {
+		match := optionsRegexp.FindSubmatch(line)
+		if match == nil {
+			continue
+		}
+		options = strings.Fields(string(match[1]))
+	}
+	return options
+}
+
+// Build writes a resolv.conf with the nameservers, search domains and
+// resolver options to path, and returns the hash of its contents.
+func Build(path string, dns, dnsSearch, dnsOptions []string) (string, error) {
 	content := bytes.NewBuffer(nil)
 	for _, dns := range dns {
 		if _, err := content.WriteString("nameserver " + dns + "\n"); err != nil {
-			return err
+			return "", err
 		}
 	}
 	if len(dnsSearch) > 0 {
 		if searchString := strings.Join(dnsSearch, " "); strings.Trim(searchString, " ") != "." {
 			if _, err := content.WriteString("search " + searchString + "\n"); err != nil {
-				return err
+				return "", err
 			}
 		}
 	}
+	if len(dnsOptions) > 0 {
+		if _, err := content.WriteString("options " + strings.Join(dnsOptions, " ") + "\n"); err != nil {
+			return "", err
+		}
+	}
 
-	return ioutil.WriteFile(path, content.Bytes(), 0644)
+	hash, err := utils.HashData(bytes.NewReader(content.Bytes()))
+	if err != nil {
+		return "", err
+	}
+	return hash, ioutil.WriteFile(path, content.Bytes(), 0644)
 }
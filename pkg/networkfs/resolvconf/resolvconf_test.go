@@ -5,6 +5,8 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+
+	"github.com/docker/docker/utils"
 )
 
 func TestGet(t *testing.T) {
@@ -110,6 +112,25 @@ func strSlicesEqual(a, b []string) bool {
 	return true
 }
 
+func TestGetOptions(t *testing.T) {
+	for resolv, result := range map[string][]string{
+		``:                             {},
+		`options`:                      {},
+		`# options ndots:2`:            {},
+		`options ndots:2`:              {"ndots:2"},
+		`options   ndots:2  rotate   `: {"ndots:2", "rotate"},
+		`nameserver 1.2.3.4
+options ndots:2 # comment`: {"ndots:2"},
+		`options timeout:1
+options ndots:2 rotate`: {"ndots:2", "rotate"},
+	} {
+		test := GetOptions([]byte(resolv))
+		if !strSlicesEqual(test, result) {
+			t.Fatalf("Wrong options string {%s} should be %v. Input: %s", test, result, resolv)
+		}
+	}
+}
+
 func TestBuild(t *testing.T) {
 	file, err := ioutil.TempFile("", "")
 	if err != nil {
@@ -117,7 +138,7 @@ func TestBuild(t *testing.T) {
 	}
 	defer os.Remove(file.Name())
 
-	err = Build(file.Name(), []string{"ns1", "ns2", "ns3"}, []string{"search1"})
+	hash, err := Build(file.Name(), []string{"ns1", "ns2", "ns3"}, []string{"search1"}, []string{"ndots:2", "rotate"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -127,9 +148,12 @@ func TestBuild(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if expected := "nameserver ns1\nnameserver ns2\nnameserver ns3\nsearch search1\n"; !bytes.Contains(content, []byte(expected)) {
+	if expected := "nameserver ns1\nnameserver ns2\nnameserver ns3\nsearch search1\noptions ndots:2 rotate\n"; !bytes.Contains(content, []byte(expected)) {
 		t.Fatalf("Expected to find '%s' got '%s'", expected, content)
 	}
+	if expected, _ := utils.HashData(bytes.NewReader(content)); hash != expected {
+		t.Fatalf("Expected the hash %s of the contents, got %s", expected, hash)
+	}
 }
 
 func TestBuildWithZeroLengthDomainSearch(t *testing.T) {
@@ -139,7 +163,7 @@ func TestBuildWithZeroLengthDomainSearch(t *testing.T) {
 	}
 	defer os.Remove(file.Name())
 
-	err = Build(file.Name(), []string{"ns1", "ns2", "ns3"}, []string{"."})
+	_, err = Build(file.Name(), []string{"ns1", "ns2", "ns3"}, []string{"."}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -235,4 +259,15 @@ func TestFilterResolvDns(t *testing.T) {
 			t.Fatalf("Failed no Localhost+IPv6 enabled: expected \n<%s> got \n<%s>", ns0, string(result))
 		}
 	}
+	// the resolver options are kept
+	ns0 = "search example.com\noptions ndots:2 rotate\n\nnameserver 8.8.8.8\nnameserver 8.8.4.4"
+	ns1 = "nameserver 127.0.0.1\nsearch example.com\noptions ndots:2 rotate\n"
+	if result, _ := FilterResolvDns([]byte(ns1), false); result != nil {
+		if ns0 != string(result) {
+			t.Fatalf("Failed to keep the options: expected \n<%s> got \n<%s>", ns0, string(result))
+		}
+		if options := GetOptions(result); !strSlicesEqual(options, []string{"ndots:2", "rotate"}) {
+			t.Fatalf("Failed to keep the options: got %v", options)
+		}
+	}
 }
@@ -0,0 +1,179 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	versionMimetype = "application/vnd.docker.plugins.v1+json"
+	defaultTimeOut  = 30 * time.Second
+)
+
+// Client calls the methods of a plugin: every method is a POST of its
+// arguments as JSON to /<Method>, answered with its results as JSON.
+type Client struct {
+	http *http.Client
+	addr string
+	host string
+}
+
+// NewClient returns a client for the plugin at addr, an url such as
+// unix:///run/docker/plugins/name.sock or tcp://localhost:8080.
+func NewClient(addr string) (*Client, error) {
+	protoAndAddr := strings.SplitN(addr, "://", 2)
+	if len(protoAndAddr) != 2 {
+		return nil, fmt.Errorf("Invalid plugin address %s", addr)
+	}
+	proto, address := protoAndAddr[0], protoAndAddr[1]
+
+	tr := &http.Transport{}
+	host := address
+	switch proto {
+	case "unix":
+		// the host of the requests is ignored, they go to the socket
+		tr.Dial = func(_, _ string) (net.Conn, error) {
+			return net.DialTimeout("unix", address, defaultTimeOut)
+		}
+		host = "plugin"
+	case "tcp":
+	default:
+		return nil, fmt.Errorf("Unsupported plugin protocol %s in %s", proto, addr)
+	}
+
+	return &Client{
+		http: &http.Client{Transport: tr},
+		addr: addr,
+		host: host,
+	}, nil
+}
+
+// Call calls serviceMethod with args, and decodes its results into ret.
+func (c *Client) Call(serviceMethod string, args interface{}, ret interface{}) error {
+	body, err := c.callWithRetry(serviceMethod, args, true)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if ret == nil {
+		return nil
+	}
+	return json.NewDecoder(body).Decode(ret)
+}
+
+// Stream calls serviceMethod with args, and returns its results as is.
+func (c *Client) Stream(serviceMethod string, args interface{}) (io.ReadCloser, error) {
+	return c.callWithRetry(serviceMethod, args, true)
+}
+
+// SendFile calls serviceMethod with data as is, and decodes its results
+// into ret. The call isn't retried, data can only be read once.
+func (c *Client) SendFile(serviceMethod string, data io.Reader, ret interface{}) error {
+	req, err := http.NewRequest("POST", "http://"+c.host+"/"+serviceMethod, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", versionMimetype)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	body, err := checkResponse(serviceMethod, resp)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if ret == nil {
+		return nil
+	}
+	return json.NewDecoder(body).Decode(ret)
+}
+
+// callWithRetry retries the calls which fail to connect, for up to
+// defaultTimeOut, as the plugin may still be starting.
+func (c *Client) callWithRetry(serviceMethod string, args interface{}, retry bool) (io.ReadCloser, error) {
+	var buf bytes.Buffer
+	if args != nil {
+		if err := json.NewEncoder(&buf).Encode(args); err != nil {
+			return nil, err
+		}
+	}
+	payload := buf.Bytes()
+
+	var (
+		retries int
+		start   = time.Now()
+	)
+	for {
+		req, err := http.NewRequest("POST", "http://"+c.host+"/"+serviceMethod, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("Accept", versionMimetype)
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			if !retry {
+				return nil, err
+			}
+
+			timeOff := backoff(retries)
+			if time.Since(start)+timeOff > defaultTimeOut {
+				return nil, err
+			}
+			retries++
+			log.Warnf("Unable to connect to plugin: %s, retrying in %v", c.addr, timeOff)
+			time.Sleep(timeOff)
+			continue
+		}
+
+		return checkResponse(serviceMethod, resp)
+	}
+}
+
+// checkResponse returns the body of a successful call, or the error the
+// plugin returned.
+func checkResponse(serviceMethod string, resp *http.Response) (io.ReadCloser, error) {
+	if resp.StatusCode == http.StatusOK {
+		return resp.Body, nil
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", serviceMethod, err)
+	}
+
+	// plugins return their errors as {"Err": "..."}
+	var e struct {
+		Err string
+	}
+	if err := json.Unmarshal(b, &e); err == nil && e.Err != "" {
+		return nil, fmt.Errorf("Plugin Error: %s, %s", serviceMethod, e.Err)
+	}
+	return nil, fmt.Errorf("Plugin Error: %s, status code %d: %s", serviceMethod, resp.StatusCode, strings.TrimSpace(string(b)))
+}
+
+// backoff doubles the wait between retries, up to 30 seconds
+func backoff(retries int) time.Duration {
+	b, max := 1, int(defaultTimeOut/time.Second)
+	for b < max && retries > 0 {
+		b *= 2
+		retries--
+	}
+	if b > max {
+		b = max
+	}
+	return time.Duration(b) * time.Second
+}
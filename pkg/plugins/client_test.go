@@ -0,0 +1,144 @@
+package plugins
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	mux    *http.ServeMux
+	server *httptest.Server
+)
+
+func setupRemotePluginServer() string {
+	mux = http.NewServeMux()
+	server = httptest.NewServer(mux)
+	return server.URL
+}
+
+func teardownRemotePluginServer() {
+	if server != nil {
+		server.Close()
+	}
+}
+
+func TestFailedConnection(t *testing.T) {
+	c, _ := NewClient("tcp://127.0.0.1:1")
+	_, err := c.callWithRetry("Service.Method", nil, false)
+	if err == nil {
+		t.Fatal("Unexpected successful connection")
+	}
+}
+
+func TestEchoInputOutput(t *testing.T) {
+	addr := setupRemotePluginServer()
+	defer teardownRemotePluginServer()
+
+	m := Manifest{[]string{"VolumeDriver", "NetworkDriver"}}
+
+	mux.HandleFunc("/Test.Echo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Fatalf("Expected POST, got %s\n", r.Method)
+		}
+		header := w.Header()
+		header.Set("Content-Type", versionMimetype)
+		io.Copy(w, r.Body)
+	})
+
+	c, _ := NewClient("tcp://" + strings.TrimPrefix(addr, "http://"))
+	var output Manifest
+	if err := c.Call("Test.Echo", m, &output); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(output, m) {
+		t.Fatalf("Expected %v, was %v\n", m, output)
+	}
+}
+
+func TestPluginError(t *testing.T) {
+	addr := setupRemotePluginServer()
+	defer teardownRemotePluginServer()
+
+	mux.HandleFunc("/Test.Fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `{"Err":"no such thing"}`)
+	})
+
+	c, _ := NewClient("tcp://" + strings.TrimPrefix(addr, "http://"))
+	err := c.Call("Test.Fail", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "no such thing") {
+		t.Fatalf("Expected the error of the plugin, got %v", err)
+	}
+}
+
+func TestStreamAndSendFile(t *testing.T) {
+	addr := setupRemotePluginServer()
+	defer teardownRemotePluginServer()
+
+	var received string
+	mux.HandleFunc("/Test.Stream", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "raw data")
+	})
+	mux.HandleFunc("/Test.SendFile", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		received = string(b)
+		io.WriteString(w, `{"Size":8}`)
+	})
+
+	c, _ := NewClient("tcp://" + strings.TrimPrefix(addr, "http://"))
+	rc, err := c.Stream("Test.Stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "raw data" {
+		t.Fatalf("Expected raw data, got %q", b)
+	}
+
+	var ret struct{ Size int64 }
+	if err := c.SendFile("Test.SendFile", strings.NewReader("raw data"), &ret); err != nil {
+		t.Fatal(err)
+	}
+	if received != "raw data" || ret.Size != 8 {
+		t.Fatalf("Expected raw data of size 8, got %q of size %d", received, ret.Size)
+	}
+}
+
+func TestInvalidAddress(t *testing.T) {
+	for _, addr := range []string{"localhost:8080", "udp://localhost:8080"} {
+		if _, err := NewClient(addr); err == nil {
+			t.Fatalf("Expected an error for %s", addr)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		retries    int
+		expTimeOff time.Duration
+	}{
+		{0, time.Duration(1)},
+		{1, time.Duration(2)},
+		{2, time.Duration(4)},
+		{4, time.Duration(16)},
+		{6, time.Duration(30)},
+		{10, time.Duration(30)},
+	}
+
+	for _, c := range cases {
+		s := c.expTimeOff * time.Second
+		if d := backoff(c.retries); d != s {
+			t.Fatalf("Retry %v, expected %v, was %v\n", c.retries, s, d)
+		}
+	}
+}
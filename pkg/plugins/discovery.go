@@ -0,0 +1,61 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrNotFound = errors.New("Plugin not found")
+
+	// SocketsPath is where the local plugins listen, on <name>.sock
+	SocketsPath = "/run/docker/plugins"
+	// SpecsPaths are where the addresses of the plugins listening
+	// elsewhere are found, in <name>.spec files
+	SpecsPaths = []string{"/etc/docker/plugins", "/usr/lib/docker/plugins"}
+)
+
+// scan looks for the plugin name, first in SocketsPath then in the spec
+// files of SpecsPaths.
+func scan(name string) (*Plugin, error) {
+	socketpath := filepath.Join(SocketsPath, name+".sock")
+	if fi, err := os.Stat(socketpath); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		return newLocalPlugin(name, "unix://"+socketpath), nil
+	}
+
+	for _, dir := range SpecsPaths {
+		p, err := readPluginInfo(name, filepath.Join(dir, name+".spec"))
+		if err == nil {
+			return p, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// readPluginInfo reads a spec file, holding the address of the plugin as
+// an url such as unix:///var/run/plugin.sock or tcp://localhost:8080
+func readPluginInfo(name, path string) (*Plugin, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	addr := strings.TrimSpace(string(content))
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	if len(u.Scheme) == 0 || !strings.Contains(addr, "://") {
+		return nil, fmt.Errorf("Unknown protocol in plugin spec %s: %s", path, addr)
+	}
+
+	return newLocalPlugin(name, addr), nil
+}
@@ -0,0 +1,127 @@
+package plugins
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func setup(t *testing.T) (string, func()) {
+	tmpdir, err := ioutil.TempDir("", "docker-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldSocketsPath, oldSpecsPaths := SocketsPath, SpecsPaths
+	SocketsPath = tmpdir
+	SpecsPaths = []string{tmpdir}
+
+	return tmpdir, func() {
+		SocketsPath, SpecsPaths = oldSocketsPath, oldSpecsPaths
+		os.RemoveAll(tmpdir)
+	}
+}
+
+func TestUnknownPlugin(t *testing.T) {
+	_, cleanup := setup(t)
+	defer cleanup()
+
+	if _, err := scan("unknown"); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestLocalSocket(t *testing.T) {
+	tmpdir, cleanup := setup(t)
+	defer cleanup()
+
+	l, err := net.Listen("unix", filepath.Join(tmpdir, "echo.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	p, err := scan("echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "echo" {
+		t.Fatalf("Expected plugin `echo`, got %s\n", p.Name)
+	}
+	addr := fmt.Sprintf("unix://%s/echo.sock", tmpdir)
+	if p.Addr != addr {
+		t.Fatalf("Expected plugin addr `%s`, got %s\n", addr, p.Addr)
+	}
+}
+
+func TestFileSpecPlugin(t *testing.T) {
+	tmpdir, cleanup := setup(t)
+	defer cleanup()
+
+	cases := []struct {
+		path string
+		name string
+		addr string
+		fail bool
+	}{
+		{filepath.Join(tmpdir, "echo.spec"), "echo", "unix://var/lib/docker/plugins/echo.sock", false},
+		{filepath.Join(tmpdir, "foo.spec"), "foo", "tcp://localhost:8080", false},
+		{filepath.Join(tmpdir, "bar.spec"), "bar", "localhost:8080", true}, // unknown protocol
+	}
+
+	for _, c := range cases {
+		if err := ioutil.WriteFile(c.path, []byte(c.addr), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		p, err := scan(c.name)
+		if c.fail && err == nil {
+			t.Fatalf("Expected error scanning %s", c.path)
+		}
+		if c.fail {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Name != c.name {
+			t.Fatalf("Expected plugin `%s`, got %s\n", c.name, p.Name)
+		}
+		if p.Addr != c.addr {
+			t.Fatalf("Expected plugin addr `%s`, got %s\n", c.addr, p.Addr)
+		}
+	}
+}
+
+func TestGetPlugin(t *testing.T) {
+	tmpdir, cleanup := setup(t)
+	defer cleanup()
+
+	l, err := net.Listen("unix", filepath.Join(tmpdir, "activate.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/Plugin.Activate", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", versionMimetype)
+		fmt.Fprintln(w, `{"Implements": ["GraphDriver"]}`)
+	})
+	go http.Serve(l, mux)
+
+	p, err := Get("activate", "GraphDriver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.Manifest.Implements, []string{"GraphDriver"}) {
+		t.Fatalf("Unexpected manifest %v", p.Manifest)
+	}
+	if _, err := Get("activate", "VolumeDriver"); err != ErrNotImplements {
+		t.Fatalf("Expected ErrNotImplements, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+// Package plugins discovers and activates the out-of-process plugins of
+// the daemon.
+//
+// A plugin is an http server listening on a unix socket in SocketsPath,
+// or at the address found in a spec file of SpecsPaths. On its first use
+// the daemon calls its Plugin.Activate method, which returns the list of
+// subsystems the plugin implements, such as "GraphDriver".
+package plugins
+
+import (
+	"errors"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+var ErrNotImplements = errors.New("Plugin does not implement the requested driver")
+
+type plugins struct {
+	sync.Mutex
+	plugins map[string]*Plugin
+	// the locks held while loading each plugin, so that the activation of
+	// one doesn't hold up the others
+	loading map[string]*sync.Mutex
+}
+
+var storage = plugins{
+	plugins: make(map[string]*Plugin),
+	loading: make(map[string]*sync.Mutex),
+}
+
+// loadLock returns the lock to hold while loading the plugin name.
+func (s *plugins) loadLock(name string) *sync.Mutex {
+	s.Lock()
+	defer s.Unlock()
+	l, ok := s.loading[name]
+	if !ok {
+		l = &sync.Mutex{}
+		s.loading[name] = l
+	}
+	return l
+}
+
+// Manifest lists the subsystems a plugin implements.
+type Manifest struct {
+	Implements []string
+}
+
+// Plugin is a plugin found on the host.
+type Plugin struct {
+	Name     string `json:"-"`
+	Addr     string
+	Client   *Client   `json:"-"`
+	Manifest *Manifest `json:"-"`
+
+	activateOnce sync.Once
+	activateErr  error
+}
+
+func newLocalPlugin(name, addr string) *Plugin {
+	return &Plugin{
+		Name: name,
+		Addr: addr,
+	}
+}
+
+func (p *Plugin) activate() error {
+	p.activateOnce.Do(func() {
+		p.activateErr = p.activateWithLock()
+	})
+	return p.activateErr
+}
+
+func (p *Plugin) activateWithLock() error {
+	c, err := NewClient(p.Addr)
+	if err != nil {
+		return err
+	}
+	p.Client = c
+
+	m := new(Manifest)
+	if err := p.Client.Call("Plugin.Activate", nil, m); err != nil {
+		return err
+	}
+	log.Debugf("%s's manifest: %v", p.Name, m)
+	p.Manifest = m
+	return nil
+}
+
+func load(name string) (*Plugin, error) {
+	l := storage.loadLock(name)
+	l.Lock()
+	defer l.Unlock()
+
+	storage.Lock()
+	p, ok := storage.plugins[name]
+	storage.Unlock()
+	if ok {
+		return p, p.activate()
+	}
+
+	p, err := scan(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.activate(); err != nil {
+		return nil, err
+	}
+	// plugins which failed to activate are scanned for again next time
+	storage.Lock()
+	storage.plugins[name] = p
+	storage.Unlock()
+	return p, nil
+}
+
+// Get returns the plugin name, activating it if needed, if it implements
+// the subsystem imp.
+func Get(name, imp string) (*Plugin, error) {
+	p, err := load(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, driver := range p.Manifest.Implements {
+		log.Debugf("%s implements: %s", name, driver)
+		if driver == imp {
+			return p, nil
+		}
+	}
+	return nil, ErrNotImplements
+}
@@ -102,10 +102,11 @@ func (bufPool *BufioWriterPool) Put(b *bufio.Writer) {
 func (bufPool *BufioWriterPool) NewWriteCloserWrapper(buf *bufio.Writer, w io.Writer) io.WriteCloser {
 	return ioutils.NewWriteCloserWrapper(w, func() error {
 		buf.Flush()
+		var err error
 		if writeCloser, ok := w.(io.WriteCloser); ok {
-			writeCloser.Close()
+			err = writeCloser.Close()
 		}
 		bufPool.Put(buf)
-		return nil
+		return err
 	})
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -157,6 +158,36 @@ func TestTCPDualStackProxy(t *testing.T) {
 	testProxyAt(t, "tcp", proxy, ipv4ProxyAddr.String())
 }
 
+func TestTCP6ToTCP4Proxy(t *testing.T) {
+	backend := NewEchoServer(t, "tcp", "127.0.0.1:0")
+	defer backend.Close()
+	backend.Run()
+	frontendAddr := &net.TCPAddr{IP: net.IPv6loopback, Port: 0}
+	proxy, err := NewProxy(frontendAddr, backend.LocalAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testProxy(t, "tcp", proxy)
+}
+
+func TestTCPProxyBothFamilies(t *testing.T) {
+	backend := NewEchoServer(t, "tcp", "127.0.0.1:0")
+	defer backend.Close()
+	backend.Run()
+	proxy4, err := NewIPv4OnlyProxy(&net.TCPAddr{IP: net.IPv4zero, Port: 0}, backend.LocalAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy4.Close()
+	// the IPv4 proxy leaves the port free on the IPv6 addresses
+	port := proxy4.FrontendAddr().(*net.TCPAddr).Port
+	proxy6, err := NewProxy(&net.TCPAddr{IP: net.IPv6unspecified, Port: port}, backend.LocalAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testProxyAt(t, "tcp", proxy6, net.JoinHostPort("::1", strconv.Itoa(port)))
+}
+
 func TestUDP4Proxy(t *testing.T) {
 	backend := NewEchoServer(t, "udp", "127.0.0.1:0")
 	defer backend.Close()
@@ -214,3 +245,22 @@ func TestUDPWriteError(t *testing.T) {
 		t.Fatal(fmt.Errorf("Expected [%v] but got [%v]", testBuf, recvBuf))
 	}
 }
+
+func TestListenNetwork(t *testing.T) {
+	for _, c := range []struct {
+		ip       net.IP
+		ipv4Only bool
+		network  string
+	}{
+		{nil, false, "tcp"},
+		{net.IPv4zero, false, "tcp"},
+		{net.IPv4zero, true, "tcp4"},
+		{net.IPv4(127, 0, 0, 1), false, "tcp4"},
+		{net.IPv6unspecified, false, "tcp6"},
+		{net.IPv6loopback, true, "tcp6"},
+	} {
+		if network := ListenNetwork("tcp", c.ip, c.ipv4Only); network != c.network {
+			t.Fatalf("Expected %s for %v (ipv4Only=%v), got %s", c.network, c.ip, c.ipv4Only, network)
+		}
+	}
+}
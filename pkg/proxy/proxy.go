@@ -18,12 +18,40 @@ type Proxy interface {
 }
 
 func NewProxy(frontendAddr, backendAddr net.Addr) (Proxy, error) {
+	return newProxy(frontendAddr, backendAddr, false)
+}
+
+// NewIPv4OnlyProxy is NewProxy for a frontend on the unspecified IPv4
+// address which leaves the port free on the IPv6 addresses, for another
+// proxy.
+func NewIPv4OnlyProxy(frontendAddr, backendAddr net.Addr) (Proxy, error) {
+	return newProxy(frontendAddr, backendAddr, true)
+}
+
+func newProxy(frontendAddr, backendAddr net.Addr, ipv4Only bool) (Proxy, error) {
 	switch frontendAddr.(type) {
 	case *net.UDPAddr:
-		return NewUDPProxy(frontendAddr.(*net.UDPAddr), backendAddr.(*net.UDPAddr))
+		return NewUDPProxy(frontendAddr.(*net.UDPAddr), backendAddr.(*net.UDPAddr), ipv4Only)
 	case *net.TCPAddr:
-		return NewTCPProxy(frontendAddr.(*net.TCPAddr), backendAddr.(*net.TCPAddr))
+		return NewTCPProxy(frontendAddr.(*net.TCPAddr), backendAddr.(*net.TCPAddr), ipv4Only)
 	default:
 		panic(fmt.Errorf("Unsupported protocol"))
 	}
 }
+
+// ListenNetwork returns the network to listen on ip with proto, tcp or
+// udp. The unspecified IPv4 address accepts the IPv6 traffic too, unless
+// ipv4Only is set because the port is listened on the unspecified IPv6
+// address separately.
+func ListenNetwork(proto string, ip net.IP, ipv4Only bool) string {
+	switch {
+	case ip == nil || ip.Equal(net.IPv4zero):
+		if ipv4Only {
+			return proto + "4"
+		}
+		return proto
+	case ip.To4() != nil:
+		return proto + "4"
+	}
+	return proto + "6"
+}
@@ -14,8 +14,8 @@ type TCPProxy struct {
 	backendAddr  *net.TCPAddr
 }
 
-func NewTCPProxy(frontendAddr, backendAddr *net.TCPAddr) (*TCPProxy, error) {
-	listener, err := net.ListenTCP("tcp", frontendAddr)
+func NewTCPProxy(frontendAddr, backendAddr *net.TCPAddr, ipv4Only bool) (*TCPProxy, error) {
+	listener, err := net.ListenTCP(ListenNetwork("tcp", frontendAddr.IP, ipv4Only), frontendAddr)
 	if err != nil {
 		return nil, err
 	}
@@ -49,8 +49,8 @@ type UDPProxy struct {
 	connTrackLock  sync.Mutex
 }
 
-func NewUDPProxy(frontendAddr, backendAddr *net.UDPAddr) (*UDPProxy, error) {
-	listener, err := net.ListenUDP("udp", frontendAddr)
+func NewUDPProxy(frontendAddr, backendAddr *net.UDPAddr, ipv4Only bool) (*UDPProxy, error) {
+	listener, err := net.ListenUDP(ListenNetwork("udp", frontendAddr.IP, ipv4Only), frontendAddr)
 	if err != nil {
 		return nil, err
 	}
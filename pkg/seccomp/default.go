@@ -0,0 +1,88 @@
+package seccomp
+
+// DefaultProfile is applied to containers without a --security-opt seccomp
+// option. It allows everything except the syscalls that manipulate the
+// kernel, other namespaces or the memory of other processes, which fail with
+// EPERM. Privileged containers run without a profile by default.
+var DefaultProfile = &Profile{
+	DefaultAction: ActAllow,
+	Syscalls: []*Syscall{
+		{Name: "acct", Action: ActErrno},
+		{Name: "add_key", Action: ActErrno},
+		{Name: "adjtimex", Action: ActErrno},
+		{Name: "bpf", Action: ActErrno},
+		{Name: "clock_adjtime", Action: ActErrno},
+		{Name: "clock_settime", Action: ActErrno},
+		{Name: "create_module", Action: ActErrno},
+		{Name: "delete_module", Action: ActErrno},
+		{Name: "finit_module", Action: ActErrno},
+		{Name: "get_kernel_syms", Action: ActErrno},
+		{Name: "get_mempolicy", Action: ActErrno},
+		{Name: "init_module", Action: ActErrno},
+		{Name: "ioperm", Action: ActErrno},
+		{Name: "iopl", Action: ActErrno},
+		{Name: "kcmp", Action: ActErrno},
+		{Name: "kexec_file_load", Action: ActErrno},
+		{Name: "kexec_load", Action: ActErrno},
+		{Name: "keyctl", Action: ActErrno},
+		{Name: "lookup_dcookie", Action: ActErrno},
+		{Name: "mbind", Action: ActErrno},
+		{Name: "mount", Action: ActErrno},
+		{Name: "move_pages", Action: ActErrno},
+		{Name: "name_to_handle_at", Action: ActErrno},
+		{Name: "nfsservctl", Action: ActErrno},
+		{Name: "open_by_handle_at", Action: ActErrno},
+		{Name: "perf_event_open", Action: ActErrno},
+		{Name: "pivot_root", Action: ActErrno},
+		{Name: "process_vm_readv", Action: ActErrno},
+		{Name: "process_vm_writev", Action: ActErrno},
+		{Name: "ptrace", Action: ActErrno},
+		{Name: "query_module", Action: ActErrno},
+		{Name: "quotactl", Action: ActErrno},
+		{Name: "reboot", Action: ActErrno},
+		{Name: "request_key", Action: ActErrno},
+		{Name: "set_mempolicy", Action: ActErrno},
+		{Name: "setns", Action: ActErrno},
+		{Name: "settimeofday", Action: ActErrno},
+		{Name: "stime", Action: ActErrno},
+		{Name: "swapoff", Action: ActErrno},
+		{Name: "swapon", Action: ActErrno},
+		{Name: "sysfs", Action: ActErrno},
+		{Name: "_sysctl", Action: ActErrno},
+		{Name: "umount", Action: ActErrno},
+		{Name: "umount2", Action: ActErrno},
+		{Name: "unshare", Action: ActErrno},
+		{Name: "uselib", Action: ActErrno},
+		{Name: "userfaultfd", Action: ActErrno},
+		{Name: "ustat", Action: ActErrno},
+		{Name: "vm86", Action: ActErrno},
+		{Name: "vm86old", Action: ActErrno},
+	},
+}
+
+// sysAdminSyscalls are denied by the default profile only to containers
+// without CAP_SYS_ADMIN, which the kernel checks for them anyway.
+var sysAdminSyscalls = map[string]bool{
+	"mount":   true,
+	"umount":  true,
+	"umount2": true,
+	"unshare": true,
+}
+
+// DefaultProfileFor returns the default profile relaxed for a container
+// with the given capabilities.
+func DefaultProfileFor(capabilities []string) *Profile {
+	for _, c := range capabilities {
+		if c != "SYS_ADMIN" {
+			continue
+		}
+		p := &Profile{DefaultAction: DefaultProfile.DefaultAction}
+		for _, s := range DefaultProfile.Syscalls {
+			if !sysAdminSyscalls[s.Name] {
+				p.Syscalls = append(p.Syscalls, s)
+			}
+		}
+		return p
+	}
+	return DefaultProfile
+}
@@ -0,0 +1,103 @@
+// Package seccomp defines the JSON format of the seccomp profiles that can be
+// applied to containers with --security-opt seccomp=<file>.
+package seccomp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type Action string
+
+const (
+	ActKill  Action = "SCMP_ACT_KILL"
+	ActTrap  Action = "SCMP_ACT_TRAP"
+	ActErrno Action = "SCMP_ACT_ERRNO" // fails the syscall with EPERM
+	ActAllow Action = "SCMP_ACT_ALLOW"
+)
+
+type Operator string
+
+const (
+	OpNotEqual     Operator = "SCMP_CMP_NE"
+	OpLessThan     Operator = "SCMP_CMP_LT"
+	OpLessEqual    Operator = "SCMP_CMP_LE"
+	OpEqualTo      Operator = "SCMP_CMP_EQ"
+	OpGreaterEqual Operator = "SCMP_CMP_GE"
+	OpGreaterThan  Operator = "SCMP_CMP_GT"
+	OpMaskedEqual  Operator = "SCMP_CMP_MASKED_EQ" // the argument masked with value equals valueTwo
+)
+
+// Arg restricts a rule to calls whose argument at Index compares to Value
+// with Op.
+type Arg struct {
+	Index    uint     `json:"index"`
+	Value    uint64   `json:"value"`
+	ValueTwo uint64   `json:"valueTwo"`
+	Op       Operator `json:"op"`
+}
+
+// Syscall applies Action to calls of the syscall Name matching all of Args.
+type Syscall struct {
+	Name   string `json:"name"`
+	Action Action `json:"action"`
+	Args   []*Arg `json:"args"`
+}
+
+// Profile is a seccomp profile. Rules are tried in order and DefaultAction
+// applies to the syscalls matching none of them.
+type Profile struct {
+	DefaultAction Action     `json:"defaultAction"`
+	Syscalls      []*Syscall `json:"syscalls"`
+}
+
+// LoadProfile parses and validates a JSON seccomp profile.
+func LoadProfile(body string) (*Profile, error) {
+	var p Profile
+	if err := json.Unmarshal([]byte(body), &p); err != nil {
+		return nil, fmt.Errorf("Decoding seccomp profile failed: %v", err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (p *Profile) validate() error {
+	if !validAction(p.DefaultAction) {
+		return fmt.Errorf("Invalid seccomp default action %q", p.DefaultAction)
+	}
+	for _, s := range p.Syscalls {
+		if s.Name == "" {
+			return fmt.Errorf("Invalid seccomp rule: missing syscall name")
+		}
+		if !validAction(s.Action) {
+			return fmt.Errorf("Invalid seccomp action %q for %s", s.Action, s.Name)
+		}
+		for _, a := range s.Args {
+			if a.Index > 5 {
+				return fmt.Errorf("Invalid seccomp argument index %d for %s", a.Index, s.Name)
+			}
+			if !validOperator(a.Op) {
+				return fmt.Errorf("Invalid seccomp operator %q for %s", a.Op, s.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func validAction(a Action) bool {
+	switch a {
+	case ActKill, ActTrap, ActErrno, ActAllow:
+		return true
+	}
+	return false
+}
+
+func validOperator(op Operator) bool {
+	switch op {
+	case OpNotEqual, OpLessThan, OpLessEqual, OpEqualTo, OpGreaterEqual, OpGreaterThan, OpMaskedEqual:
+		return true
+	}
+	return false
+}
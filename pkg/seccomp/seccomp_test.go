@@ -0,0 +1,79 @@
+package seccomp
+
+import (
+	"testing"
+)
+
+func TestLoadProfile(t *testing.T) {
+	p, err := LoadProfile(`{
+		"defaultAction": "SCMP_ACT_ERRNO",
+		"syscalls": [
+			{"name": "read", "action": "SCMP_ACT_ALLOW"},
+			{"name": "personality", "action": "SCMP_ACT_ALLOW", "args": [
+				{"index": 0, "value": 8, "op": "SCMP_CMP_EQ"}
+			]}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.DefaultAction != ActErrno {
+		t.Fatalf("expected default action %s, got %s", ActErrno, p.DefaultAction)
+	}
+	if len(p.Syscalls) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(p.Syscalls))
+	}
+	if a := p.Syscalls[1].Args; len(a) != 1 || a[0].Value != 8 || a[0].Op != OpEqualTo {
+		t.Fatalf("unexpected args %v", a)
+	}
+}
+
+func TestLoadInvalidProfile(t *testing.T) {
+	for _, body := range []string{
+		`not json`,
+		`{"defaultAction": "SCMP_ACT_NOPE"}`,
+		`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"action": "SCMP_ACT_KILL"}]}`,
+		`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"name": "read", "action": "allow"}]}`,
+		`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"name": "read", "action": "SCMP_ACT_KILL", "args": [{"index": 6, "op": "SCMP_CMP_EQ"}]}]}`,
+		`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"name": "read", "action": "SCMP_ACT_KILL", "args": [{"index": 0, "op": "=="}]}]}`,
+	} {
+		if _, err := LoadProfile(body); err == nil {
+			t.Errorf("expected an error loading %s", body)
+		}
+	}
+}
+
+func TestDefaultProfileIsValid(t *testing.T) {
+	if err := DefaultProfile.validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefaultProfileFor(t *testing.T) {
+	denied := func(p *Profile, name string) bool {
+		for _, s := range p.Syscalls {
+			if s.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	p := DefaultProfileFor([]string{"CHOWN", "NET_RAW"})
+	if !denied(p, "mount") || !denied(p, "unshare") {
+		t.Fatal("expected mount and unshare to be denied without SYS_ADMIN")
+	}
+
+	p = DefaultProfileFor([]string{"CHOWN", "SYS_ADMIN"})
+	for _, name := range []string{"mount", "umount", "umount2", "unshare"} {
+		if denied(p, name) {
+			t.Fatalf("expected %s to be allowed with SYS_ADMIN", name)
+		}
+	}
+	if !denied(p, "reboot") {
+		t.Fatal("expected reboot to stay denied with SYS_ADMIN")
+	}
+	if !denied(DefaultProfile, "mount") {
+		t.Fatal("expected the default profile to be left unchanged")
+	}
+}
@@ -1,10 +1,18 @@
 package signal
 
 import (
+	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 )
 
+// DefaultStopSignal is the signal sent to stop a container whose
+// configuration does not set one.
+const DefaultStopSignal = "SIGTERM"
+
 func CatchAll(sigc chan os.Signal) {
 	handledSigs := []os.Signal{}
 	for _, s := range SignalMap {
@@ -17,3 +25,20 @@ func StopCatch(sigc chan os.Signal) {
 	signal.Stop(sigc)
 	close(sigc)
 }
+
+// ParseSignal translates a signal number, or a name with or without the SIG
+// prefix ("9", "KILL" or "SIGKILL"), to a signal.
+func ParseSignal(rawSignal string) (syscall.Signal, error) {
+	// the largest legal signal is 31, so parse on 5 bits
+	if s, err := strconv.ParseUint(rawSignal, 10, 5); err == nil {
+		if s == 0 {
+			return -1, fmt.Errorf("Invalid signal: %s", rawSignal)
+		}
+		return syscall.Signal(s), nil
+	}
+	s, ok := SignalMap[strings.TrimPrefix(strings.ToUpper(rawSignal), "SIG")]
+	if !ok {
+		return -1, fmt.Errorf("Invalid signal: %s", rawSignal)
+	}
+	return s, nil
+}
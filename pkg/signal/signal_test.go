@@ -0,0 +1,33 @@
+package signal
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestParseSignal(t *testing.T) {
+	if len(SignalMap) == 0 {
+		t.Skip("no signal table on this platform")
+	}
+	for raw, expected := range map[string]syscall.Signal{
+		"9":       syscall.Signal(9),
+		"15":      syscall.Signal(15),
+		"KILL":    SignalMap["KILL"],
+		"SIGQUIT": SignalMap["QUIT"],
+		"sigint":  SignalMap["INT"],
+	} {
+		s, err := ParseSignal(raw)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %v", raw, err)
+		}
+		if s != expected {
+			t.Fatalf("Expected %q to parse as %d, got %d", raw, expected, s)
+		}
+	}
+
+	for _, raw := range []string{"", "0", "32", "SIGNOPE", "-1"} {
+		if _, err := ParseSignal(raw); err == nil {
+			t.Fatalf("Expected an error parsing %q", raw)
+		}
+	}
+}
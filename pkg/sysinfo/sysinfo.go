@@ -7,13 +7,16 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/libcontainer/cgroups"
+	"github.com/docker/libcontainer/seccomp"
 )
 
 type SysInfo struct {
 	MemoryLimit            bool
 	SwapLimit              bool
+	PidsLimit              bool
 	IPv4ForwardingDisabled bool
 	AppArmor               bool
+	Seccomp                bool
 }
 
 func New(quiet bool) *SysInfo {
@@ -37,11 +40,28 @@ func New(quiet bool) *SysInfo {
 		}
 	}
 
+	if cgroupPidsMountpoint, err := cgroups.FindCgroupMountpoint("pids"); err != nil {
+		if !quiet {
+			log.Printf("WARNING: %s\n", err)
+		}
+	} else {
+		_, err := ioutil.ReadFile(path.Join(cgroupPidsMountpoint, "pids.max"))
+		sysInfo.PidsLimit = err == nil
+		if !sysInfo.PidsLimit && !quiet {
+			log.Printf("WARNING: Your kernel does not support cgroup pids limit.")
+		}
+	}
+
 	// Check if AppArmor seems to be enabled on this system.
 	if _, err := os.Stat("/sys/kernel/security/apparmor"); os.IsNotExist(err) {
 		sysInfo.AppArmor = false
 	} else {
 		sysInfo.AppArmor = true
 	}
+
+	sysInfo.Seccomp = seccomp.IsEnabled()
+	if !sysInfo.Seccomp && !quiet {
+		log.Printf("WARNING: Your kernel does not support seccomp filters.")
+	}
 	return sysInfo
 }
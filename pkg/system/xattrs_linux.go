@@ -1,6 +1,7 @@
 package system
 
 import (
+	"strings"
 	"syscall"
 	"unsafe"
 )
@@ -17,21 +18,26 @@ func Lgetxattr(path string, attr string) ([]byte, error) {
 	}
 
 	dest := make([]byte, 128)
-	destBytes := unsafe.Pointer(&dest[0])
-	sz, _, errno := syscall.Syscall6(syscall.SYS_LGETXATTR, uintptr(unsafe.Pointer(pathBytes)), uintptr(unsafe.Pointer(attrBytes)), uintptr(destBytes), uintptr(len(dest)), 0, 0)
-	if errno == syscall.ENODATA {
-		return nil, nil
-	}
-	if errno == syscall.ERANGE {
-		dest = make([]byte, sz)
-		destBytes := unsafe.Pointer(&dest[0])
-		sz, _, errno = syscall.Syscall6(syscall.SYS_LGETXATTR, uintptr(unsafe.Pointer(pathBytes)), uintptr(unsafe.Pointer(attrBytes)), uintptr(destBytes), uintptr(len(dest)), 0, 0)
-	}
-	if errno != 0 {
-		return nil, errno
-	}
+	for {
+		sz, _, errno := syscall.Syscall6(syscall.SYS_LGETXATTR, uintptr(unsafe.Pointer(pathBytes)), uintptr(unsafe.Pointer(attrBytes)), uintptr(unsafe.Pointer(&dest[0])), uintptr(len(dest)), 0, 0)
+		if errno == syscall.ENODATA {
+			return nil, nil
+		}
+		if errno == syscall.ERANGE {
+			// the value doesn't fit, ask for its size
+			sz, _, errno = syscall.Syscall6(syscall.SYS_LGETXATTR, uintptr(unsafe.Pointer(pathBytes)), uintptr(unsafe.Pointer(attrBytes)), 0, 0, 0, 0)
+			if errno != 0 {
+				return nil, errno
+			}
+			dest = make([]byte, sz+1)
+			continue
+		}
+		if errno != 0 {
+			return nil, errno
+		}
 
-	return dest[:sz], nil
+		return dest[:sz], nil
+	}
 }
 
 var _zero uintptr
@@ -57,3 +63,37 @@ func Lsetxattr(path string, attr string, data []byte, flags int) error {
 	}
 	return nil
 }
+
+// Llistxattr returns the names of the xattrs set on path, without following
+// symlinks.
+func Llistxattr(path string) ([]string, error) {
+	pathBytes, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dest := make([]byte, 128)
+	for {
+		sz, _, errno := syscall.Syscall(syscall.SYS_LLISTXATTR, uintptr(unsafe.Pointer(pathBytes)), uintptr(unsafe.Pointer(&dest[0])), uintptr(len(dest)))
+		if errno == syscall.ERANGE {
+			// the list grew, ask for its size
+			sz, _, errno = syscall.Syscall(syscall.SYS_LLISTXATTR, uintptr(unsafe.Pointer(pathBytes)), 0, 0)
+			if errno != 0 {
+				return nil, errno
+			}
+			dest = make([]byte, sz+1)
+			continue
+		}
+		if errno != 0 {
+			return nil, errno
+		}
+
+		var attrs []string
+		for _, name := range strings.Split(string(dest[:sz]), "\x00") {
+			if name != "" {
+				attrs = append(attrs, name)
+			}
+		}
+		return attrs, nil
+	}
+}
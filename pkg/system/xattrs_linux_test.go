@@ -0,0 +1,39 @@
+package system
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestLgetxattrLargeValue(t *testing.T) {
+	file, err := ioutil.TempFile("/var/tmp", "docker-xattr-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	// larger than the first buffer of Lgetxattr
+	value := bytes.Repeat([]byte{'a'}, 1000)
+	if err := Lsetxattr(file.Name(), "user.test", value, 0); err != nil {
+		if err == syscall.EOPNOTSUPP {
+			t.Skip("The filesystem doesn't support user xattrs")
+		}
+		t.Fatal(err)
+	}
+
+	data, err := Lgetxattr(file.Name(), "user.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, value) {
+		t.Fatalf("Expected a value of %d bytes, got %d bytes", len(value), len(data))
+	}
+
+	if data, err := Lgetxattr(file.Name(), "user.missing"); err != nil || data != nil {
+		t.Fatalf("Expected no value for a missing xattr, got %q and %v", data, err)
+	}
+}
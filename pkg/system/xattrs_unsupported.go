@@ -9,3 +9,7 @@ func Lgetxattr(path string, attr string) ([]byte, error) {
 func Lsetxattr(path string, attr string, data []byte, flags int) error {
 	return ErrNotSupportedPlatform
 }
+
+func Llistxattr(path string) ([]string, error) {
+	return nil, ErrNotSupportedPlatform
+}
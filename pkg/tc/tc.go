@@ -0,0 +1,179 @@
+// Package tc shapes the traffic of network interfaces with the tc command.
+//
+// The traffic sent out of an interface is shaped with a tbf qdisc, and the
+// traffic received on it is policed with a filter of the ingress qdisc,
+// which drops the packets over the rate.
+package tc
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// latency is the longest a packet waits in the tbf qdisc
+	latency = "50ms"
+	// minBurst is the smallest burst of the shaping, in bytes, which has
+	// to hold a few full frames
+	minBurst = 16 * 1024
+)
+
+var (
+	tcPath        string
+	ErrTcNotFound = errors.New("tc not found")
+
+	droppedRegex = regexp.MustCompile(`\(dropped (\d+),`)
+)
+
+// Stats are the packets dropped by the shaping of an interface.
+type Stats struct {
+	// Dropped counts the packets sent out of the interface dropped by
+	// the tbf qdisc
+	Dropped uint64
+	// IngressDropped counts the packets received on the interface dropped
+	// by the ingress policer
+	IngressDropped uint64
+}
+
+func initCheck() error {
+	if tcPath == "" {
+		path, err := exec.LookPath("tc")
+		if err != nil {
+			return ErrTcNotFound
+		}
+		tcPath = path
+	}
+	return nil
+}
+
+// Call 'tc' system command, passing supplied arguments
+func Raw(args ...string) ([]byte, error) {
+	if err := initCheck(); err != nil {
+		return nil, err
+	}
+	log.Debugf("%s, %v", tcPath, args)
+
+	output, err := exec.Command(tcPath, args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("tc failed: tc %v: %s (%s)", strings.Join(args, " "), output, err)
+	}
+	return output, nil
+}
+
+// SetRate shapes the traffic sent out of iface to rate, and polices the
+// traffic received on iface to ingressRate, in bits per second. A rate of 0
+// removes the shaping of its direction. Setting the ingress rate resets the
+// drop counter of the ingress policer.
+func SetRate(iface string, rate, ingressRate int64) error {
+	qdiscs, err := qdiscs(iface)
+	if err != nil {
+		return err
+	}
+
+	if rate > 0 {
+		if _, err := Raw("qdisc", "replace", "dev", iface, "root", "tbf",
+			"rate", bits(rate), "burst", burst(rate), "latency", latency); err != nil {
+			return err
+		}
+	} else if qdiscs["tbf"] {
+		if _, err := Raw("qdisc", "del", "dev", iface, "root"); err != nil {
+			return err
+		}
+	}
+
+	// the ingress qdisc is created again, rather than its filter replaced
+	if qdiscs["ingress"] {
+		if _, err := Raw("qdisc", "del", "dev", iface, "ingress"); err != nil {
+			return err
+		}
+	}
+	if ingressRate > 0 {
+		if _, err := Raw("qdisc", "add", "dev", iface, "handle", "ffff:", "ingress"); err != nil {
+			return err
+		}
+		if _, err := Raw("filter", "add", "dev", iface, "parent", "ffff:", "protocol", "all",
+			"prio", "1", "u32", "match", "u32", "0", "0",
+			"police", "rate", bits(ingressRate), "burst", burst(ingressRate), "drop", "flowid", ":1"); err != nil {
+			Raw("qdisc", "del", "dev", iface, "ingress")
+			return err
+		}
+	}
+	return nil
+}
+
+// GetStats returns the packets dropped by the shaping of iface.
+func GetStats(iface string) (*Stats, error) {
+	output, err := Raw("-s", "qdisc", "show", "dev", iface)
+	if err != nil {
+		return nil, err
+	}
+	return parseStats(string(output)), nil
+}
+
+// qdiscs returns the kinds of the qdiscs of iface
+func qdiscs(iface string) (map[string]bool, error) {
+	output, err := Raw("qdisc", "show", "dev", iface)
+	if err != nil {
+		return nil, err
+	}
+	kinds := make(map[string]bool)
+	for _, line := range strings.Split(string(output), "\n") {
+		if fields := strings.Fields(line); len(fields) > 1 && fields[0] == "qdisc" {
+			kinds[fields[1]] = true
+		}
+	}
+	return kinds, nil
+}
+
+// parseStats reads the drop counters of the tbf and ingress qdiscs in the
+// output of 'tc -s qdisc show', where the statistics follow each qdisc:
+//
+//	qdisc tbf 8001: root refcnt 2 rate 1Mbit burst 16Kb lat 50.0ms
+//	 Sent 1234 bytes 10 pkt (dropped 5, overlimits 3 requeues 0)
+func parseStats(output string) *Stats {
+	var (
+		stats = &Stats{}
+		kind  string
+	)
+	for _, line := range strings.Split(output, "\n") {
+		if fields := strings.Fields(line); len(fields) > 1 && fields[0] == "qdisc" {
+			kind = fields[1]
+			continue
+		}
+		matches := droppedRegex.FindStringSubmatch(line)
+		if len(matches) != 2 {
+			continue
+		}
+		dropped, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch kind {
+		case "tbf":
+			stats.Dropped = dropped
+		case "ingress":
+			stats.IngressDropped = dropped
+		}
+		kind = ""
+	}
+	return stats
+}
+
+func bits(rate int64) string {
+	return strconv.FormatInt(rate, 10) + "bit"
+}
+
+// burst allows 10ms worth of traffic at rate
+func burst(rate int64) string {
+	b := rate / 8 / 100
+	if b < minBurst {
+		b = minBurst
+	}
+	return strconv.FormatInt(b, 10)
+}
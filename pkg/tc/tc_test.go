@@ -0,0 +1,78 @@
+package tc
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestParseStats(t *testing.T) {
+	output := `qdisc tbf 8001: root refcnt 2 rate 1Mbit burst 16Kb lat 50.0ms 
+ Sent 123456 bytes 130 pkt (dropped 12, overlimits 40 requeues 0) 
+ backlog 0b 0p requeues 0
+qdisc ingress ffff: parent ffff:fff1 ---------------- 
+ Sent 8000 bytes 20 pkt (dropped 3, overlimits 0 requeues 0) 
+ backlog 0b 0p requeues 0
+`
+	stats := parseStats(output)
+	if stats.Dropped != 12 || stats.IngressDropped != 3 {
+		t.Fatalf("Expected 12 and 3 dropped packets, got %+v", stats)
+	}
+
+	output = `qdisc noqueue 0: root refcnt 2 
+ Sent 0 bytes 0 pkt (dropped 7, overlimits 0 requeues 0) 
+ backlog 0b 0p requeues 0
+`
+	if stats := parseStats(output); stats.Dropped != 0 || stats.IngressDropped != 0 {
+		t.Fatalf("Expected no packet dropped by the shaping, got %+v", stats)
+	}
+}
+
+func TestSetRate(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("Shaping an interface requires root")
+	}
+	if out, err := exec.Command("ip", "link", "add", "tctest0", "type", "veth", "peer", "name", "tctest1").CombinedOutput(); err != nil {
+		t.Skipf("Could not create a veth pair: %s, %v", out, err)
+	}
+	defer exec.Command("ip", "link", "del", "tctest0").Run()
+
+	if err := SetRate("tctest0", 10000000, 0); err != nil {
+		t.Fatal(err)
+	}
+	kinds, err := qdiscs("tctest0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !kinds["tbf"] || kinds["ingress"] {
+		t.Fatalf("Expected a tbf qdisc only, got %v", kinds)
+	}
+	if _, err := GetStats("tctest0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetRate("tctest0", 0, 10000000); err != nil {
+		// the police action may not be available in the kernel
+		if strings.Contains(err.Error(), "action") {
+			t.Skip(err)
+		}
+		t.Fatal(err)
+	}
+	if kinds, err = qdiscs("tctest0"); err != nil {
+		t.Fatal(err)
+	}
+	if kinds["tbf"] || !kinds["ingress"] {
+		t.Fatalf("Expected an ingress qdisc only, got %v", kinds)
+	}
+
+	if err := SetRate("tctest0", 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if kinds, err = qdiscs("tctest0"); err != nil {
+		t.Fatal(err)
+	}
+	if kinds["tbf"] || kinds["ingress"] {
+		t.Fatalf("Expected the shaping to be removed, got %v", kinds)
+	}
+}
@@ -0,0 +1,35 @@
+package units
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var rateRegex = regexp.MustCompile(`^(\d+)([kKmMgGtT])?(bit|bps)?$`)
+
+// RateInBits parses a human-readable rate, the way tc does, and returns the
+// number of bits per second, or -1 if the string is unparseable. The rate
+// is in bits per second with the 'bit' suffix or no suffix (eg. "10mbit"),
+// and in bytes per second with the 'bps' suffix (eg. "1mbps"). Prefixes are
+// decimal and case-insensitive.
+func RateInBits(rate string) (int64, error) {
+	matches := rateRegex.FindStringSubmatch(rate)
+	if len(matches) != 4 {
+		return -1, fmt.Errorf("invalid rate: '%s'", rate)
+	}
+
+	bits, err := strconv.ParseInt(matches[1], 10, 0)
+	if err != nil {
+		return -1, err
+	}
+
+	if mul, ok := decimalMap[strings.ToLower(matches[2])]; ok {
+		bits *= mul
+	}
+	if matches[3] == "bps" {
+		bits *= 8
+	}
+	return bits, nil
+}
@@ -0,0 +1,26 @@
+package units
+
+import (
+	"testing"
+)
+
+func TestRateInBits(t *testing.T) {
+	assertSuccessEquals(t, 32, RateInBits, "32")
+	assertSuccessEquals(t, 32, RateInBits, "32bit")
+	assertSuccessEquals(t, 32*KB, RateInBits, "32k")
+	assertSuccessEquals(t, 32*KB, RateInBits, "32kbit")
+	assertSuccessEquals(t, 32*MB, RateInBits, "32Mbit")
+	assertSuccessEquals(t, 32*GB, RateInBits, "32gbit")
+	assertSuccessEquals(t, 32*TB, RateInBits, "32Tbit")
+	assertSuccessEquals(t, 32*8, RateInBits, "32bps")
+	assertSuccessEquals(t, 32*8*MB, RateInBits, "32mbps")
+
+	assertError(t, RateInBits, "")
+	assertError(t, RateInBits, "hello")
+	assertError(t, RateInBits, "-32")
+	assertError(t, RateInBits, "32.3mbit")
+	assertError(t, RateInBits, " 32 ")
+	assertError(t, RateInBits, "32 mbit")
+	assertError(t, RateInBits, "32mb")
+	assertError(t, RateInBits, "32pbit")
+}
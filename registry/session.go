@@ -18,6 +18,7 @@ import (
 	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/httputils"
 	"github.com/docker/docker/pkg/tarsum"
 	"github.com/docker/docker/utils"
@@ -390,18 +391,43 @@ func (r *Session) PushImageJSONRegistry(imgData *ImgData, jsonRaw []byte, regist
 	return nil
 }
 
-func (r *Session) PushImageLayerRegistry(imgID string, layer io.Reader, registry string, token []string, jsonRaw []byte) (checksum string, checksumPayload string, err error) {
+// compressLayer compresses the tar stream layer as it is read.
+func compressLayer(layer io.Reader, compression archive.Compression) (io.ReadCloser, error) {
+	pipeR, pipeW := io.Pipe()
+	compressed, err := archive.ParallelCompressStream(pipeW, compression)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		_, err := io.Copy(compressed, layer)
+		if cerr := compressed.Close(); err == nil {
+			err = cerr
+		}
+		pipeW.CloseWithError(err)
+	}()
+	return pipeR, nil
+}
+
+// PushImageLayerRegistry pushes the uncompressed tar layer, compressed with
+// compression. The returned checksum is the tarsum of the tar, whatever its
+// compression, and checksumPayload the checksum of the pushed bytes.
+func (r *Session) PushImageLayerRegistry(imgID string, layer io.Reader, registry string, token []string, jsonRaw []byte, compression archive.Compression) (checksum string, checksumPayload string, err error) {
 
 	log.Debugf("[registry] Calling PUT %s", registry+"images/"+imgID+"/layer")
 
-	tarsumLayer, err := tarsum.NewTarSum(layer, false, tarsum.Version0)
+	tarsumLayer, err := tarsum.NewTarSum(layer, true, tarsum.Version0)
+	if err != nil {
+		return "", "", err
+	}
+	compressedLayer, err := compressLayer(tarsumLayer, compression)
 	if err != nil {
 		return "", "", err
 	}
+	defer compressedLayer.Close()
 	h := sha256.New()
 	h.Write(jsonRaw)
 	h.Write([]byte{'\n'})
-	checksumLayer := io.TeeReader(tarsumLayer, h)
+	checksumLayer := io.TeeReader(compressedLayer, h)
 
 	req, err := r.reqFactory.NewRequest("PUT", registry+"images/"+imgID+"/layer", checksumLayer)
 	if err != nil {
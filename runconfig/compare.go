@@ -14,7 +14,8 @@ func Compare(a, b *Config) bool {
 		a.MemorySwap != b.MemorySwap ||
 		a.CpuShares != b.CpuShares ||
 		a.OpenStdin != b.OpenStdin ||
-		a.Tty != b.Tty {
+		a.Tty != b.Tty ||
+		a.StopSignal != b.StopSignal {
 		return false
 	}
 	if len(a.Cmd) != len(b.Cmd) ||
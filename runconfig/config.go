@@ -32,7 +32,10 @@ type Config struct {
 	Entrypoint      []string
 	NetworkDisabled bool
 	MacAddress      string
+	IPAddress       string // Static IPv4 address on the bridge
+	IPv6Address     string // Static IPv6 address on the bridge
 	OnBuild         []string
+	StopSignal      string // Signal to stop the container, SIGTERM if empty
 }
 
 func ContainerConfigFromJob(job *engine.Job) *Config {
@@ -54,6 +57,9 @@ func ContainerConfigFromJob(job *engine.Job) *Config {
 		WorkingDir:      job.Getenv("WorkingDir"),
 		NetworkDisabled: job.GetenvBool("NetworkDisabled"),
 		MacAddress:      job.Getenv("MacAddress"),
+		IPAddress:       job.Getenv("IPAddress"),
+		IPv6Address:     job.Getenv("IPv6Address"),
+		StopSignal:      job.Getenv("StopSignal"),
 	}
 	job.GetenvJson("ExposedPorts", &config.ExposedPorts)
 	job.GetenvJson("Volumes", &config.Volumes)
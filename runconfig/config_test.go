@@ -262,3 +262,23 @@ func TestMerge(t *testing.T) {
 	}
 
 }
+
+func TestMergeStopSignal(t *testing.T) {
+	configImage := &Config{StopSignal: "SIGQUIT"}
+
+	configUser := &Config{}
+	if err := Merge(configUser, configImage); err != nil {
+		t.Fatal(err)
+	}
+	if configUser.StopSignal != "SIGQUIT" {
+		t.Fatalf("Expected the stop signal of the image, SIGQUIT, found %q", configUser.StopSignal)
+	}
+
+	configUser = &Config{StopSignal: "SIGINT"}
+	if err := Merge(configUser, configImage); err != nil {
+		t.Fatal(err)
+	}
+	if configUser.StopSignal != "SIGINT" {
+		t.Fatalf("Expected the user stop signal, SIGINT, found %q", configUser.StopSignal)
+	}
+}
@@ -29,6 +29,22 @@ func (n NetworkMode) IsNone() bool {
 	return n == "none"
 }
 
+// IsMacvlan indicates whether container use a macvlan interface of the
+// parent interface of the daemon
+func (n NetworkMode) IsMacvlan() bool {
+	return n == "macvlan"
+}
+
+// IsPlugin indicates whether container is attached to the network of the
+// network driver plugin named by the network mode
+func (n NetworkMode) IsPlugin() bool {
+	switch {
+	case n == "", n == "bridge", n.IsMacvlan(), !n.IsPrivate():
+		return false
+	}
+	return true
+}
+
 type IpcMode string
 
 // IsPrivate indicates whether container use it's private ipc stack
@@ -100,27 +116,34 @@ type RestartPolicy struct {
 }
 
 type HostConfig struct {
-	Binds           []string
-	ContainerIDFile string
-	LxcConf         []utils.KeyValuePair
-	Privileged      bool
-	PortBindings    nat.PortMap
-	Links           []string
-	PublishAllPorts bool
-	Dns             []string
-	DnsSearch       []string
-	ExtraHosts      []string
-	VolumesFrom     []string
-	Devices         []DeviceMapping
-	NetworkMode     NetworkMode
-	IpcMode         IpcMode
-	PidMode         PidMode
-	CapAdd          []string
-	CapDrop         []string
-	RestartPolicy   RestartPolicy
-	SecurityOpt     []string
-	ReadonlyRootfs  bool
-	Ulimits         []*ulimit.Ulimit
+	Binds              []string
+	ContainerIDFile    string
+	LxcConf            []utils.KeyValuePair
+	Privileged         bool
+	PortBindings       nat.PortMap
+	Links              []string
+	PublishAllPorts    bool
+	Dns                []string
+	DnsSearch          []string
+	DnsOptions         []string
+	ExtraHosts         []string
+	VolumesFrom        []string
+	Devices            []DeviceMapping
+	NetworkMode        NetworkMode
+	IpcMode            IpcMode
+	PidMode            PidMode
+	CapAdd             []string
+	CapDrop            []string
+	RestartPolicy      RestartPolicy
+	SecurityOpt        []string
+	ReadonlyRootfs     bool
+	Ulimits            []*ulimit.Ulimit
+	PidsLimit          int64
+	Init               *bool             // Run an init in the container, the daemon default if nil
+	Tmpfs              map[string]string // Paths of tmpfs mounts to their mount options
+	StorageOpt         map[string]string // Storage driver options of the container filesystem
+	NetworkIngressRate int64             // Rate of the traffic received by the container in bits per second, 0 is unlimited
+	NetworkEgressRate  int64             // Rate of the traffic sent by the container in bits per second, 0 is unlimited
 }
 
 // This is used by the create command when you want to set both the
@@ -145,13 +168,16 @@ func ContainerHostConfigFromJob(job *engine.Job) *HostConfig {
 	}
 
 	hostConfig := &HostConfig{
-		ContainerIDFile: job.Getenv("ContainerIDFile"),
-		Privileged:      job.GetenvBool("Privileged"),
-		PublishAllPorts: job.GetenvBool("PublishAllPorts"),
-		NetworkMode:     NetworkMode(job.Getenv("NetworkMode")),
-		IpcMode:         IpcMode(job.Getenv("IpcMode")),
-		PidMode:         PidMode(job.Getenv("PidMode")),
-		ReadonlyRootfs:  job.GetenvBool("ReadonlyRootfs"),
+		ContainerIDFile:    job.Getenv("ContainerIDFile"),
+		Privileged:         job.GetenvBool("Privileged"),
+		PublishAllPorts:    job.GetenvBool("PublishAllPorts"),
+		NetworkMode:        NetworkMode(job.Getenv("NetworkMode")),
+		IpcMode:            IpcMode(job.Getenv("IpcMode")),
+		PidMode:            PidMode(job.Getenv("PidMode")),
+		ReadonlyRootfs:     job.GetenvBool("ReadonlyRootfs"),
+		PidsLimit:          job.GetenvInt64("PidsLimit"),
+		NetworkIngressRate: job.GetenvInt64("NetworkIngressRate"),
+		NetworkEgressRate:  job.GetenvInt64("NetworkEgressRate"),
 	}
 
 	job.GetenvJson("LxcConf", &hostConfig.LxcConf)
@@ -160,6 +186,9 @@ func ContainerHostConfigFromJob(job *engine.Job) *HostConfig {
 	job.GetenvJson("RestartPolicy", &hostConfig.RestartPolicy)
 
 	job.GetenvJson("Ulimits", &hostConfig.Ulimits)
+	job.GetenvJson("Init", &hostConfig.Init)
+	job.GetenvJson("Tmpfs", &hostConfig.Tmpfs)
+	job.GetenvJson("StorageOpt", &hostConfig.StorageOpt)
 
 	hostConfig.SecurityOpt = job.GetenvList("SecurityOpt")
 	if Binds := job.GetenvList("Binds"); Binds != nil {
@@ -174,6 +203,9 @@ func ContainerHostConfigFromJob(job *engine.Job) *HostConfig {
 	if DnsSearch := job.GetenvList("DnsSearch"); DnsSearch != nil {
 		hostConfig.DnsSearch = DnsSearch
 	}
+	if DnsOptions := job.GetenvList("DnsOptions"); DnsOptions != nil {
+		hostConfig.DnsOptions = DnsOptions
+	}
 	if ExtraHosts := job.GetenvList("ExtraHosts"); ExtraHosts != nil {
 		hostConfig.ExtraHosts = ExtraHosts
 	}
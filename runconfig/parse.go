@@ -2,7 +2,10 @@ package runconfig
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net"
 	"path"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -10,6 +13,7 @@ import (
 	"github.com/docker/docker/opts"
 	flag "github.com/docker/docker/pkg/mflag"
 	"github.com/docker/docker/pkg/parsers"
+	"github.com/docker/docker/pkg/signal"
 	"github.com/docker/docker/pkg/ulimit"
 	"github.com/docker/docker/pkg/units"
 	"github.com/docker/docker/utils"
@@ -22,6 +26,13 @@ var (
 	ErrConflictNetworkHostname          = fmt.Errorf("Conflicting options: -h and the network mode (--net)")
 	ErrConflictHostNetworkAndDns        = fmt.Errorf("Conflicting options: --net=host can't be used with --dns. This configuration is invalid.")
 	ErrConflictHostNetworkAndLinks      = fmt.Errorf("Conflicting options: --net=host can't be used with links. This would result in undefined behavior.")
+	ErrConflictNetworkIPAddress         = fmt.Errorf("Conflicting options: --ip and --ip6 require the bridge network mode or a network driver plugin, or the macvlan network mode for --ip (--net)")
+	ErrConflictMacvlanNetworkAndPorts   = fmt.Errorf("Conflicting options: --net=macvlan can't be used with -p or -P. The container is reached directly on the network of the parent interface.")
+	ErrConflictNetworkRate              = fmt.Errorf("Conflicting options: --network-ingress-rate and --network-egress-rate require the bridge network mode (--net)")
+
+	// validPluginName matches the names of the network driver plugins,
+	// which name their sockets
+	validPluginName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
 )
 
 func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSet, error) {
@@ -40,6 +51,7 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		flExpose      = opts.NewListOpts(nil)
 		flDns         = opts.NewListOpts(opts.ValidateIPAddress)
 		flDnsSearch   = opts.NewListOpts(opts.ValidateDnsSearch)
+		flDnsOptions  = opts.NewListOpts(opts.ValidateDnsOpt)
 		flExtraHosts  = opts.NewListOpts(opts.ValidateExtraHost)
 		flVolumesFrom = opts.NewListOpts(nil)
 		flLxcOpts     = opts.NewListOpts(nil)
@@ -47,6 +59,8 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		flCapAdd      = opts.NewListOpts(nil)
 		flCapDrop     = opts.NewListOpts(nil)
 		flSecurityOpt = opts.NewListOpts(nil)
+		flTmpfs       = opts.NewListOpts(nil)
+		flStorageOpt  = opts.NewListOpts(nil)
 
 		flNetwork         = cmd.Bool([]string{"#n", "#-networking"}, true, "Enable networking for this container")
 		flPrivileged      = cmd.Bool([]string{"#privileged", "-privileged"}, false, "Give extended privileges to this container")
@@ -65,9 +79,16 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		flCpuset          = cmd.String([]string{"-cpuset"}, "", "CPUs in which to allow execution (0-3, 0,1)")
 		flNetMode         = cmd.String([]string{"-net"}, "bridge", "Set the Network mode for the container")
 		flMacAddress      = cmd.String([]string{"-mac-address"}, "", "Container MAC address (e.g. 92:d0:c6:0a:29:33)")
+		flIPAddress       = cmd.String([]string{"-ip"}, "", "Container IPv4 address (e.g. 172.17.0.10)")
+		flIPv6Address     = cmd.String([]string{"-ip6"}, "", "Container IPv6 address (e.g. 2001:db8::33)")
 		flIpcMode         = cmd.String([]string{"-ipc"}, "", "IPC namespace to use")
 		flRestartPolicy   = cmd.String([]string{"-restart"}, "", "Restart policy to apply when a container exits")
 		flReadonlyRootfs  = cmd.Bool([]string{"-read-only"}, false, "Mount the container's root filesystem as read only")
+		flPidsLimit       = cmd.Int64([]string{"-pids-limit"}, 0, "Tune container pids limit (set -1 for unlimited)")
+		flInit            = cmd.Bool([]string{"-init"}, false, "Run an init inside the container that forwards signals and reaps processes")
+		flStopSignal      = cmd.String([]string{"-stop-signal"}, "", fmt.Sprintf("Signal to stop a container, %s by default", signal.DefaultStopSignal))
+		flIngressRate     = cmd.String([]string{"-network-ingress-rate"}, "", "Limit the rate of the traffic received by the container (e.g. 10mbit)")
+		flEgressRate      = cmd.String([]string{"-network-egress-rate"}, "", "Limit the rate of the traffic sent by the container (e.g. 10mbit)")
 	)
 
 	cmd.Var(&flAttach, []string{"a", "-attach"}, "Attach to STDIN, STDOUT or STDERR")
@@ -80,6 +101,7 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 	cmd.Var(&flExpose, []string{"#expose", "-expose"}, "Expose a port or a range of ports")
 	cmd.Var(&flDns, []string{"#dns", "-dns"}, "Set custom DNS servers")
 	cmd.Var(&flDnsSearch, []string{"-dns-search"}, "Set custom DNS search domains")
+	cmd.Var(&flDnsOptions, []string{"-dns-opt"}, "Set custom DNS resolver options")
 	cmd.Var(&flExtraHosts, []string{"-add-host"}, "Add a custom host-to-IP mapping (host:ip)")
 	cmd.Var(&flVolumesFrom, []string{"#volumes-from", "-volumes-from"}, "Mount volumes from the specified container(s)")
 	cmd.Var(&flLxcOpts, []string{"#lxc-conf", "-lxc-conf"}, "Add custom lxc options")
@@ -87,6 +109,8 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 	cmd.Var(&flCapDrop, []string{"-cap-drop"}, "Drop Linux capabilities")
 	cmd.Var(&flSecurityOpt, []string{"-security-opt"}, "Security Options")
 	cmd.Var(flUlimits, []string{"-ulimit"}, "Ulimit options")
+	cmd.Var(&flTmpfs, []string{"-tmpfs"}, "Mount a tmpfs directory (format: <path>[:<options>])")
+	cmd.Var(&flStorageOpt, []string{"-storage-opt"}, "Set storage driver options per container")
 
 	cmd.Require(flag.Min, 1)
 
@@ -99,19 +123,45 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		return nil, nil, cmd, ErrInvalidWorkingDirectory
 	}
 
+	if *flStopSignal != "" {
+		if _, err := signal.ParseSignal(*flStopSignal); err != nil {
+			return nil, nil, cmd, err
+		}
+	}
+
 	// Validate the input mac address
 	if *flMacAddress != "" {
 		if _, err := opts.ValidateMACAddress(*flMacAddress); err != nil {
 			return nil, nil, cmd, fmt.Errorf("%s is not a valid mac address", *flMacAddress)
 		}
 	}
+	if *flIPAddress != "" {
+		if ip := net.ParseIP(*flIPAddress); ip == nil || ip.To4() == nil {
+			return nil, nil, cmd, fmt.Errorf("%s is not a valid IPv4 address", *flIPAddress)
+		}
+	}
+	if *flIPv6Address != "" {
+		if ip := net.ParseIP(*flIPv6Address); ip == nil || ip.To4() != nil {
+			return nil, nil, cmd, fmt.Errorf("%s is not a valid IPv6 address", *flIPv6Address)
+		}
+	}
+	if (*flIPAddress != "" || *flIPv6Address != "") && !*flNetwork {
+		return nil, nil, cmd, ErrConflictNetworkIPAddress
+	}
+	if *flIPAddress != "" && *flNetMode != "bridge" && *flNetMode != "macvlan" && !NetworkMode(*flNetMode).IsPlugin() {
+		return nil, nil, cmd, ErrConflictNetworkIPAddress
+	}
+	if *flIPv6Address != "" && *flNetMode != "bridge" && !NetworkMode(*flNetMode).IsPlugin() {
+		return nil, nil, cmd, ErrConflictNetworkIPAddress
+	}
+
 	var (
 		attachStdin  = flAttach.Get("stdin")
 		attachStdout = flAttach.Get("stdout")
 		attachStderr = flAttach.Get("stderr")
 	)
 
-	if *flNetMode != "bridge" && *flNetMode != "none" && *flHostname != "" {
+	if *flNetMode != "bridge" && *flNetMode != "none" && *flNetMode != "macvlan" && !NetworkMode(*flNetMode).IsPlugin() && *flHostname != "" {
 		return nil, nil, cmd, ErrConflictNetworkHostname
 	}
 
@@ -131,6 +181,14 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		return nil, nil, cmd, ErrConflictContainerNetworkAndDns
 	}
 
+	if *flNetMode == "macvlan" && (flPublish.Len() > 0 || *flPublishAll) {
+		return nil, nil, cmd, ErrConflictMacvlanNetworkAndPorts
+	}
+
+	if *flNetMode != "bridge" && (*flIngressRate != "" || *flEgressRate != "") {
+		return nil, nil, cmd, ErrConflictNetworkRate
+	}
+
 	// If neither -d or -a are set, attach to everything by default
 	if flAttach.Len() == 0 {
 		attachStdout = true
@@ -162,6 +220,15 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		}
 	}
 
+	ingressRate, err := ParseNetworkRate(*flIngressRate)
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+	egressRate, err := ParseNetworkRate(*flEgressRate)
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+
 	var binds []string
 	// add any bind targets to the list of container volumes
 	for bind := range flVolumes.GetMap() {
@@ -178,6 +245,16 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		}
 	}
 
+	tmpfs, err := parseTmpfs(flTmpfs.GetAll())
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+
+	storageOpt, err := parseStorageOpts(flStorageOpt.GetAll())
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+
 	var (
 		parsedArgs = cmd.Args()
 		runCmd     []string
@@ -274,6 +351,17 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		return nil, nil, cmd, err
 	}
 
+	// without --init, the default of the daemon applies
+	var runInit *bool
+	if cmd.IsSet("-init") {
+		runInit = flInit
+	}
+
+	securityOpts, err := parseSecurityOpts(flSecurityOpt.GetAll())
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+
 	config := &Config{
 		Hostname:        hostname,
 		Domainname:      domainname,
@@ -295,32 +383,42 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 		Image:           image,
 		Volumes:         flVolumes.GetMap(),
 		MacAddress:      *flMacAddress,
+		IPAddress:       *flIPAddress,
+		IPv6Address:     *flIPv6Address,
 		Entrypoint:      entrypoint,
 		WorkingDir:      *flWorkingDir,
+		StopSignal:      *flStopSignal,
 	}
 
 	hostConfig := &HostConfig{
-		Binds:           binds,
-		ContainerIDFile: *flContainerIDFile,
-		LxcConf:         lxcConf,
-		Privileged:      *flPrivileged,
-		PortBindings:    portBindings,
-		Links:           flLinks.GetAll(),
-		PublishAllPorts: *flPublishAll,
-		Dns:             flDns.GetAll(),
-		DnsSearch:       flDnsSearch.GetAll(),
-		ExtraHosts:      flExtraHosts.GetAll(),
-		VolumesFrom:     flVolumesFrom.GetAll(),
-		NetworkMode:     netMode,
-		IpcMode:         ipcMode,
-		PidMode:         pidMode,
-		Devices:         deviceMappings,
-		CapAdd:          flCapAdd.GetAll(),
-		CapDrop:         flCapDrop.GetAll(),
-		RestartPolicy:   restartPolicy,
-		SecurityOpt:     flSecurityOpt.GetAll(),
-		ReadonlyRootfs:  *flReadonlyRootfs,
-		Ulimits:         flUlimits.GetList(),
+		Binds:              binds,
+		ContainerIDFile:    *flContainerIDFile,
+		LxcConf:            lxcConf,
+		Privileged:         *flPrivileged,
+		PortBindings:       portBindings,
+		Links:              flLinks.GetAll(),
+		PublishAllPorts:    *flPublishAll,
+		Dns:                flDns.GetAll(),
+		DnsSearch:          flDnsSearch.GetAll(),
+		DnsOptions:         flDnsOptions.GetAll(),
+		ExtraHosts:         flExtraHosts.GetAll(),
+		VolumesFrom:        flVolumesFrom.GetAll(),
+		NetworkMode:        netMode,
+		IpcMode:            ipcMode,
+		PidMode:            pidMode,
+		Devices:            deviceMappings,
+		CapAdd:             flCapAdd.GetAll(),
+		CapDrop:            flCapDrop.GetAll(),
+		RestartPolicy:      restartPolicy,
+		SecurityOpt:        securityOpts,
+		ReadonlyRootfs:     *flReadonlyRootfs,
+		Ulimits:            flUlimits.GetList(),
+		PidsLimit:          *flPidsLimit,
+		Init:               runInit,
+		Tmpfs:              tmpfs,
+		StorageOpt:         storageOpt,
+		NetworkIngressRate: ingressRate,
+		NetworkEgressRate:  egressRate,
 	}
 
 	// When allocating stdin in attached mode, close stdin at client disconnect
@@ -330,6 +428,83 @@ func Parse(cmd *flag.FlagSet, args []string) (*Config, *HostConfig, *flag.FlagSe
 	return config, hostConfig, cmd, nil
 }
 
+// ParseNetworkRate parses the rate of --network-ingress-rate or
+// --network-egress-rate, in bits per second. An empty rate is unlimited.
+func ParseNetworkRate(rate string) (int64, error) {
+	if rate == "" {
+		return 0, nil
+	}
+	bits, err := units.RateInBits(rate)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid network rate %s: %s", rate, err)
+	}
+	return bits, nil
+}
+
+// parseTmpfs maps the paths of tmpfs mounts to their mount options. The
+// options are validated by the daemon, which knows the mount flags.
+func parseTmpfs(specs []string) (map[string]string, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	tmpfs := make(map[string]string)
+	for _, spec := range specs {
+		var (
+			parts   = strings.SplitN(spec, ":", 2)
+			dest    = parts[0]
+			options string
+		)
+		if len(parts) == 2 {
+			options = parts[1]
+		}
+		if !path.IsAbs(dest) {
+			return nil, fmt.Errorf("Invalid tmpfs mount %q: path must be absolute", spec)
+		}
+		if dest == "/" {
+			return nil, fmt.Errorf("Invalid tmpfs mount %q: path can't be '/'", spec)
+		}
+		tmpfs[path.Clean(dest)] = options
+	}
+	return tmpfs, nil
+}
+
+// parseStorageOpts maps the keys of storage driver options to their values.
+// The options are validated by the storage driver of the daemon.
+func parseStorageOpts(specs []string) (map[string]string, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	storageOpt := make(map[string]string)
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid storage option %q: expected key=value", spec)
+		}
+		storageOpt[parts[0]] = parts[1]
+	}
+	return storageOpt, nil
+}
+
+// parseSecurityOpts replaces the file name of seccomp profiles with their
+// content, as the profile is read by the client but applied by the daemon.
+func parseSecurityOpts(securityOpts []string) ([]string, error) {
+	for i, opt := range securityOpts {
+		if !strings.HasPrefix(opt, "seccomp=") && !strings.HasPrefix(opt, "seccomp:") {
+			continue
+		}
+		profile := opt[len("seccomp="):]
+		if profile == "unconfined" {
+			continue
+		}
+		content, err := ioutil.ReadFile(profile)
+		if err != nil {
+			return nil, fmt.Errorf("Opening seccomp profile (%s) failed: %v", profile, err)
+		}
+		securityOpts[i] = "seccomp=" + string(content)
+	}
+	return securityOpts, nil
+}
+
 // parseRestartPolicy returns the parsed policy or an error indicating what is incorrect
 func parseRestartPolicy(policy string) (RestartPolicy, error) {
 	p := RestartPolicy{}
@@ -401,13 +576,16 @@ func parseKeyValueOpts(opts opts.ListOpts) ([]utils.KeyValuePair, error) {
 func parseNetMode(netMode string) (NetworkMode, error) {
 	parts := strings.Split(netMode, ":")
 	switch mode := parts[0]; mode {
-	case "bridge", "none", "host":
+	case "bridge", "none", "host", "macvlan":
 	case "container":
 		if len(parts) < 2 || parts[1] == "" {
 			return "", fmt.Errorf("invalid container format container:<name|id>")
 		}
 	default:
-		return "", fmt.Errorf("invalid --net: %s", netMode)
+		// any other mode is the name of a network driver plugin
+		if !validPluginName.MatchString(netMode) {
+			return "", fmt.Errorf("invalid --net: %s", netMode)
+		}
 	}
 	return NetworkMode(netMode), nil
 }
@@ -1,7 +1,9 @@
 package runconfig
 
 import (
+	"fmt"
 	"io/ioutil"
+	"os"
 	"testing"
 
 	flag "github.com/docker/docker/pkg/mflag"
@@ -57,3 +59,213 @@ func TestNetHostname(t *testing.T) {
 		t.Fatalf("Expected error ErrConflictNetworkHostname, got: %s", err)
 	}
 }
+
+func TestParseIPAddress(t *testing.T) {
+	config, _, _, err := parseRun([]string{"--ip=172.17.0.10", "--ip6=2001:db8::10", "img", "cmd"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if config.IPAddress != "172.17.0.10" || config.IPv6Address != "2001:db8::10" {
+		t.Fatalf("Expected the static addresses, got %q and %q", config.IPAddress, config.IPv6Address)
+	}
+
+	for _, args := range [][]string{
+		{"--ip=2001:db8::10", "img", "cmd"},
+		{"--ip=172.17.0", "img", "cmd"},
+		{"--ip6=172.17.0.10", "img", "cmd"},
+	} {
+		if _, _, _, err := parseRun(args); err == nil {
+			t.Fatalf("Expected an error for %v", args)
+		}
+	}
+
+	if _, _, _, err := parseRun([]string{"--ip=172.17.0.10", "--net=host", "img", "cmd"}); err != ErrConflictNetworkIPAddress {
+		t.Fatalf("Expected error ErrConflictNetworkIPAddress, got: %v", err)
+	}
+}
+
+func TestParseMacvlanNetMode(t *testing.T) {
+	config, hostConfig, _, err := parseRun([]string{"--net=macvlan", "--ip=10.1.0.10", "-h=name", "img", "cmd"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !hostConfig.NetworkMode.IsMacvlan() || !hostConfig.NetworkMode.IsPrivate() {
+		t.Fatalf("Expected the macvlan network mode, got %q", hostConfig.NetworkMode)
+	}
+	if config.IPAddress != "10.1.0.10" {
+		t.Fatalf("Expected the static address, got %q", config.IPAddress)
+	}
+
+	if _, _, _, err := parseRun([]string{"--net=macvlan", "--ip6=2001:db8::10", "img", "cmd"}); err != ErrConflictNetworkIPAddress {
+		t.Fatalf("Expected error ErrConflictNetworkIPAddress, got: %v", err)
+	}
+	for _, args := range [][]string{
+		{"--net=macvlan", "-p=80:80", "img", "cmd"},
+		{"--net=macvlan", "-P", "img", "cmd"},
+	} {
+		if _, _, _, err := parseRun(args); err != ErrConflictMacvlanNetworkAndPorts {
+			t.Fatalf("Expected error ErrConflictMacvlanNetworkAndPorts for %v, got: %v", args, err)
+		}
+	}
+}
+
+func TestParsePluginNetMode(t *testing.T) {
+	config, hostConfig, _, err := parseRun([]string{"--net=my-driver", "--ip=10.1.0.10", "-p=80:80", "-h=name", "img", "cmd"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !hostConfig.NetworkMode.IsPlugin() || !hostConfig.NetworkMode.IsPrivate() {
+		t.Fatalf("Expected the network mode of a plugin, got %q", hostConfig.NetworkMode)
+	}
+	if config.IPAddress != "10.1.0.10" || config.Hostname != "name" {
+		t.Fatalf("Expected the static address and hostname, got %q and %q", config.IPAddress, config.Hostname)
+	}
+
+	for _, mode := range []NetworkMode{"", "bridge", "host", "none", "macvlan", "container:c1"} {
+		if mode.IsPlugin() {
+			t.Fatalf("Expected %q not to be the network mode of a plugin", mode)
+		}
+	}
+	if _, _, _, err := parseRun([]string{"--net=../driver", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for an invalid plugin name")
+	}
+}
+
+func TestParsePidsLimit(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--pids-limit=100", "img", "cmd"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if hostConfig.PidsLimit != 100 {
+		t.Fatalf("Expected PidsLimit 100, got %d", hostConfig.PidsLimit)
+	}
+
+	_, hostConfig, _, err = parseRun([]string{"img", "cmd"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if hostConfig.PidsLimit != 0 {
+		t.Fatalf("Expected no PidsLimit by default, got %d", hostConfig.PidsLimit)
+	}
+}
+
+func TestParseSeccompProfile(t *testing.T) {
+	f, err := ioutil.TempFile("", "seccomp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	profile := `{"defaultAction": "SCMP_ACT_ALLOW"}`
+	if _, err := f.WriteString(profile); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	_, hostConfig, _, err := parseRun([]string{"--security-opt", "seccomp=" + f.Name(), "--security-opt", "label:disable", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostConfig.SecurityOpt) != 2 || hostConfig.SecurityOpt[0] != "seccomp="+profile || hostConfig.SecurityOpt[1] != "label:disable" {
+		t.Fatalf("Unexpected security options: %v", hostConfig.SecurityOpt)
+	}
+
+	_, hostConfig, _, err = parseRun([]string{"--security-opt", "seccomp=unconfined", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostConfig.SecurityOpt) != 1 || hostConfig.SecurityOpt[0] != "seccomp=unconfined" {
+		t.Fatalf("Unexpected security options: %v", hostConfig.SecurityOpt)
+	}
+
+	if _, _, _, err := parseRun([]string{"--security-opt", "seccomp=/nonexistent/profile.json", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for a missing seccomp profile")
+	}
+}
+
+func TestParseStopSignal(t *testing.T) {
+	config, _, _, err := parseRun([]string{"--stop-signal", "SIGQUIT", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.StopSignal != "SIGQUIT" {
+		t.Fatalf("Expected stop signal SIGQUIT, got %q", config.StopSignal)
+	}
+
+	if _, _, _, err := parseRun([]string{"--stop-signal", "SIGNOPE", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for an invalid stop signal")
+	}
+}
+
+func TestParseInit(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.Init != nil {
+		t.Fatalf("Expected the daemon default without --init, got %v", *hostConfig.Init)
+	}
+
+	for _, value := range []bool{true, false} {
+		_, hostConfig, _, err := parseRun([]string{fmt.Sprintf("--init=%v", value), "img", "cmd"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hostConfig.Init == nil || *hostConfig.Init != value {
+			t.Fatalf("Expected Init to be %v, got %v", value, hostConfig.Init)
+		}
+	}
+}
+
+func TestParseTmpfs(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--tmpfs", "/run", "--tmpfs", "/tmp/:size=64m,mode=1777", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostConfig.Tmpfs) != 2 || hostConfig.Tmpfs["/run"] != "" || hostConfig.Tmpfs["/tmp"] != "size=64m,mode=1777" {
+		t.Fatalf("Unexpected tmpfs mounts %v", hostConfig.Tmpfs)
+	}
+
+	for _, spec := range []string{"run", "/", "relative/path:size=1m"} {
+		if _, _, _, err := parseRun([]string{"--tmpfs", spec, "img", "cmd"}); err == nil {
+			t.Fatalf("Expected an error for --tmpfs %s", spec)
+		}
+	}
+}
+
+func TestParseStorageOpt(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--storage-opt", "size=10G", "img", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostConfig.StorageOpt) != 1 || hostConfig.StorageOpt["size"] != "10G" {
+		t.Fatalf("Unexpected storage options %v", hostConfig.StorageOpt)
+	}
+
+	for _, spec := range []string{"size", "=10G"} {
+		if _, _, _, err := parseRun([]string{"--storage-opt", spec, "img", "cmd"}); err == nil {
+			t.Fatalf("Expected an error for --storage-opt %s", spec)
+		}
+	}
+}
+
+func TestParseNetworkRate(t *testing.T) {
+	_, hostConfig, _, err := parseRun([]string{"--network-ingress-rate=10mbit", "--network-egress-rate=1mbps", "img", "cmd"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if hostConfig.NetworkIngressRate != 10000000 || hostConfig.NetworkEgressRate != 8000000 {
+		t.Fatalf("Expected the rates in bits per second, got %d and %d", hostConfig.NetworkIngressRate, hostConfig.NetworkEgressRate)
+	}
+
+	if _, _, _, err := parseRun([]string{"--network-ingress-rate=fast", "img", "cmd"}); err == nil {
+		t.Fatal("Expected an error for an invalid rate")
+	}
+	for _, args := range [][]string{
+		{"--net=host", "--network-ingress-rate=10mbit", "img", "cmd"},
+		{"--net=macvlan", "--network-egress-rate=10mbit", "img", "cmd"},
+	} {
+		if _, _, _, err := parseRun(args); err != ErrConflictNetworkRate {
+			t.Fatalf("Expected error ErrConflictNetworkRate for %v, got: %v", args, err)
+		}
+	}
+}
@@ -167,7 +167,7 @@ func (r *Repository) Delete(path string) error {
 }
 
 func (r *Repository) createNewVolumePath(id string) (string, error) {
-	if err := r.driver.Create(id, ""); err != nil {
+	if err := r.driver.Create(id, "", nil); err != nil {
 		return "", err
 	}
 
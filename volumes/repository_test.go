@@ -156,7 +156,7 @@ func newRepo(root string) (*Repository, error) {
 	configPath := filepath.Join(root, "repo-config")
 	graphDir := filepath.Join(root, "repo-graph")
 
-	driver, err := graphdriver.GetDriver("vfs", graphDir, []string{})
+	driver, err := graphdriver.GetDriver("vfs", graphDir, []string{}, nil, nil)
 	if err != nil {
 		return nil, err
 	}